@@ -0,0 +1,60 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuthNonceStatusIssued = "issued"
+	AuthNonceStatusUsed   = "used"
+)
+
+// AuthNonce is a single-use nonce handed out for a Sign-In with Ethereum
+// message.
+type AuthNonce struct {
+	ID          int        `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Nonce       string     `gorm:"column:nonce;not null;type:VARCHAR(32);uniqueIndex:uk_nonce" json:"nonce"`
+	Status      string     `gorm:"column:status;not null;type:ENUM('issued','used');default:issued" json:"status"`
+	Address     string     `gorm:"column:address;not null;type:VARCHAR(64);default:''" json:"address"` // set when used
+	ExpiresTime time.Time  `gorm:"column:expires_time;not null" json:"expires_time"`
+	UsedTime    *time.Time `gorm:"column:used_time" json:"used_time"`
+	CreatedTime time.Time  `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (AuthNonce) TableName() string {
+	return "auth_nonce"
+}
+
+type AuthNonceManager struct {
+	db *gorm.DB
+}
+
+func NewAuthNonceManager(db *gorm.DB) *AuthNonceManager {
+	return &AuthNonceManager{db: db}
+}
+
+func (m *AuthNonceManager) Create(nonce *AuthNonce) error {
+	if err := m.db.Create(nonce).Error; err != nil {
+		return fmt.Errorf("AuthNonceManager Create: %w", err)
+	}
+	return nil
+}
+
+// Consume marks an issued, unexpired nonce used by address. It returns
+// false when the nonce is unknown, expired or already used.
+func (m *AuthNonceManager) Consume(nonce, address string, now time.Time) (bool, error) {
+	result := m.db.Model(&AuthNonce{}).
+		Where("nonce = ? AND status = ? AND expires_time > ?", nonce, AuthNonceStatusIssued, now).
+		Updates(map[string]interface{}{
+			"status":    AuthNonceStatusUsed,
+			"address":   address,
+			"used_time": now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("AuthNonceManager Consume: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
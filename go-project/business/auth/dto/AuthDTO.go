@@ -0,0 +1,17 @@
+package dto
+
+type LoginDTO struct {
+	Message   string `json:"message" binding:"required,max=4096"` // EIP-4361 message
+	Signature string `json:"signature" binding:"required,max=132"`
+}
+
+type NonceResp struct {
+	Nonce     string `json:"nonce"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type LoginResp struct {
+	Token     string `json:"token"`
+	Address   string `json:"address"`
+	ExpiresAt int64  `json:"expires_at"`
+}
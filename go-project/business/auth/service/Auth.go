@@ -0,0 +1,137 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/auth/do"
+	"go-project/business/auth/dto"
+	"go-project/main/log"
+	"go-project/util/jwt"
+	"go-project/util/siwe"
+)
+
+var LoginFailedError = errors.New("LoginFailedError")
+
+// Settings are the parameters of Sign-In with Ethereum and the sessions it
+// issues.
+type Settings struct {
+	Domain     string
+	ChainID    int64
+	JwtSecret  []byte
+	NonceTtl   time.Duration
+	SessionTtl time.Duration
+}
+
+type Service struct {
+	logger   *log.ZapLogger
+	db       *gorm.DB
+	settings Settings
+}
+
+func NewService(logger *log.ZapLogger, db *gorm.DB, settings Settings) *Service {
+	return &Service{
+		logger:   logger,
+		db:       db,
+		settings: settings,
+	}
+}
+
+// IssueNonce stores a fresh nonce the client puts in its SIWE message.
+func (service *Service) IssueNonce() (*dto.NonceResp, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	nonce := &do.AuthNonce{
+		Nonce:       hex.EncodeToString(random),
+		Status:      do.AuthNonceStatusIssued,
+		ExpiresTime: time.Now().Add(service.settings.NonceTtl),
+	}
+	if err := do.NewAuthNonceManager(service.db).Create(nonce); err != nil {
+		service.logger.Error("IssueNonce", zap.Error(err))
+		return nil, err
+	}
+	return &dto.NonceResp{Nonce: nonce.Nonce, ExpiresAt: nonce.ExpiresTime.Unix()}, nil
+}
+
+// Login verifies a signed SIWE message, consumes its nonce and returns a
+// session token bound to the signing address.
+func (service *Service) Login(input *dto.LoginDTO) (*dto.LoginResp, error) {
+	now := time.Now()
+	message, err := siwe.ParseMessage(input.Message)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", LoginFailedError, err)
+	}
+	if err := service.checkMessage(message, now); err != nil {
+		return nil, err
+	}
+	if err := verifyPersonalSignature(input.Message, input.Signature, message); err != nil {
+		return nil, err
+	}
+
+	address := message.Address.Hex()
+	consumed, err := do.NewAuthNonceManager(service.db).Consume(message.Nonce, address, now)
+	if err != nil {
+		service.logger.Error("Login Consume nonce", zap.Error(err))
+		return nil, err
+	}
+	if !consumed {
+		return nil, fmt.Errorf("%w: nonce unknown, expired or used", LoginFailedError)
+	}
+
+	expiresAt := now.Add(service.settings.SessionTtl)
+	token, err := jwt.Sign(jwt.Claims{
+		Subject:   address,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+		ID:        message.Nonce,
+	}, service.settings.JwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	service.logger.Info("login", zap.String("address", address))
+	return &dto.LoginResp{Token: token, Address: address, ExpiresAt: expiresAt.Unix()}, nil
+}
+
+func (service *Service) checkMessage(message *siwe.Message, now time.Time) error {
+	if message.Domain != service.settings.Domain {
+		return fmt.Errorf("%w: domain %s is not %s", LoginFailedError, message.Domain, service.settings.Domain)
+	}
+	if message.ChainID != service.settings.ChainID {
+		return fmt.Errorf("%w: chain id %d is not %d", LoginFailedError, message.ChainID, service.settings.ChainID)
+	}
+	if !message.ValidAt(now) {
+		return fmt.Errorf("%w: message expired or not yet valid", LoginFailedError)
+	}
+	return nil
+}
+
+// verifyPersonalSignature checks the EIP-191 personal_sign signature of
+// text was made by the address in the message.
+func verifyPersonalSignature(text, signatureHex string, message *siwe.Message) error {
+	signature, err := hexutil.Decode(signatureHex)
+	if err != nil || len(signature) != crypto.SignatureLength {
+		return fmt.Errorf("%w: malformed signature", LoginFailedError)
+	}
+	if signature[crypto.RecoveryIDOffset] >= 27 {
+		signature[crypto.RecoveryIDOffset] -= 27
+	}
+	publicKey, err := crypto.SigToPub(accounts.TextHash([]byte(text)), signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", LoginFailedError, err)
+	}
+	if crypto.PubkeyToAddress(*publicKey) != message.Address {
+		return fmt.Errorf("%w: signature does not match %s", LoginFailedError, message.Address.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"go-project/util/siwe"
+)
+
+func TestVerifyPersonalSignature(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	message := &siwe.Message{
+		Domain:   "localhost:8888",
+		Address:  crypto.PubkeyToAddress(key.PublicKey),
+		URI:      "http://localhost:8888",
+		Version:  "1",
+		ChainID:  31337,
+		Nonce:    "abcdef012345",
+		IssuedAt: time.Unix(1700000000, 0),
+	}
+	text := message.String()
+	signature, err := crypto.Sign(accounts.TextHash([]byte(text)), key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+
+	if err := verifyPersonalSignature(text, hexutil.Encode(signature), message); err != nil {
+		t.Fatalf("Expected valid signature, got %v", err)
+	}
+
+	other, _ := crypto.GenerateKey()
+	impersonated := *message
+	impersonated.Address = crypto.PubkeyToAddress(other.PublicKey)
+	if err := verifyPersonalSignature(text, hexutil.Encode(signature), &impersonated); !errors.Is(err, LoginFailedError) {
+		t.Fatalf("Expected LoginFailedError for other address, got %v", err)
+	}
+}
+
+func TestCheckMessage(t *testing.T) {
+	service := &Service{settings: Settings{Domain: "localhost:8888", ChainID: 31337}}
+	now := time.Unix(1700000000, 0)
+	expired := now.Add(-time.Second)
+
+	valid := &siwe.Message{Domain: "localhost:8888", ChainID: 31337}
+	if err := service.checkMessage(valid, now); err != nil {
+		t.Fatalf("Expected valid message, got %v", err)
+	}
+	for name, message := range map[string]*siwe.Message{
+		"other domain": {Domain: "evil.example", ChainID: 31337},
+		"other chain":  {Domain: "localhost:8888", ChainID: 1},
+		"expired":      {Domain: "localhost:8888", ChainID: 31337, ExpirationTime: &expired},
+	} {
+		if err := service.checkMessage(message, now); !errors.Is(err, LoginFailedError) {
+			t.Errorf("%s: expected LoginFailedError, got %v", name, err)
+		}
+	}
+}
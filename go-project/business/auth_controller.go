@@ -0,0 +1,41 @@
+package business
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/auth/dto"
+	"go-project/business/auth/service"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+func AuthNonce(c *gin.Context, db *gorm.DB, log *log.ZapLogger, settings service.Settings) {
+	nonce, err := service.NewService(log, db, settings).IssueNonce()
+	if err != nil {
+		log.Error("AuthNonce service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, nonce)
+}
+
+func AuthLogin(c *gin.Context, db *gorm.DB, log *log.ZapLogger, settings service.Settings) {
+	var input dto.LoginDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("AuthLogin ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	session, err := service.NewService(log, db, settings).Login(&input)
+	if err != nil {
+		log.Error("AuthLogin service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, session)
+}
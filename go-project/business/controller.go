@@ -1,23 +1,25 @@
 package business
 
 import (
+	"context"
+	"errors"
 	"math/big"
+	"net/http"
 
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	tokendo "go-project/business/token/do"
 	"go-project/business/workflow/dto"
 	"go-project/business/workflow/service"
 	"go-project/chain/eth"
-	globalconst "go-project/common"
-	"go-project/common/types"
 	"go-project/common/web"
 	"go-project/main/log"
 )
 
-func CreateWorkFlow(c *gin.Context, db *gorm.DB, log *log.ZapLogger, ERC20Client eth.TestErc20Client) {
+func CreateWorkFlow(c *gin.Context, db *gorm.DB, log *log.ZapLogger, tokens *eth.TokenRegistry, signer eth.Signer) {
 	var input dto.WorkflowInfoCreateDTO
 	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Error("CreateWorkFlow ShouldBindJSON", zap.Any("error", err))
@@ -25,40 +27,62 @@ func CreateWorkFlow(c *gin.Context, db *gorm.DB, log *log.ZapLogger, ERC20Client
 		return
 	}
 
-	privateKey, err := crypto.HexToECDSA(globalconst.OWNER_PRV_KEY)
+	workflowService := service.NewService(log, db)
+	tokenInfo, requiredBalance, err := workflowService.ResolvePayout(&input)
 	if err != nil {
-		log.Error("Failed to OWNER_PRV_KEY HexToECDSA", zap.Error(err))
-		web.Fail(c, "Failed to OWNER_PRV_KEY HexToECDSA")
+		log.Error("CreateWorkFlow ResolvePayout", zap.Error(err))
+		web.Fail(c, err.Error())
 		return
 	}
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
 
-	balance, err := ERC20Client.BalanceOf(c.Request.Context(), fromAddress)
+	fromAddress := signer.Address()
+	balance, err := signerBalance(c.Request.Context(), tokens, tokenInfo, fromAddress)
 	if err != nil {
 		log.Error("Failed to get balance", zap.Error(err))
 		web.Fail(c, "Failed to get balance")
 		return
 	}
 
-	requiredBalance := big.NewInt(2e6)
 	if balance.Cmp(requiredBalance) < 0 {
 		log.Error("Insufficient balance", zap.String("address", fromAddress.Hex()), zap.String("balance", balance.String()))
 		web.Fail(c, "Insufficient balance")
 		return
 	}
 
-	info, err := service.NewService(log, db).CreateWorkFlowService(&input)
+	info, err := workflowService.CreateWorkFlowService(&input, web.CallerAddress(c))
 	if err != nil {
+		log.Error("CreateWorkFlow service error", zap.Error(err))
+		web.Fail(c, err.Error())
 		return
 	}
 
 	web.Success(c, info)
 }
 
+func signerBalance(ctx context.Context, tokens *eth.TokenRegistry, tokenInfo *tokendo.TokenInfo, address common.Address) (*big.Int, error) {
+	if tokenInfo.IsNative() {
+		return tokens.NativeBalance(ctx, address)
+	}
+	erc20Client, err := tokens.Client(common.HexToAddress(tokenInfo.ContractAddress))
+	if err != nil {
+		return nil, err
+	}
+	return erc20Client.BalanceOf(ctx, address)
+}
+
 func WorkFlowList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
-	var pageReq types.GenericPageReq[dto.WorkflowInfoCreateDTO]
+	var input dto.WorkflowPageDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("WorkFlowList ShouldBindQuery", zap.Error(err))
+		web.FailV2(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	pageResp, err := service.NewService(log, db).PageWorkFlowList(pageReq)
+	pageResp, err := service.NewService(log, db).PageWorkFlowList(&input)
+	if errors.Is(err, service.InvalidPageRequestError) {
+		web.FailV2(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		log.Error("WorkFlowList service error", zap.Error(err))
 		web.Fail(c, err.Error())
@@ -68,6 +92,24 @@ func WorkFlowList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
 	web.Success(c, pageResp)
 }
 
+func WorkFlowDetail(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkflowDetailDTO
+	if err := c.ShouldBindUri(&input); err != nil {
+		log.Error("WorkFlowDetail ShouldBindUri", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	detail, err := service.NewService(log, db).GetWorkflowDetail(input.ID)
+	if err != nil {
+		log.Error("WorkFlowDetail service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, detail)
+}
+
 func WorkFlowApproval(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
 	var input dto.WorkFlowApprovalDTO
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -76,7 +118,7 @@ func WorkFlowApproval(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
 		return
 	}
 
-	err := service.NewService(log, db).ApproveWorkFlow(&input)
+	err := service.NewService(log, db).ApproveWorkFlow(&input, web.CallerAddress(c))
 	if err != nil {
 		log.Error("WorkFlowApproval service error", zap.Error(err))
 		web.Fail(c, err.Error())
@@ -85,3 +127,78 @@ func WorkFlowApproval(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
 
 	web.Success(c, "")
 }
+
+func WorkFlowApprovalTypedData(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkFlowApprovalTypedDataDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("WorkFlowApprovalTypedData ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, service.NewService(log, db).ApprovalTypedData(&input))
+}
+
+func WorkFlowCancel(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkFlowCancelDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("WorkFlowCancel ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	if err := service.NewService(log, db).CancelWorkFlow(&input, web.CallerAddress(c)); err != nil {
+		log.Error("WorkFlowCancel service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, "")
+}
+
+func WorkFlowStatusHistory(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkFlowHistoryDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("WorkFlowStatusHistory ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	list, err := service.NewService(log, db).ListStatusHistory(input.WorkflowID)
+	if err != nil {
+		log.Error("WorkFlowStatusHistory service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, list)
+}
+
+func ApprovalPolicyList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	list, err := service.NewService(log, db).ListApprovalPolicies()
+	if err != nil {
+		log.Error("ApprovalPolicyList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, list)
+}
+
+func CreateApprovalPolicy(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.ApprovalPolicyCreateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("CreateApprovalPolicy ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	policy, err := service.NewService(log, db).CreateApprovalPolicy(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("CreateApprovalPolicy service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, policy)
+}
@@ -0,0 +1,117 @@
+package do
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	IdempotencyStatusProcessing = "processing"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey remembers a request made with an Idempotency-Key header
+// and, once it succeeded, the response to replay for retries.
+type IdempotencyKey struct {
+	ID           int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	CallerAddr   string    `gorm:"column:caller_addr;not null;type:VARCHAR(64);uniqueIndex:uk_caller_key" json:"caller_addr"`
+	IdemKey      string    `gorm:"column:idem_key;not null;type:VARCHAR(128);uniqueIndex:uk_caller_key" json:"idem_key"`
+	RequestHash  string    `gorm:"column:request_hash;not null;type:CHAR(64)" json:"request_hash"` // sha256 of method, path and body
+	Status       string    `gorm:"column:status;not null;type:ENUM('processing','completed');default:processing" json:"status"`
+	ResponseCode int       `gorm:"column:response_code;not null;default:0" json:"response_code"`
+	ResponseBody string    `gorm:"column:response_body;type:MEDIUMTEXT" json:"response_body"`
+	ExpiresTime  time.Time `gorm:"column:expires_time;not null;index:idx_expires_time" json:"expires_time"`
+	CreatedTime  time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedTime  time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_key"
+}
+
+type IdempotencyKeyManager struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyKeyManager(db *gorm.DB) *IdempotencyKeyManager {
+	return &IdempotencyKeyManager{db: db}
+}
+
+// CreateIfAbsent inserts the key and reports false when the caller already
+// used it.
+func (m *IdempotencyKeyManager) CreateIfAbsent(key *IdempotencyKey) (bool, error) {
+	result := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, fmt.Errorf("IdempotencyKeyManager CreateIfAbsent: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (m *IdempotencyKeyManager) Get(callerAddr, idemKey string) (*IdempotencyKey, error) {
+	var key IdempotencyKey
+	err := m.db.Where("caller_addr = ? AND idem_key = ?", callerAddr, idemKey).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("IdempotencyKeyManager Get: %w", err)
+	}
+	return &key, nil
+}
+
+// Reclaim restarts a key that expired or whose request stopped processing
+// before staleBefore, so a crashed request does not block the key forever.
+// It reports false when another request got there first.
+func (m *IdempotencyKeyManager) Reclaim(id int, requestHash string, now, staleBefore, expiresTime time.Time) (bool, error) {
+	result := m.db.Model(&IdempotencyKey{}).
+		Where("id = ? AND (expires_time < ? OR (status = ? AND updated_time < ?))", id, now, IdempotencyStatusProcessing, staleBefore).
+		Updates(map[string]interface{}{
+			"request_hash":  requestHash,
+			"status":        IdempotencyStatusProcessing,
+			"response_code": 0,
+			"response_body": "",
+			"expires_time":  expiresTime,
+			"updated_time":  now,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("IdempotencyKeyManager Reclaim: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (m *IdempotencyKeyManager) Complete(callerAddr, idemKey string, responseCode int, responseBody string) error {
+	err := m.db.Model(&IdempotencyKey{}).
+		Where("caller_addr = ? AND idem_key = ? AND status = ?", callerAddr, idemKey, IdempotencyStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":        IdempotencyStatusCompleted,
+			"response_code": responseCode,
+			"response_body": responseBody,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("IdempotencyKeyManager Complete: %w", err)
+	}
+	return nil
+}
+
+// Delete forgets a key still processing, letting the caller retry it.
+func (m *IdempotencyKeyManager) Delete(callerAddr, idemKey string) error {
+	err := m.db.Where("caller_addr = ? AND idem_key = ? AND status = ?", callerAddr, idemKey, IdempotencyStatusProcessing).
+		Delete(&IdempotencyKey{}).Error
+	if err != nil {
+		return fmt.Errorf("IdempotencyKeyManager Delete: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes up to limit keys that expired before before.
+func (m *IdempotencyKeyManager) DeleteExpired(before time.Time, limit int) (int64, error) {
+	result := m.db.Where("expires_time < ?", before).Limit(limit).Delete(&IdempotencyKey{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("IdempotencyKeyManager DeleteExpired: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
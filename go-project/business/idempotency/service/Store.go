@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/idempotency/do"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+const (
+	// keyTtl is how long a completed response is replayed.
+	keyTtl = 24 * time.Hour
+	// processingLease is how long a request may hold its key before a retry
+	// may take it over, e.g. after the server crashed mid-request.
+	processingLease = 5 * time.Minute
+)
+
+// Store keeps idempotency keys in MySQL.
+type Store struct {
+	logger *log.ZapLogger
+	db     *gorm.DB
+}
+
+var _ web.IdempotencyStore = (*Store)(nil)
+
+func NewStore(logger *log.ZapLogger, db *gorm.DB) *Store {
+	return &Store{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (s *Store) Begin(callerAddr, key, requestHash string) (*web.StoredResponse, error) {
+	now := time.Now()
+	manager := do.NewIdempotencyKeyManager(s.db)
+	created, err := manager.CreateIfAbsent(&do.IdempotencyKey{
+		CallerAddr:  callerAddr,
+		IdemKey:     key,
+		RequestHash: requestHash,
+		Status:      do.IdempotencyStatusProcessing,
+		ExpiresTime: now.Add(keyTtl),
+	})
+	if err != nil {
+		s.logger.Error("Idempotency Begin", zap.Error(err))
+		return nil, err
+	}
+	if created {
+		return nil, nil
+	}
+
+	existing, err := manager.Get(callerAddr, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		// released between the insert and the read
+		return nil, web.IdempotencyKeyInProgressError
+	}
+	if existing.ExpiresTime.Before(now) || (existing.Status == do.IdempotencyStatusProcessing && existing.UpdatedTime.Before(now.Add(-processingLease))) {
+		reclaimed, err := manager.Reclaim(existing.ID, requestHash, now, now.Add(-processingLease), now.Add(keyTtl))
+		if err != nil {
+			return nil, err
+		}
+		if reclaimed {
+			return nil, nil
+		}
+		return nil, web.IdempotencyKeyInProgressError
+	}
+	return replay(existing, requestHash)
+}
+
+func (s *Store) Complete(callerAddr, key string, response web.StoredResponse) error {
+	err := do.NewIdempotencyKeyManager(s.db).Complete(callerAddr, key, response.Code, string(response.Body))
+	if err != nil {
+		s.logger.Error("Idempotency Complete", zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+func (s *Store) Release(callerAddr, key string) error {
+	err := do.NewIdempotencyKeyManager(s.db).Delete(callerAddr, key)
+	if err != nil {
+		s.logger.Error("Idempotency Release", zap.String("key", key), zap.Error(err))
+	}
+	return err
+}
+
+// replay decides what a retry of an existing, unexpired key gets.
+func replay(existing *do.IdempotencyKey, requestHash string) (*web.StoredResponse, error) {
+	if existing.RequestHash != requestHash {
+		return nil, fmt.Errorf("%w: key %s", web.IdempotencyKeyReusedError, existing.IdemKey)
+	}
+	if existing.Status != do.IdempotencyStatusCompleted {
+		return nil, web.IdempotencyKeyInProgressError
+	}
+	return &web.StoredResponse{Code: existing.ResponseCode, Body: []byte(existing.ResponseBody)}, nil
+}
+
+// DeleteExpired removes up to limit keys whose response is no longer
+// replayed, so the table does not grow without bound.
+func (s *Store) DeleteExpired(now time.Time, limit int) (int64, error) {
+	deleted, err := do.NewIdempotencyKeyManager(s.db).DeleteExpired(now, limit)
+	if err != nil {
+		s.logger.Error("Idempotency DeleteExpired", zap.Error(err))
+		return 0, err
+	}
+	return deleted, nil
+}
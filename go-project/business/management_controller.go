@@ -0,0 +1,95 @@
+package business
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/dto"
+	"go-project/business/workflow/service"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+func ManagementList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	list, err := service.NewService(log, db).ListManagements()
+	if err != nil {
+		log.Error("ManagementList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, list)
+}
+
+func CreateManagement(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.ManagementCreateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("CreateManagement ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	management, err := service.NewService(log, db).CreateManagement(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("CreateManagement service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, management)
+}
+
+func UpdateManagement(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.ManagementUpdateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("UpdateManagement ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	management, err := service.NewService(log, db).UpdateManagementPermission(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("UpdateManagement service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, management)
+}
+
+func DeactivateManagement(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.ManagementDeactivateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("DeactivateManagement ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	management, err := service.NewService(log, db).DeactivateManagement(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("DeactivateManagement service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, management)
+}
+
+func ManagementAuditList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.ManagementAuditDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("ManagementAuditList ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	list, err := service.NewService(log, db).ListManagementAudits(&input)
+	if err != nil {
+		log.Error("ManagementAuditList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, list)
+}
@@ -0,0 +1,84 @@
+package business
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/dto"
+	"go-project/business/workflow/service"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+func PayoutList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.PayoutPageDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("PayoutList ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	page, err := service.NewService(log, db).PagePayouts(&input)
+	if err != nil {
+		log.Error("PayoutList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, page)
+}
+
+func PayoutDetail(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.PayoutDTO
+	if err := c.ShouldBindUri(&input); err != nil {
+		log.Error("PayoutDetail ShouldBindUri", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	payout, err := service.NewService(log, db).GetPayout(input.ID)
+	if err != nil {
+		log.Error("PayoutDetail service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, payout)
+}
+
+func RetryPayout(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.PayoutRetryDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("RetryPayout ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	payout, err := service.NewService(log, db).RetryPayout(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("RetryPayout service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, payout)
+}
+
+func CancelPayout(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.PayoutCancelDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("CancelPayout ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	payout, err := service.NewService(log, db).CancelPayout(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("CancelPayout service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, payout)
+}
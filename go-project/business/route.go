@@ -4,26 +4,115 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	authservice "go-project/business/auth/service"
+	idempotencyservice "go-project/business/idempotency/service"
 	"go-project/chain/eth"
+	"go-project/common/web"
 	"go-project/main/log"
 )
 
 type Route struct {
-	DB          *gorm.DB
-	Log         *log.ZapLogger
-	ERC20Client eth.TestErc20Client
+	DB     *gorm.DB
+	Log    *log.ZapLogger
+	Tokens *eth.TokenRegistry
+	Signer eth.Signer
+	Auth   authservice.Settings
 }
 
 func (r *Route) Register(engine *gin.Engine) {
-	root := engine.Group("")
+	public := engine.Group("")
+	public.GET("/auth/nonce", func(c *gin.Context) {
+		AuthNonce(c, r.DB, r.Log, r.Auth)
+	})
+	public.POST("/auth/login", func(c *gin.Context) {
+		AuthLogin(c, r.DB, r.Log, r.Auth)
+	})
 
-	root.POST("/workflow/create", func(c *gin.Context) {
-		CreateWorkFlow(c, r.DB, r.Log, r.ERC20Client)
+	root := engine.Group("", web.AuthHandler(r.Auth.JwtSecret))
+	idempotent := web.IdempotencyHandler(idempotencyservice.NewStore(r.Log, r.DB))
+
+	root.POST("/workflow/create", idempotent, func(c *gin.Context) {
+		CreateWorkFlow(c, r.DB, r.Log, r.Tokens, r.Signer)
 	})
 	root.GET("/workflow/page", func(c *gin.Context) {
 		WorkFlowList(c, r.DB, r.Log)
 	})
-	root.POST("/workflow/approve", func(c *gin.Context) {
+	root.GET("/workflow/:id", func(c *gin.Context) {
+		WorkFlowDetail(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/approve", idempotent, func(c *gin.Context) {
 		WorkFlowApproval(c, r.DB, r.Log)
 	})
+	root.GET("/workflow/approve/typed-data", func(c *gin.Context) {
+		WorkFlowApprovalTypedData(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/cancel", func(c *gin.Context) {
+		WorkFlowCancel(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/schedule/pause", func(c *gin.Context) {
+		PauseSchedule(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/schedule/resume", func(c *gin.Context) {
+		ResumeSchedule(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/schedule/cancel", func(c *gin.Context) {
+		CancelSchedule(c, r.DB, r.Log)
+	})
+	root.GET("/workflow/history", func(c *gin.Context) {
+		WorkFlowStatusHistory(c, r.DB, r.Log)
+	})
+	root.GET("/workflow/policy/list", func(c *gin.Context) {
+		ApprovalPolicyList(c, r.DB, r.Log)
+	})
+	root.POST("/workflow/policy/create", func(c *gin.Context) {
+		CreateApprovalPolicy(c, r.DB, r.Log)
+	})
+
+	root.GET("/management/list", func(c *gin.Context) {
+		ManagementList(c, r.DB, r.Log)
+	})
+	root.POST("/management/create", func(c *gin.Context) {
+		CreateManagement(c, r.DB, r.Log)
+	})
+	root.POST("/management/update", func(c *gin.Context) {
+		UpdateManagement(c, r.DB, r.Log)
+	})
+	root.POST("/management/deactivate", func(c *gin.Context) {
+		DeactivateManagement(c, r.DB, r.Log)
+	})
+	root.GET("/management/audit", func(c *gin.Context) {
+		ManagementAuditList(c, r.DB, r.Log)
+	})
+
+	root.GET("/payout/page", func(c *gin.Context) {
+		PayoutList(c, r.DB, r.Log)
+	})
+	root.GET("/payout/:id", func(c *gin.Context) {
+		PayoutDetail(c, r.DB, r.Log)
+	})
+	root.POST("/payout/retry", idempotent, func(c *gin.Context) {
+		RetryPayout(c, r.DB, r.Log)
+	})
+	root.POST("/payout/cancel", func(c *gin.Context) {
+		CancelPayout(c, r.DB, r.Log)
+	})
+
+	root.GET("/token/list", func(c *gin.Context) {
+		TokenInfoList(c, r.DB, r.Log)
+	})
+	root.GET("/token/detail", func(c *gin.Context) {
+		TokenInfoDetail(c, r.DB, r.Log)
+	})
+	root.POST("/token/create", func(c *gin.Context) {
+		CreateTokenInfo(c, r.DB, r.Log, r.Tokens)
+	})
+	root.POST("/token/update", func(c *gin.Context) {
+		UpdateTokenInfo(c, r.DB, r.Log, r.Tokens)
+	})
+	root.POST("/token/delete", func(c *gin.Context) {
+		DeleteTokenInfo(c, r.DB, r.Log)
+	})
+	root.GET("/token/event/page", func(c *gin.Context) {
+		TokenTransferEventList(c, r.DB, r.Log)
+	})
 }
@@ -58,3 +58,21 @@ func (bm *BlockInfoManager) GetLatestBlockNumber() (uint64, error) {
 	}
 	return block.BlockNumber, nil
 }
+
+func (bm *BlockInfoManager) GetByNumber(number uint64) (*BlockInfo, error) {
+	var block BlockInfo
+	var result = bm.db.Where("block_number = ?", number).First(&block)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &block, nil
+}
+
+func (bm *BlockInfoManager) DeleteAfterNumber(number uint64) (int64, error) {
+	result := bm.db.Where("block_number > ?", number).Delete(&BlockInfo{})
+	return result.RowsAffected, result.Error
+}
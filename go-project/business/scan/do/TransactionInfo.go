@@ -41,3 +41,25 @@ func NewTransactionInfoManager(db *gorm.DB) *TransactionInfoManager {
 func (m *TransactionInfoManager) Create(info *TransactionInfo) error {
 	return m.db.Create(info).Error
 }
+
+func (m *TransactionInfoManager) ListByTxHashes(txHashes []string) ([]TransactionInfo, error) {
+	var infos []TransactionInfo
+	if len(txHashes) == 0 {
+		return infos, nil
+	}
+	err := m.db.Where("tx_hash IN ?", txHashes).Find(&infos).Error
+	return infos, err
+}
+
+func (m *TransactionInfoManager) ListTxHashesAfterBlockNumber(number uint64) ([]string, error) {
+	var hashes []string
+	err := m.db.Model(&TransactionInfo{}).
+		Where("block_number > ?", number).
+		Pluck("tx_hash", &hashes).Error
+	return hashes, err
+}
+
+func (m *TransactionInfoManager) DeleteAfterBlockNumber(number uint64) (int64, error) {
+	result := m.db.Where("block_number > ?", number).Delete(&TransactionInfo{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,66 @@
+package business
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/dto"
+	"go-project/business/workflow/service"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+func PauseSchedule(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkflowScheduleActionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("PauseSchedule ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	workflow, err := service.NewService(log, db).PauseSchedule(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("PauseSchedule service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, workflow)
+}
+
+func ResumeSchedule(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkflowScheduleActionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("ResumeSchedule ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	workflow, err := service.NewService(log, db).ResumeSchedule(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("ResumeSchedule service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, workflow)
+}
+
+func CancelSchedule(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.WorkflowScheduleActionDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("CancelSchedule ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	workflow, err := service.NewService(log, db).CancelSchedule(&input, web.CallerAddress(c))
+	if err != nil {
+		log.Error("CancelSchedule service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, workflow)
+}
@@ -0,0 +1,152 @@
+package do
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	NonceStatusReserved  = "reserved"
+	NonceStatusSent      = "sent"
+	NonceStatusReleased  = "released"
+	NonceStatusConfirmed = "confirmed"
+)
+
+type SignerNonce struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Address     string    `gorm:"column:address;not null;type:VARCHAR(64);uniqueIndex:uk_address_nonce,priority:1" json:"address"`
+	Nonce       uint64    `gorm:"column:nonce;not null;type:BIGINT UNSIGNED;uniqueIndex:uk_address_nonce,priority:2" json:"nonce"`
+	Status      string    `gorm:"column:status;not null;type:ENUM('reserved','sent','released','confirmed');default:reserved" json:"status"`
+	TxHash      string    `gorm:"column:tx_hash;not null;type:VARCHAR(66)" json:"tx_hash"`
+	CreatedTime time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedTime time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+}
+
+func (SignerNonce) TableName() string {
+	return "signer_nonce"
+}
+
+type SignerNonceManager struct {
+	db *gorm.DB
+}
+
+func NewSignerNonceManager(db *gorm.DB) *SignerNonceManager {
+	return &SignerNonceManager{db: db}
+}
+
+// Reserve hands out the next nonce for address. chainNonce is the signer's
+// pending transaction count; everything below it is already used on chain.
+// Released nonces are reused first so gaps get filled before new nonces
+// are allocated.
+func (m *SignerNonceManager) Reserve(address string, chainNonce uint64) (uint64, error) {
+	var reserved uint64
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var rows []SignerNonce
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("address = ? AND status <> ?", address, NonceStatusConfirmed).
+			Order("nonce ASC").
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&SignerNonce{}).
+			Where("address = ? AND nonce < ? AND status <> ?", address, chainNonce, NonceStatusConfirmed).
+			Update("status", NonceStatusConfirmed).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if row.Nonce >= chainNonce && row.Status == NonceStatusReleased {
+				reserved = row.Nonce
+				return tx.Model(&SignerNonce{}).
+					Where("id = ?", row.ID).
+					Updates(map[string]interface{}{"status": NonceStatusReserved, "tx_hash": "", "updated_time": time.Now()}).Error
+			}
+		}
+
+		next := chainNonce
+		var highest SignerNonce
+		err = tx.Where("address = ?", address).Order("nonce DESC").First(&highest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && highest.Nonce >= next {
+			next = highest.Nonce + 1
+		}
+
+		reserved = next
+		return tx.Create(&SignerNonce{
+			Address: address,
+			Nonce:   next,
+			Status:  NonceStatusReserved,
+		}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("SignerNonceManager Reserve: %w", err)
+	}
+	return reserved, nil
+}
+
+func (m *SignerNonceManager) MarkSent(address string, nonce uint64, txHash string) error {
+	return m.updateStatus(address, nonce, NonceStatusSent, txHash)
+}
+
+func (m *SignerNonceManager) Release(address string, nonce uint64) error {
+	return m.updateStatus(address, nonce, NonceStatusReleased, "")
+}
+
+// ListGaps returns nonces at or above fromNonce that were released, or
+// reserved before staleBefore and never sent, while a later nonce has been
+// sent. They are holes that block every later transaction of the signer.
+func (m *SignerNonceManager) ListGaps(address string, fromNonce uint64, staleBefore time.Time) ([]uint64, error) {
+	var nonces []uint64
+	highestSent := m.db.Model(&SignerNonce{}).
+		Select("MAX(nonce)").
+		Where("address = ? AND status = ?", address, NonceStatusSent)
+	err := m.db.Model(&SignerNonce{}).
+		Where("address = ? AND nonce >= ? AND nonce < (?)", address, fromNonce, highestSent).
+		Where("(status = ? OR (status = ? AND updated_time < ?))", NonceStatusReleased, NonceStatusReserved, staleBefore).
+		Order("nonce ASC").
+		Pluck("nonce", &nonces).Error
+	if err != nil {
+		return nil, fmt.Errorf("SignerNonceManager ListGaps: %w", err)
+	}
+	return nonces, nil
+}
+
+// Claim takes over a gap nonce returned by ListGaps. It reports false when
+// another caller reserved or sent it in the meantime.
+func (m *SignerNonceManager) Claim(address string, nonce uint64, staleBefore time.Time) (bool, error) {
+	result := m.db.Model(&SignerNonce{}).
+		Where("address = ? AND nonce = ?", address, nonce).
+		Where("(status = ? OR (status = ? AND updated_time < ?))", NonceStatusReleased, NonceStatusReserved, staleBefore).
+		Updates(map[string]interface{}{
+			"status":       NonceStatusReserved,
+			"tx_hash":      "",
+			"updated_time": time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("SignerNonceManager Claim: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (m *SignerNonceManager) updateStatus(address string, nonce uint64, status, txHash string) error {
+	err := m.db.Model(&SignerNonce{}).
+		Where("address = ? AND nonce = ?", address, nonce).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"tx_hash":      txHash,
+			"updated_time": time.Now(),
+		}).Error
+	if err != nil {
+		return fmt.Errorf("SignerNonceManager update %s: %w", status, err)
+	}
+	return nil
+}
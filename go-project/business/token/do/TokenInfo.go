@@ -8,12 +8,22 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	TokenTypeERC20  = "erc20"
+	TokenTypeNative = "native"
+)
+
+// NativeTokenAddress is stored as the contract address of the native asset
+// entry, which has no contract.
+const NativeTokenAddress = "0x0000000000000000000000000000000000000000"
+
 type TokenInfo struct {
 	ID              int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
 	TokenName       string    `gorm:"column:token_name;not null;type:VARCHAR(100)" json:"token_name"`
 	TokenSymbol     string    `gorm:"column:token_symbol;not null;type:VARCHAR(64)" json:"token_symbol"`
-	ContractAddress string    `gorm:"column:contract_address;not null;type:VARCHAR(64)" json:"contract_address"`
+	ContractAddress string    `gorm:"column:contract_address;not null;type:VARCHAR(64);uniqueIndex:uk_contract_address" json:"contract_address"`
 	Decimals        int       `gorm:"column:decimals;not null;default:18" json:"decimals"`
+	TokenType       string    `gorm:"column:token_type;not null;type:ENUM('erc20','native');default:erc20" json:"token_type"`
 	CreateBy        string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
 	CreateAddr      string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
 	CreatedTime     time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
@@ -26,6 +36,10 @@ func (TokenInfo) TableName() string {
 	return "token_info"
 }
 
+func (t *TokenInfo) IsNative() bool {
+	return t.TokenType == TokenTypeNative
+}
+
 type TokenInfoManager struct {
 	db *gorm.DB
 }
@@ -45,3 +59,45 @@ func (m *TokenInfoManager) GetByID(id int) (*TokenInfo, error) {
 	}
 	return &tokenInfo, nil
 }
+
+func (m *TokenInfoManager) List() ([]TokenInfo, error) {
+	var tokenInfos []TokenInfo
+	err := m.db.Order("id ASC").Find(&tokenInfos).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenInfoManager List: %w", err)
+	}
+	return tokenInfos, nil
+}
+
+func (m *TokenInfoManager) GetByContractAddress(contractAddress string) (*TokenInfo, error) {
+	var tokenInfo TokenInfo
+	err := m.db.Where("contract_address = ?", contractAddress).First(&tokenInfo).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("TokenInfoManager GetByContractAddress: %w", err)
+	}
+	return &tokenInfo, nil
+}
+
+func (m *TokenInfoManager) Create(tokenInfo *TokenInfo) error {
+	if err := m.db.Create(tokenInfo).Error; err != nil {
+		return fmt.Errorf("TokenInfoManager Create: %w", err)
+	}
+	return nil
+}
+
+func (m *TokenInfoManager) Update(tokenInfo *TokenInfo) error {
+	if err := m.db.Save(tokenInfo).Error; err != nil {
+		return fmt.Errorf("TokenInfoManager Update: %w", err)
+	}
+	return nil
+}
+
+func (m *TokenInfoManager) Delete(id int) error {
+	if err := m.db.Delete(&TokenInfo{}, id).Error; err != nil {
+		return fmt.Errorf("TokenInfoManager Delete: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AttemptOutcomeBroadcast       = "broadcast"
+	AttemptOutcomeBroadcastFailed = "broadcast_failed" // saved signed, rebroadcast later
+	AttemptOutcomeFailed          = "failed"
+	AttemptOutcomeLeaseExpired    = "lease_expired"
+	AttemptOutcomeDropped         = "dropped"
+	AttemptOutcomeDeadLetter      = "dead_letter"
+	AttemptOutcomeRetried         = "retried"
+	AttemptOutcomeCancelled       = "cancelled"
+)
+
+// TokenTransferAttempt is one entry of a payout's history: every send
+// attempt and what became of it, and every operator action on the payout.
+type TokenTransferAttempt struct {
+	ID                 int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenTransferLogID int       `gorm:"column:token_transfer_log_id;not null;index:idx_token_transfer_log_id" json:"token_transfer_log_id"`
+	Outcome            string    `gorm:"column:outcome;not null;type:VARCHAR(32)" json:"outcome"`
+	RetryCount         int       `gorm:"column:retry_count;not null;default:0" json:"retry_count"` // of the payout after this attempt
+	TxHash             string    `gorm:"column:tx_hash;not null;type:VARCHAR(66);default:''" json:"tx_hash"`
+	Error              string    `gorm:"column:error;type:VARCHAR(512)" json:"error"`
+	OperatorAddr       string    `gorm:"column:operator_addr;not null;type:VARCHAR(64)" json:"operator_addr"`
+	CreatedTime        time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (TokenTransferAttempt) TableName() string {
+	return "token_transfer_attempt"
+}
+
+type TokenTransferAttemptManager struct {
+	db *gorm.DB
+}
+
+func NewTokenTransferAttemptManager(db *gorm.DB) *TokenTransferAttemptManager {
+	return &TokenTransferAttemptManager{db: db}
+}
+
+func (m *TokenTransferAttemptManager) Create(attempt *TokenTransferAttempt) error {
+	if len(attempt.Error) > 512 {
+		attempt.Error = attempt.Error[:512]
+	}
+	if attempt.CreatedTime.IsZero() {
+		attempt.CreatedTime = time.Now()
+	}
+	if err := m.db.Create(attempt).Error; err != nil {
+		return fmt.Errorf("TokenTransferAttemptManager Create: %w", err)
+	}
+	return nil
+}
+
+// ListByTransferLogID returns the payout's history, oldest first.
+func (m *TokenTransferAttemptManager) ListByTransferLogID(transferLogID int) ([]TokenTransferAttempt, error) {
+	var attempts []TokenTransferAttempt
+	err := m.db.Where("token_transfer_log_id = ?", transferLogID).Order("id ASC").Find(&attempts).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenTransferAttemptManager ListByTransferLogID: %w", err)
+	}
+	return attempts, nil
+}
+
+// NewTokenTransferAttempt records outcome for the payout as it is now, with
+// its failure reason as the error.
+func NewTokenTransferAttempt(transferLog *TokenTransferLog, outcome, operatorAddr string) *TokenTransferAttempt {
+	return &TokenTransferAttempt{
+		TokenTransferLogID: transferLog.ID,
+		Outcome:            outcome,
+		RetryCount:         transferLog.RetryCount,
+		TxHash:             transferLog.TransactionHash,
+		Error:              transferLog.FailureReason,
+		OperatorAddr:       operatorAddr,
+	}
+}
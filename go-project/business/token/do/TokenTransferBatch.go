@@ -0,0 +1,132 @@
+package do
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BatchStatusSigned    = "signed"    // persisted, broadcast not confirmed by the node yet
+	BatchStatusBroadcast = "broadcast" // accepted by the node
+	BatchStatusSettled   = "settled"   // mined, its payouts settled from the Transfer events
+	BatchStatusReverted  = "reverted"  // mined but reverted, its payouts failed
+	BatchStatusDropped   = "dropped"   // its nonce was used by another transaction, payouts re-queued
+)
+
+// TokenTransferBatch is one disperse contract call paying several payouts
+// of the same token. Its payouts point to it through batch_id and carry its
+// hash as transaction_hash, the scanner settles each of them from the
+// Transfer events of the receipt. Like TokenTransferTx it is saved signed
+// before it is broadcast.
+type TokenTransferBatch struct {
+	ID                   int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenInfoID          int       `gorm:"column:token_info_id;not null" json:"token_info_id"`
+	ContractAddress      string    `gorm:"column:contract_address;not null;type:VARCHAR(42)" json:"contract_address"`
+	DisperseAddress      string    `gorm:"column:disperse_address;not null;type:VARCHAR(42)" json:"disperse_address"`
+	FromAddress          string    `gorm:"column:from_address;not null;type:VARCHAR(42)" json:"from_address"`
+	TxHash               string    `gorm:"column:tx_hash;not null;type:VARCHAR(66);uniqueIndex:uk_tx_hash" json:"tx_hash"`
+	PayoutCount          int       `gorm:"column:payout_count;not null" json:"payout_count"`
+	TotalAmount          string    `gorm:"column:total_amount;not null;type:DECIMAL(65,0)" json:"total_amount"`
+	Nonce                uint64    `gorm:"column:nonce;not null" json:"nonce"`
+	GasLimit             uint64    `gorm:"column:gas_limit;not null" json:"gas_limit"`
+	MaxFeePerGas         string    `gorm:"column:max_fee_per_gas;not null;type:VARCHAR(78)" json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string    `gorm:"column:max_priority_fee_per_gas;not null;type:VARCHAR(78)" json:"max_priority_fee_per_gas"`
+	RawTx                string    `gorm:"column:raw_tx;type:TEXT" json:"-"`
+	Status               string    `gorm:"column:status;not null;type:ENUM('signed','broadcast','settled','reverted','dropped');default:signed;index:idx_status" json:"status"`
+	CreatedTime          time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedTime          time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+}
+
+func (TokenTransferBatch) TableName() string {
+	return "token_transfer_batch"
+}
+
+type TokenTransferBatchManager struct {
+	db *gorm.DB
+}
+
+func NewTokenTransferBatchManager(db *gorm.DB) *TokenTransferBatchManager {
+	return &TokenTransferBatchManager{db: db}
+}
+
+func (m *TokenTransferBatchManager) Create(batch *TokenTransferBatch) error {
+	if err := m.db.Create(batch).Error; err != nil {
+		return fmt.Errorf("TokenTransferBatchManager Create: %w", err)
+	}
+	return nil
+}
+
+func (m *TokenTransferBatchManager) GetByTxHash(txHash string) (*TokenTransferBatch, error) {
+	var batch TokenTransferBatch
+	err := m.db.Where("tx_hash = ?", txHash).First(&batch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("TokenTransferBatchManager GetByTxHash: %w", err)
+	}
+	return &batch, nil
+}
+
+// MarkBroadcast records that the node accepted a signed batch.
+func (m *TokenTransferBatchManager) MarkBroadcast(txHash string) error {
+	return m.setStatus("MarkBroadcast", txHash, []string{BatchStatusSigned}, BatchStatusBroadcast)
+}
+
+// MarkDropped records that a batch can no longer be mined.
+func (m *TokenTransferBatchManager) MarkDropped(txHash string) error {
+	return m.setStatus("MarkDropped", txHash, []string{BatchStatusSigned, BatchStatusBroadcast}, BatchStatusDropped)
+}
+
+// MarkMined records the outcome of a mined batch, settled or reverted. A
+// batch mined again after a reorg is updated as well.
+func (m *TokenTransferBatchManager) MarkMined(txHash, status string) error {
+	return m.setStatus("MarkMined", txHash, []string{BatchStatusSigned, BatchStatusBroadcast, BatchStatusSettled, BatchStatusReverted}, status)
+}
+
+// ResetMinedByTxHashes puts settled or reverted batches mined by the given
+// transactions back to broadcast, e.g. when their block was dropped by a
+// reorg, so the dispatcher watches them again.
+func (m *TokenTransferBatchManager) ResetMinedByTxHashes(txHashes []string) (int64, error) {
+	if len(txHashes) == 0 {
+		return 0, nil
+	}
+	result := m.db.Model(&TokenTransferBatch{}).
+		Where("tx_hash IN ? AND status IN ?", txHashes, []string{BatchStatusSettled, BatchStatusReverted}).
+		Updates(map[string]interface{}{"status": BatchStatusBroadcast, "updated_time": time.Now()})
+	if result.Error != nil {
+		return 0, fmt.Errorf("TokenTransferBatchManager ResetMinedByTxHashes: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (m *TokenTransferBatchManager) setStatus(method, txHash string, from []string, to string) error {
+	err := m.db.Model(&TokenTransferBatch{}).
+		Where("tx_hash = ? AND status IN ?", txHash, from).
+		Updates(map[string]interface{}{"status": to, "updated_time": time.Now()}).Error
+	if err != nil {
+		return fmt.Errorf("TokenTransferBatchManager %s: %w", method, err)
+	}
+	return nil
+}
+
+// ListUnconfirmed returns batches in one of statuses that still have
+// unsettled payouts and can be rebroadcast, oldest first.
+func (m *TokenTransferBatchManager) ListUnconfirmed(statuses []string, limit int) ([]TokenTransferBatch, error) {
+	var batches []TokenTransferBatch
+	err := m.db.Table("token_transfer_batch AS b").
+		Select("b.*").
+		Where("b.status IN ? AND b.raw_tx <> ''", statuses).
+		Where("EXISTS (SELECT 1 FROM token_transfer_log AS l WHERE l.batch_id = b.id AND l.transaction_hash = b.tx_hash AND l.status IN ?)",
+			[]string{StatusPending, StatusSending}).
+		Order("b.id ASC").
+		Limit(limit).
+		Find(&batches).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenTransferBatchManager ListUnconfirmed: %w", err)
+	}
+	return batches, nil
+}
@@ -0,0 +1,115 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventTypeTransfer = "transfer"
+	EventTypeApproval = "approval"
+)
+
+type TokenTransferEvent struct {
+	ID           uint64    `gorm:"column:id;primaryKey;autoIncrement" json:"id"`
+	TokenInfoID  int       `gorm:"column:token_info_id;not null" json:"token_info_id"`
+	TokenAddress string    `gorm:"column:token_address;type:varchar(64);not null;index" json:"token_address"`
+	EventType    string    `gorm:"column:event_type;type:ENUM('transfer','approval');not null" json:"event_type"`
+	FromAddress  string    `gorm:"column:from_address;type:varchar(64);not null;index" json:"from_address"`
+	ToAddress    string    `gorm:"column:to_address;type:varchar(64);not null;index" json:"to_address"`
+	Value        string    `gorm:"column:value;type:varchar(78);not null" json:"value"`
+	LogIndex     uint      `gorm:"column:log_index;not null;uniqueIndex:uk_tx_hash_log_index,priority:2" json:"log_index"`
+	BlockNumber  uint64    `gorm:"column:block_number;type:bigint unsigned;not null;index" json:"block_number"`
+	BlockHash    string    `gorm:"column:block_hash;type:varchar(128);not null" json:"block_hash"`
+	TxHash       string    `gorm:"column:tx_hash;type:varchar(128);not null;uniqueIndex:uk_tx_hash_log_index,priority:1" json:"tx_hash"`
+	CreatedTime  time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (TokenTransferEvent) TableName() string {
+	return "token_transfer_event"
+}
+
+// TokenTransferEventQuery holds the optional filters of the event page API,
+// zero values are ignored.
+type TokenTransferEventQuery struct {
+	TokenAddress string
+	EventType    string
+	FromAddress  string
+	ToAddress    string
+	Address      string
+	TxHash       string
+	BlockFrom    uint64
+	BlockTo      uint64
+}
+
+type TokenTransferEventManager struct {
+	db *gorm.DB
+}
+
+func NewTokenTransferEventManager(db *gorm.DB) *TokenTransferEventManager {
+	return &TokenTransferEventManager{db: db}
+}
+
+func (m *TokenTransferEventManager) Create(event *TokenTransferEvent) error {
+	return m.db.Create(event).Error
+}
+
+func (m *TokenTransferEventManager) ListByTxHash(txHash string) ([]TokenTransferEvent, error) {
+	var events []TokenTransferEvent
+	err := m.db.Where("tx_hash = ?", txHash).Order("log_index ASC").Find(&events).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListByTxHash err: %w", err)
+	}
+	return events, nil
+}
+
+func (m *TokenTransferEventManager) Page(query *TokenTransferEventQuery, offset, limit uint64) ([]TokenTransferEvent, error) {
+	var events []TokenTransferEvent
+	err := m.filter(query).
+		Order("block_number DESC, log_index DESC").
+		Offset(int(offset)).Limit(int(limit)).
+		Find(&events).Error
+	return events, err
+}
+
+func (m *TokenTransferEventManager) Count(query *TokenTransferEventQuery) (uint64, error) {
+	var count int64
+	err := m.filter(query).Count(&count).Error
+	return uint64(count), err
+}
+
+func (m *TokenTransferEventManager) DeleteAfterBlockNumber(number uint64) (int64, error) {
+	result := m.db.Where("block_number > ?", number).Delete(&TokenTransferEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (m *TokenTransferEventManager) filter(query *TokenTransferEventQuery) *gorm.DB {
+	db := m.db.Model(&TokenTransferEvent{})
+	if query.TokenAddress != "" {
+		db = db.Where("token_address = ?", query.TokenAddress)
+	}
+	if query.EventType != "" {
+		db = db.Where("event_type = ?", query.EventType)
+	}
+	if query.FromAddress != "" {
+		db = db.Where("from_address = ?", query.FromAddress)
+	}
+	if query.ToAddress != "" {
+		db = db.Where("to_address = ?", query.ToAddress)
+	}
+	if query.Address != "" {
+		db = db.Where("(from_address = ? OR to_address = ?)", query.Address, query.Address)
+	}
+	if query.TxHash != "" {
+		db = db.Where("tx_hash = ?", query.TxHash)
+	}
+	if query.BlockFrom > 0 {
+		db = db.Where("block_number >= ?", query.BlockFrom)
+	}
+	if query.BlockTo > 0 {
+		db = db.Where("block_number <= ?", query.BlockTo)
+	}
+	return db
+}
@@ -6,38 +6,59 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
-	StatusSuccess = "success"
-	StatusFailed  = "failed"
-	StatusPending = "pending"
+	StatusSuccess    = "success"
+	StatusFailed     = "failed"
+	StatusPending    = "pending"
+	StatusSending    = "sending"     // claimed by a dispatcher, being broadcast
+	StatusDeadLetter = "dead_letter" // out of retries, waiting for an operator
+	StatusCancelled  = "cancelled"   // given up by an operator, never sent again
 )
 
 type TokenTransferLog struct {
-	ID              int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	TokenInfoID     int       `gorm:"column:token_info_id;not null" json:"token_info_id"`
-	WorkflowID      int       `gorm:"column:workflow_id;not null" json:"workflow_id"`
-	FromAddress     string    `gorm:"column:from_address;not null;type:VARCHAR(42)" json:"from_address"`
-	ToAddress       string    `gorm:"column:to_address;not null;type:VARCHAR(42)" json:"to_address"`
-	ContractAddress string    `gorm:"column:contract_address;not null;type:VARCHAR(42)" json:"contract_address"`
-	Amount          uint64    `gorm:"column:amount;not null" json:"amount"`
-	TransferData    string    `gorm:"column:transfer_data;not null;type:VARCHAR(512)" json:"transfer_data"`
-	Status          string    `gorm:"column:status;not null;type:ENUM('failed','success','pending');default:pending" json:"status"`
-	RetryCount      int       `gorm:"column:retry_count;not null;default:0" json:"retry_count"`
-	TransactionHash string    `gorm:"column:transaction_hash;not null;type:VARCHAR(66)" json:"transaction_hash"`
-	CreateBy        string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
-	CreateAddr      string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
-	CreatedTime     time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
-	UpdatedBy       string    `gorm:"column:updated_by;type:VARCHAR(64)" json:"updated_by"`
-	UpdatedAddr     string    `gorm:"column:updated_addr;type:VARCHAR(64)" json:"updated_addr"`
-	UpdatedTime     time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+	ID                   int        `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenInfoID          int        `gorm:"column:token_info_id;not null" json:"token_info_id"`
+	WorkflowID           int        `gorm:"column:workflow_id;not null;uniqueIndex:uk_workflow_run" json:"workflow_id"`             // at most one payout per workflow run
+	ScheduleRun          int        `gorm:"column:schedule_run;not null;default:0;uniqueIndex:uk_workflow_run" json:"schedule_run"` // run of a scheduled workflow, 0 when paid on approval
+	FromAddress          string     `gorm:"column:from_address;not null;type:VARCHAR(42)" json:"from_address"`
+	ToAddress            string     `gorm:"column:to_address;not null;type:VARCHAR(42)" json:"to_address"`
+	ContractAddress      string     `gorm:"column:contract_address;not null;type:VARCHAR(42)" json:"contract_address"`
+	Amount               string     `gorm:"column:amount;not null;type:DECIMAL(65,0)" json:"amount"` // token base units
+	TransferData         string     `gorm:"column:transfer_data;not null;type:VARCHAR(512)" json:"transfer_data"`
+	Status               string     `gorm:"column:status;not null;type:ENUM('failed','success','pending','sending','dead_letter','cancelled');default:pending" json:"status"`
+	RetryCount           int        `gorm:"column:retry_count;not null;default:0" json:"retry_count"`
+	TransactionHash      string     `gorm:"column:transaction_hash;not null;type:VARCHAR(66)" json:"transaction_hash"`
+	BatchID              int        `gorm:"column:batch_id;not null;default:0;index:idx_batch_id" json:"batch_id"` // disperse batch the payout was sent in, 0 when sent alone
+	GasLimit             uint64     `gorm:"column:gas_limit;not null;default:0" json:"gas_limit"`
+	MaxFeePerGas         string     `gorm:"column:max_fee_per_gas;not null;type:VARCHAR(78);default:''" json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string     `gorm:"column:max_priority_fee_per_gas;not null;type:VARCHAR(78);default:''" json:"max_priority_fee_per_gas"`
+	FailureReason        string     `gorm:"column:failure_reason;type:VARCHAR(512)" json:"failure_reason"`
+	GasLimitOverride     uint64     `gorm:"column:gas_limit_override;not null;default:0" json:"gas_limit_override"` // set by an operator retrying the payout
+	MaxFeePerGasOverride string     `gorm:"column:max_fee_per_gas_override;not null;type:VARCHAR(78);default:''" json:"max_fee_per_gas_override"`
+	PriorityFeeOverride  string     `gorm:"column:priority_fee_override;not null;type:VARCHAR(78);default:''" json:"priority_fee_override"`
+	LeaseOwner           string     `gorm:"column:lease_owner;not null;type:VARCHAR(128);default:''" json:"lease_owner"` // dispatcher holding the sending row
+	LeaseExpiresTime     *time.Time `gorm:"column:lease_expires_time" json:"lease_expires_time"`
+	CreateBy             string     `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
+	CreateAddr           string     `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
+	CreatedTime          time.Time  `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedBy            string     `gorm:"column:updated_by;type:VARCHAR(64)" json:"updated_by"`
+	UpdatedAddr          string     `gorm:"column:updated_addr;type:VARCHAR(64)" json:"updated_addr"`
+	UpdatedTime          time.Time  `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
 }
 
 func (TokenTransferLog) TableName() string {
 	return "token_transfer_log"
 }
 
+// IsNative reports whether the payout sends the native asset rather than
+// an ERC-20 token.
+func (l *TokenTransferLog) IsNative() bool {
+	return l.ContractAddress == NativeTokenAddress
+}
+
 type TokenTransferLogManager struct {
 	db *gorm.DB
 }
@@ -46,52 +67,346 @@ func NewTokenTransferLogManager(db *gorm.DB) *TokenTransferLogManager {
 	return &TokenTransferLogManager{db: db}
 }
 
+// Update saves the row unconditionally, dropping any lease on it.
 func (r *TokenTransferLogManager) Update(log *TokenTransferLog) error {
+	columns := updateColumns(log)
+	columns["lease_owner"] = ""
+	columns["lease_expires_time"] = nil
 	return r.db.Model(&TokenTransferLog{}).
 		Where("id = ?", log.ID).
-		Updates(map[string]interface{}{
-			"token_info_id":    log.TokenInfoID,
-			"workflow_id":      log.WorkflowID,
-			"from_address":     log.FromAddress,
-			"to_address":       log.ToAddress,
-			"contract_address": log.ContractAddress,
-			"amount":           log.Amount,
-			"transfer_data":    log.TransferData,
-			"status":           log.Status,
-			"retry_count":      log.RetryCount,
-			"transaction_hash": log.TransactionHash,
-			"updated_by":       log.UpdatedBy,
-			"updated_addr":     log.UpdatedAddr,
-			"updated_time":     time.Now(),
-		}).Error
+		Updates(columns).Error
+}
+
+// FinishSending saves the outcome of a send and gives up the lease. It
+// reports false, saving nothing, when owner no longer holds the lease.
+func (r *TokenTransferLogManager) FinishSending(log *TokenTransferLog, owner string) (bool, error) {
+	columns := updateColumns(log)
+	columns["lease_owner"] = ""
+	columns["lease_expires_time"] = nil
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", log.ID, StatusSending, owner).
+		Updates(columns)
+	if result.Error != nil {
+		return false, fmt.Errorf("FinishSending err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// SaveSigned saves the signed transaction of a sending payout before it is
+// broadcast, keeping the lease. It reports false when owner no longer
+// holds the lease.
+func (r *TokenTransferLogManager) SaveSigned(log *TokenTransferLog, owner string) (bool, error) {
+	columns := updateColumns(log)
+	columns["status"] = StatusSending
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", log.ID, StatusSending, owner).
+		Updates(columns)
+	if result.Error != nil {
+		return false, fmt.Errorf("SaveSigned err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func updateColumns(log *TokenTransferLog) map[string]interface{} {
+	return map[string]interface{}{
+		"token_info_id":            log.TokenInfoID,
+		"workflow_id":              log.WorkflowID,
+		"schedule_run":             log.ScheduleRun,
+		"from_address":             log.FromAddress,
+		"to_address":               log.ToAddress,
+		"contract_address":         log.ContractAddress,
+		"amount":                   log.Amount,
+		"transfer_data":            log.TransferData,
+		"status":                   log.Status,
+		"retry_count":              log.RetryCount,
+		"transaction_hash":         log.TransactionHash,
+		"batch_id":                 log.BatchID,
+		"gas_limit":                log.GasLimit,
+		"max_fee_per_gas":          log.MaxFeePerGas,
+		"max_priority_fee_per_gas": log.MaxPriorityFeePerGas,
+		"failure_reason":           log.FailureReason,
+		"gas_limit_override":       log.GasLimitOverride,
+		"max_fee_per_gas_override": log.MaxFeePerGasOverride,
+		"priority_fee_override":    log.PriorityFeeOverride,
+		"updated_by":               log.UpdatedBy,
+		"updated_addr":             log.UpdatedAddr,
+		"updated_time":             time.Now(),
+	}
 }
 
 func (r *TokenTransferLogManager) Create(log *TokenTransferLog) error {
 	return r.db.Create(log).Error
 }
 
-func (r *TokenTransferLogManager) GetPendingTokenTransferLogs() ([]TokenTransferLog, error) {
+// ClaimPending moves up to limit unsent pending payouts to sending under a
+// lease held by owner. Rows locked by another dispatcher are skipped, so
+// concurrent dispatchers never claim the same payout.
+func (r *TokenTransferLogManager) ClaimPending(owner string, leaseExpires time.Time, maxRetry, limit int) ([]TokenTransferLog, error) {
 	var logs []TokenTransferLog
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND retry_count <= ? AND transaction_hash = ''", StatusPending, maxRetry).
+			Order("id ASC").
+			Limit(limit).
+			Find(&logs).Error
+		if err != nil || len(logs) == 0 {
+			return err
+		}
+		ids := make([]int, len(logs))
+		for i := range logs {
+			ids[i] = logs[i].ID
+			logs[i].Status = StatusSending
+			logs[i].LeaseOwner = owner
+			logs[i].LeaseExpiresTime = &leaseExpires
+		}
+		return tx.Model(&TokenTransferLog{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":             StatusSending,
+				"lease_owner":        owner,
+				"lease_expires_time": leaseExpires,
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ClaimPending err: %w", err)
+	}
+	return logs, nil
+}
+
+// RenewLease extends the lease of a sending payout. It reports false when
+// owner lost the lease.
+func (r *TokenTransferLogManager) RenewLease(id int, owner string, leaseExpires time.Time) (bool, error) {
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", id, StatusSending, owner).
+		Update("lease_expires_time", leaseExpires)
+	if result.Error != nil {
+		return false, fmt.Errorf("RenewLease err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RecoverExpiredLeases puts payouts whose dispatcher stopped renewing the
+// lease back to pending and returns them. An interrupted send counts as a
+// retry unless its signed transaction was saved, which is rebroadcast
+// instead of re-sent.
+func (r *TokenTransferLogManager) RecoverExpiredLeases(now time.Time) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND lease_expires_time < ?", StatusSending, now).
+			Find(&logs).Error
+		if err != nil || len(logs) == 0 {
+			return err
+		}
+		ids := make([]int, len(logs))
+		for i := range logs {
+			ids[i] = logs[i].ID
+			if logs[i].TransactionHash == "" {
+				logs[i].RetryCount++
+			}
+			logs[i].Status = StatusPending
+			logs[i].FailureReason = "lease expired"
+		}
+		return tx.Model(&TokenTransferLog{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":             StatusPending,
+				"lease_owner":        "",
+				"lease_expires_time": nil,
+				"retry_count":        gorm.Expr("retry_count + IF(transaction_hash = '', 1, 0)"),
+				"failure_reason":     "lease expired",
+			}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RecoverExpiredLeases err: %w", err)
+	}
+	return logs, nil
+}
+
+// ListExhausted returns unsent pending payouts that used up their retries.
+func (r *TokenTransferLogManager) ListExhausted(maxRetry, limit int) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Where("status = ? AND retry_count > ? AND transaction_hash = ''", StatusPending, maxRetry).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListExhausted err: %w", err)
+	}
+	return logs, nil
+}
+
+// MoveStatus changes the status of a payout that is still in one of from,
+// together with columns. It reports false when the payout moved on.
+func (r *TokenTransferLogManager) MoveStatus(id int, from []string, to string, columns map[string]interface{}) (bool, error) {
+	updates := map[string]interface{}{"status": to, "updated_time": time.Now()}
+	for column, value := range columns {
+		updates[column] = value
+	}
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if result.Error != nil {
+		return false, fmt.Errorf("MoveStatus err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Requeue puts a pending payout whose transaction txHash was dropped back
+// in line to be sent again, on its own, counting it as a retry. It reports
+// false when the payout moved on in the meantime.
+func (r *TokenTransferLogManager) Requeue(id int, txHash, reason string) (bool, error) {
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("id = ? AND status = ? AND transaction_hash = ?", id, StatusPending, txHash).
+		Updates(map[string]interface{}{
+			"transaction_hash": "",
+			"batch_id":         0,
+			"retry_count":      gorm.Expr("retry_count + 1"),
+			"failure_reason":   reason,
+			"updated_by":       "system",
+			"updated_addr":     "system",
+			"updated_time":     time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("Requeue err: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *TokenTransferLogManager) GetByID(id int) (*TokenTransferLog, error) {
+	var log TokenTransferLog
+	err := r.db.First(&log, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetByID err: %w", err)
+	}
+	return &log, nil
+}
 
-	err := r.db.Where("status = ? AND retry_count <= ? and transaction_hash = ''", "pending", 3).
-		Limit(10).
+// GetByIDForUpdate locks the payout row until the end of the transaction.
+func (r *TokenTransferLogManager) GetByIDForUpdate(id int) (*TokenTransferLog, error) {
+	var log TokenTransferLog
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&log, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("GetByIDForUpdate err: %w", err)
+	}
+	return &log, nil
+}
+
+// PageByStatuses returns payouts in one of statuses, newest first.
+func (r *TokenTransferLogManager) PageByStatuses(statuses []string, offset, limit uint64) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Where("status IN ?", statuses).
+		Order("id DESC").
+		Offset(int(offset)).Limit(int(limit)).
 		Find(&logs).Error
 	if err != nil {
-		return nil, fmt.Errorf("GetPendingTokenTransferLogs err: %w", err)
+		return nil, fmt.Errorf("PageByStatuses err: %w", err)
+	}
+	return logs, nil
+}
+
+func (r *TokenTransferLogManager) CountByStatuses(statuses []string) (uint64, error) {
+	var count int64
+	err := r.db.Model(&TokenTransferLog{}).Where("status IN ?", statuses).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("CountByStatuses err: %w", err)
 	}
+	return uint64(count), nil
+}
 
+// ListByWorkflowID returns the payouts of a workflow, oldest first.
+func (r *TokenTransferLogManager) ListByWorkflowID(workflowID int) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Where("workflow_id = ?", workflowID).Order("id ASC").Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListByWorkflowID err: %w", err)
+	}
 	return logs, nil
 }
 
-func (r *TokenTransferLogManager) GetByTxHashAndAddresses(txHash, from, to string) (*TokenTransferLog, error) {
+// ListStuckPending returns pending payouts that were broadcast but not
+// settled, whose latest transaction was sent before sentBefore. Payouts of
+// a disperse batch share its transaction and are not replaced one by one.
+func (r *TokenTransferLogManager) ListStuckPending(sentBefore time.Time, limit int) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Where("status = ? AND transaction_hash <> '' AND batch_id = 0 AND updated_time < ?", StatusPending, sentBefore).
+		Order("id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListStuckPending err: %w", err)
+	}
+	return logs, nil
+}
+
+// GetPendingByTxHashAndFrom finds the unsettled payout sent in txHash. A
+// sending payout is included, its transaction can be mined before the
+// dispatcher gives up the lease.
+func (r *TokenTransferLogManager) GetPendingByTxHashAndFrom(txHash, from string) (*TokenTransferLog, error) {
 	var log TokenTransferLog
-	err := r.db.Where("transaction_hash = ? AND from_address = ? and status = 'pending'", txHash, from).
+	err := r.db.Where("transaction_hash = ? AND from_address = ? AND status IN ?", txHash, from, []string{StatusPending, StatusSending}).
 		First(&log).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("GetByTxHashAndAddresses err: %w", err)
+		return nil, fmt.Errorf("GetPendingByTxHashAndFrom err: %w", err)
 	}
 	return &log, nil
 }
+
+// ListUnsettledByBatch returns the payouts of batchID that still wait for
+// its transaction txHash, oldest first.
+func (r *TokenTransferLogManager) ListUnsettledByBatch(batchID int, txHash string) ([]TokenTransferLog, error) {
+	var logs []TokenTransferLog
+	err := r.db.Where("batch_id = ? AND transaction_hash = ? AND status IN ?", batchID, txHash, []string{StatusPending, StatusSending}).
+		Order("id ASC").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListUnsettledByBatch err: %w", err)
+	}
+	return logs, nil
+}
+
+// ListWorkflowIDsSettledByTxHashes returns the workflows whose payouts were
+// settled by the given transactions.
+func (r *TokenTransferLogManager) ListWorkflowIDsSettledByTxHashes(txHashes []string) ([]int, error) {
+	var workflowIDs []int
+	if len(txHashes) == 0 {
+		return workflowIDs, nil
+	}
+	err := r.db.Model(&TokenTransferLog{}).
+		Where("transaction_hash IN ? AND status IN ?", txHashes, []string{StatusSuccess, StatusFailed}).
+		Pluck("workflow_id", &workflowIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("ListWorkflowIDsSettledByTxHashes err: %w", err)
+	}
+	return workflowIDs, nil
+}
+
+// RevertSettledByTxHashes puts payouts settled by the given transactions
+// back to pending, e.g. when their block was dropped by a reorg.
+func (r *TokenTransferLogManager) RevertSettledByTxHashes(txHashes []string, updatedBy string) (int64, error) {
+	if len(txHashes) == 0 {
+		return 0, nil
+	}
+	result := r.db.Model(&TokenTransferLog{}).
+		Where("transaction_hash IN ? AND status IN ?", txHashes, []string{StatusSuccess, StatusFailed}).
+		Updates(map[string]interface{}{
+			"status":         StatusPending,
+			"failure_reason": "",
+			"updated_by":     updatedBy,
+			"updated_addr":   "system",
+			"updated_time":   time.Now(),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("RevertSettledByTxHashes err: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
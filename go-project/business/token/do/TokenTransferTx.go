@@ -0,0 +1,134 @@
+package do
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TxTypeOriginal = "original"
+	TxTypeSpeedUp  = "speed_up"
+	TxTypeCancel   = "cancel"
+)
+
+const (
+	TxStatusSigned    = "signed"    // persisted, broadcast not confirmed by the node yet
+	TxStatusBroadcast = "broadcast" // accepted by the node
+	TxStatusDropped   = "dropped"   // its nonce was used by another transaction
+)
+
+// TokenTransferTx records every transaction broadcast for a payout. A
+// payout can have several when stuck transactions are replaced, and the
+// scanner settles the payout with whichever one is mined. Payout
+// transactions are saved signed before they are broadcast, so RawTx can be
+// rebroadcast after a crash.
+type TokenTransferTx struct {
+	ID                   int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	TokenTransferLogID   int       `gorm:"column:token_transfer_log_id;not null;index:idx_token_transfer_log_id" json:"token_transfer_log_id"`
+	TxHash               string    `gorm:"column:tx_hash;not null;type:VARCHAR(66);uniqueIndex:uk_tx_hash" json:"tx_hash"`
+	TxType               string    `gorm:"column:tx_type;not null;type:ENUM('original','speed_up','cancel');default:original" json:"tx_type"`
+	FromAddress          string    `gorm:"column:from_address;not null;type:VARCHAR(42)" json:"from_address"`
+	Nonce                uint64    `gorm:"column:nonce;not null" json:"nonce"`
+	GasLimit             uint64    `gorm:"column:gas_limit;not null" json:"gas_limit"`
+	MaxFeePerGas         string    `gorm:"column:max_fee_per_gas;not null;type:VARCHAR(78)" json:"max_fee_per_gas"`
+	MaxPriorityFeePerGas string    `gorm:"column:max_priority_fee_per_gas;not null;type:VARCHAR(78)" json:"max_priority_fee_per_gas"`
+	RawTx                string    `gorm:"column:raw_tx;type:TEXT" json:"-"`
+	Status               string    `gorm:"column:status;not null;type:ENUM('signed','broadcast','dropped');default:broadcast;index:idx_status" json:"status"`
+	CreatedTime          time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (TokenTransferTx) TableName() string {
+	return "token_transfer_tx"
+}
+
+type TokenTransferTxManager struct {
+	db *gorm.DB
+}
+
+func NewTokenTransferTxManager(db *gorm.DB) *TokenTransferTxManager {
+	return &TokenTransferTxManager{db: db}
+}
+
+func (m *TokenTransferTxManager) Create(tx *TokenTransferTx) error {
+	if err := m.db.Create(tx).Error; err != nil {
+		return fmt.Errorf("TokenTransferTxManager Create: %w", err)
+	}
+	return nil
+}
+
+func (m *TokenTransferTxManager) GetByTxHash(txHash string) (*TokenTransferTx, error) {
+	var tx TokenTransferTx
+	err := m.db.Where("tx_hash = ?", txHash).First(&tx).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("TokenTransferTxManager GetByTxHash: %w", err)
+	}
+	return &tx, nil
+}
+
+// ListByTransferLogID returns the payout's transactions, oldest first.
+func (m *TokenTransferTxManager) ListByTransferLogID(transferLogID int) ([]TokenTransferTx, error) {
+	var txs []TokenTransferTx
+	err := m.db.Where("token_transfer_log_id = ?", transferLogID).Order("id ASC").Find(&txs).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenTransferTxManager ListByTransferLogID: %w", err)
+	}
+	return txs, nil
+}
+
+// ListByTransferLogIDs returns the transactions of several payouts, oldest
+// first.
+func (m *TokenTransferTxManager) ListByTransferLogIDs(transferLogIDs []int) ([]TokenTransferTx, error) {
+	var txs []TokenTransferTx
+	if len(transferLogIDs) == 0 {
+		return txs, nil
+	}
+	err := m.db.Where("token_transfer_log_id IN ?", transferLogIDs).Order("id ASC").Find(&txs).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenTransferTxManager ListByTransferLogIDs: %w", err)
+	}
+	return txs, nil
+}
+
+// MarkBroadcast records that the node accepted a signed transaction.
+func (m *TokenTransferTxManager) MarkBroadcast(txHash string) error {
+	return m.setStatus("MarkBroadcast", txHash, []string{TxStatusSigned}, TxStatusBroadcast)
+}
+
+// MarkDropped records that a transaction can no longer be mined.
+func (m *TokenTransferTxManager) MarkDropped(txHash string) error {
+	return m.setStatus("MarkDropped", txHash, []string{TxStatusSigned, TxStatusBroadcast}, TxStatusDropped)
+}
+
+func (m *TokenTransferTxManager) setStatus(method, txHash string, from []string, to string) error {
+	err := m.db.Model(&TokenTransferTx{}).
+		Where("tx_hash = ? AND status IN ?", txHash, from).
+		Update("status", to).Error
+	if err != nil {
+		return fmt.Errorf("TokenTransferTxManager %s: %w", method, err)
+	}
+	return nil
+}
+
+// ListUnconfirmed returns the current transactions of unsettled payouts
+// that are in one of statuses and can be rebroadcast, oldest first.
+func (m *TokenTransferTxManager) ListUnconfirmed(statuses []string, limit int) ([]TokenTransferTx, error) {
+	var txs []TokenTransferTx
+	err := m.db.Table("token_transfer_tx AS t").
+		Select("t.*").
+		Joins("JOIN token_transfer_log AS l ON l.id = t.token_transfer_log_id").
+		Where("t.status IN ? AND t.raw_tx <> '' AND l.transaction_hash = t.tx_hash AND l.status IN ?",
+			statuses, []string{StatusPending, StatusSending}).
+		Order("t.id ASC").
+		Limit(limit).
+		Find(&txs).Error
+	if err != nil {
+		return nil, fmt.Errorf("TokenTransferTxManager ListUnconfirmed: %w", err)
+	}
+	return txs, nil
+}
@@ -0,0 +1,25 @@
+package dto
+
+// TokenInfoCreateDTO registers an ERC-20 token or the native asset. For
+// ERC-20 tokens name, symbol and decimals are optional; when given they must
+// match what the contract reports.
+type TokenInfoCreateDTO struct {
+	TokenType       string `json:"token_type" binding:"omitempty,oneof=erc20 native"`
+	ContractAddress string `json:"contract_address" binding:"required_unless=TokenType native,max=64"`
+	TokenName       string `json:"token_name" binding:"max=100"`
+	TokenSymbol     string `json:"token_symbol" binding:"max=64"`
+	Decimals        *int   `json:"decimals" binding:"omitempty,min=0,max=255"`
+}
+
+// TokenInfoUpdateDTO re-reads the token metadata from the contract.
+type TokenInfoUpdateDTO struct {
+	ID int `json:"id" binding:"required"`
+}
+
+type TokenInfoDetailDTO struct {
+	ID int `form:"id" binding:"required"`
+}
+
+type TokenInfoDeleteDTO struct {
+	ID int `json:"id" binding:"required"`
+}
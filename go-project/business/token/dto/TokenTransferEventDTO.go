@@ -0,0 +1,15 @@
+package dto
+
+import "go-project/common/types"
+
+type TokenTransferEventPageDTO struct {
+	types.PageReq
+	TokenAddress string `form:"token_address" binding:"omitempty,max=64"`
+	EventType    string `form:"event_type" binding:"omitempty,oneof=transfer approval"`
+	FromAddress  string `form:"from_address" binding:"omitempty,max=64"`
+	ToAddress    string `form:"to_address" binding:"omitempty,max=64"`
+	Address      string `form:"address" binding:"omitempty,max=64"`
+	TxHash       string `form:"tx_hash" binding:"omitempty,max=128"`
+	BlockFrom    uint64 `form:"block_from"`
+	BlockTo      uint64 `form:"block_to"`
+}
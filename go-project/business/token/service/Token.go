@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	"go-project/business/token/dto"
+	workflowdo "go-project/business/workflow/do"
+	"go-project/chain/eth"
+	"go-project/common/types"
+	"go-project/main/log"
+)
+
+// NotAllowedToManageTokenError means the caller is not an active member with
+// full permission. The dispatcher and the scanner trust token_info, so only
+// those members may change it.
+var NotAllowedToManageTokenError = errors.New("NotAllowedToManageTokenError")
+
+type Service struct {
+	logger *log.ZapLogger
+	db     *gorm.DB
+}
+
+func NewService(logger *log.ZapLogger, db *gorm.DB) *Service {
+	return &Service{
+		logger: logger,
+		db:     db,
+	}
+}
+
+func (service *Service) PageTokenTransferEvents(req *dto.TokenTransferEventPageDTO) (*types.GenericPageResp[do.TokenTransferEvent], error) {
+	if req.PageNum == 0 {
+		req.PageNum = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 10
+	}
+
+	resp := &types.GenericPageResp[do.TokenTransferEvent]{
+		PageResp: types.PageResp{
+			PageNum:  req.PageNum,
+			PageSize: req.PageSize,
+		},
+	}
+
+	// addresses are stored checksummed by the scanner
+	query := &do.TokenTransferEventQuery{
+		TokenAddress: checksumAddress(req.TokenAddress),
+		EventType:    req.EventType,
+		FromAddress:  checksumAddress(req.FromAddress),
+		ToAddress:    checksumAddress(req.ToAddress),
+		Address:      checksumAddress(req.Address),
+		TxHash:       req.TxHash,
+		BlockFrom:    req.BlockFrom,
+		BlockTo:      req.BlockTo,
+	}
+
+	offset := (resp.PageNum - 1) * resp.PageSize
+
+	eventManager := do.NewTokenTransferEventManager(service.db)
+	list, err := eventManager.Page(query, offset, resp.PageSize)
+	if err != nil {
+		service.logger.Error("PageTokenTransferEvents Page", zap.Any("err", err))
+		return nil, err
+	}
+
+	total, err := eventManager.Count(query)
+	if err != nil {
+		service.logger.Error("PageTokenTransferEvents Count", zap.Any("err", err))
+		return nil, err
+	}
+
+	resp.List = list
+	resp.Total = total
+	resp.TotalPage = (total + resp.PageSize - 1) / resp.PageSize
+	return resp, nil
+}
+
+func (service *Service) ListTokenInfos() ([]do.TokenInfo, error) {
+	return do.NewTokenInfoManager(service.db).List()
+}
+
+func (service *Service) GetTokenInfo(id int) (*do.TokenInfo, error) {
+	tokenInfo, err := do.NewTokenInfoManager(service.db).GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if tokenInfo == nil {
+		return nil, fmt.Errorf("token info %d not found", id)
+	}
+	return tokenInfo, nil
+}
+
+// CreateTokenInfo registers a token with the metadata read from its
+// contract, rejecting user supplied values that disagree with the chain.
+func (service *Service) CreateTokenInfo(req *dto.TokenInfoCreateDTO, metadata *eth.TokenMetadata, callerAddr string) (*do.TokenInfo, error) {
+	return service.createTokenInfo(req, do.TokenTypeERC20, common.HexToAddress(req.ContractAddress).Hex(), metadata, callerAddr)
+}
+
+// CreateNativeTokenInfo registers the chain's native asset. It has no
+// contract, so name and symbol default to ETH's and decimals must be 18.
+func (service *Service) CreateNativeTokenInfo(req *dto.TokenInfoCreateDTO, callerAddr string) (*do.TokenInfo, error) {
+	metadata := &eth.TokenMetadata{Name: "Ether", Symbol: "ETH", Decimals: 18}
+	if req.TokenName != "" {
+		metadata.Name = req.TokenName
+	}
+	if req.TokenSymbol != "" {
+		metadata.Symbol = req.TokenSymbol
+	}
+	return service.createTokenInfo(req, do.TokenTypeNative, do.NativeTokenAddress, metadata, callerAddr)
+}
+
+func (service *Service) createTokenInfo(req *dto.TokenInfoCreateDTO, tokenType, contractAddress string, metadata *eth.TokenMetadata, callerAddr string) (*do.TokenInfo, error) {
+	if req.TokenName != "" && req.TokenName != metadata.Name {
+		return nil, fmt.Errorf("token name mismatch: contract reports %q", metadata.Name)
+	}
+	if req.TokenSymbol != "" && req.TokenSymbol != metadata.Symbol {
+		return nil, fmt.Errorf("token symbol mismatch: contract reports %q", metadata.Symbol)
+	}
+	if req.Decimals != nil && *req.Decimals != int(metadata.Decimals) {
+		return nil, fmt.Errorf("token decimals mismatch: contract reports %d", metadata.Decimals)
+	}
+
+	tokenInfo := &do.TokenInfo{
+		TokenName:       metadata.Name,
+		TokenSymbol:     metadata.Symbol,
+		ContractAddress: contractAddress,
+		Decimals:        int(metadata.Decimals),
+		TokenType:       tokenType,
+		CreateBy:        callerAddr,
+		CreateAddr:      callerAddr,
+	}
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := requireFullPermission(tx, callerAddr); err != nil {
+			return err
+		}
+		tokenInfoManager := do.NewTokenInfoManager(tx)
+		existing, err := tokenInfoManager.GetByContractAddress(contractAddress)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("token %s already registered with id %d", contractAddress, existing.ID)
+		}
+		return tokenInfoManager.Create(tokenInfo)
+	})
+	if err != nil {
+		service.logger.Error("CreateTokenInfo", zap.Error(err))
+		return nil, err
+	}
+	return tokenInfo, nil
+}
+
+// RefreshTokenInfo overwrites the stored metadata with what the contract
+// reports now.
+func (service *Service) RefreshTokenInfo(tokenInfo *do.TokenInfo, metadata *eth.TokenMetadata, callerAddr string) (*do.TokenInfo, error) {
+	if err := requireFullPermission(service.db, callerAddr); err != nil {
+		return nil, err
+	}
+	if tokenInfo.Decimals != int(metadata.Decimals) {
+		count, err := workflowdo.NewWorkFlowInfoManager(service.db).CountByTokenInfoID(tokenInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		// stored amounts are base units of the old decimals
+		if count > 0 {
+			return nil, fmt.Errorf("token decimals changed from %d to %d but %d workflows use it", tokenInfo.Decimals, metadata.Decimals, count)
+		}
+	}
+
+	tokenInfo.TokenName = metadata.Name
+	tokenInfo.TokenSymbol = metadata.Symbol
+	tokenInfo.Decimals = int(metadata.Decimals)
+	tokenInfo.UpdatedBy = callerAddr
+	tokenInfo.UpdatedAddr = callerAddr
+	if err := do.NewTokenInfoManager(service.db).Update(tokenInfo); err != nil {
+		service.logger.Error("RefreshTokenInfo", zap.Error(err))
+		return nil, err
+	}
+	return tokenInfo, nil
+}
+
+// DeleteTokenInfo removes a token no workflow references.
+func (service *Service) DeleteTokenInfo(id int, callerAddr string) error {
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		if err := requireFullPermission(tx, callerAddr); err != nil {
+			return err
+		}
+		count, err := workflowdo.NewWorkFlowInfoManager(tx).CountByTokenInfoID(id)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("token info %d is used by %d workflows", id, count)
+		}
+		return do.NewTokenInfoManager(tx).Delete(id)
+	})
+}
+
+func requireFullPermission(db *gorm.DB, callerAddr string) error {
+	hasFullPermission, err := workflowdo.NewManagementManager(db).HasFullPermission(callerAddr)
+	if err != nil {
+		return fmt.Errorf("check permission error: %w", err)
+	}
+	if !hasFullPermission {
+		return fmt.Errorf("%w: %s", NotAllowedToManageTokenError, callerAddr)
+	}
+	return nil
+}
+
+func checksumAddress(addr string) string {
+	if addr == "" || !common.IsHexAddress(addr) {
+		return addr
+	}
+	return common.HexToAddress(addr).Hex()
+}
@@ -0,0 +1,157 @@
+package business
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	"go-project/business/token/dto"
+	"go-project/business/token/service"
+	"go-project/chain/eth"
+	"go-project/common/web"
+	"go-project/main/log"
+)
+
+func TokenTransferEventList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.TokenTransferEventPageDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("TokenTransferEventList ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	pageResp, err := service.NewService(log, db).PageTokenTransferEvents(&input)
+	if err != nil {
+		log.Error("TokenTransferEventList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, pageResp)
+}
+
+func TokenInfoList(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	list, err := service.NewService(log, db).ListTokenInfos()
+	if err != nil {
+		log.Error("TokenInfoList service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, list)
+}
+
+func TokenInfoDetail(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.TokenInfoDetailDTO
+	if err := c.ShouldBindQuery(&input); err != nil {
+		log.Error("TokenInfoDetail ShouldBindQuery", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	tokenInfo, err := service.NewService(log, db).GetTokenInfo(input.ID)
+	if err != nil {
+		log.Error("TokenInfoDetail service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, tokenInfo)
+}
+
+func CreateTokenInfo(c *gin.Context, db *gorm.DB, log *log.ZapLogger, tokens *eth.TokenRegistry) {
+	var input dto.TokenInfoCreateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("CreateTokenInfo ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	tokenService := service.NewService(log, db)
+	if input.TokenType == do.TokenTypeNative {
+		tokenInfo, err := tokenService.CreateNativeTokenInfo(&input, web.CallerAddress(c))
+		if err != nil {
+			log.Error("CreateTokenInfo service error", zap.Error(err))
+			web.Fail(c, err.Error())
+			return
+		}
+		web.Success(c, tokenInfo)
+		return
+	}
+
+	if !common.IsHexAddress(input.ContractAddress) {
+		web.Fail(c, "invalid contract address")
+		return
+	}
+
+	metadata, err := tokens.Metadata(c.Request.Context(), common.HexToAddress(input.ContractAddress))
+	if err != nil {
+		log.Error("CreateTokenInfo Metadata", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	tokenInfo, err := tokenService.CreateTokenInfo(&input, metadata, web.CallerAddress(c))
+	if err != nil {
+		log.Error("CreateTokenInfo service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, tokenInfo)
+}
+
+func UpdateTokenInfo(c *gin.Context, db *gorm.DB, log *log.ZapLogger, tokens *eth.TokenRegistry) {
+	var input dto.TokenInfoUpdateDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("UpdateTokenInfo ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	tokenService := service.NewService(log, db)
+	tokenInfo, err := tokenService.GetTokenInfo(input.ID)
+	if err != nil {
+		web.Fail(c, err.Error())
+		return
+	}
+	if tokenInfo.IsNative() {
+		web.Fail(c, "native token has no contract metadata to refresh")
+		return
+	}
+
+	metadata, err := tokens.Metadata(c.Request.Context(), common.HexToAddress(tokenInfo.ContractAddress))
+	if err != nil {
+		log.Error("UpdateTokenInfo Metadata", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	tokenInfo, err = tokenService.RefreshTokenInfo(tokenInfo, metadata, web.CallerAddress(c))
+	if err != nil {
+		log.Error("UpdateTokenInfo service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, tokenInfo)
+}
+
+func DeleteTokenInfo(c *gin.Context, db *gorm.DB, log *log.ZapLogger) {
+	var input dto.TokenInfoDeleteDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error("DeleteTokenInfo ShouldBindJSON", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	if err := service.NewService(log, db).DeleteTokenInfo(input.ID, web.CallerAddress(c)); err != nil {
+		log.Error("DeleteTokenInfo service error", zap.Error(err))
+		web.Fail(c, err.Error())
+		return
+	}
+
+	web.Success(c, nil)
+}
@@ -0,0 +1,94 @@
+package do
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultRequiredWeight is the quorum used when no approval_policy row
+// matches a workflow: two approvers of weight 1.
+const DefaultRequiredWeight = 2
+
+// ApprovalPolicy decides when a workflow is approved or rejected. TokenInfoID
+// 0 applies to every token; MinAmount is the inclusive lower bound, in token
+// base units, of the amount tier the policy covers. A single rejection from
+// a member of a veto level rejects the workflow outright.
+type ApprovalPolicy struct {
+	ID             int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Name           string    `gorm:"column:name;not null;type:VARCHAR(100)" json:"name"`
+	TokenInfoID    int       `gorm:"column:token_info_id;not null;default:0" json:"token_info_id"`
+	MinAmount      string    `gorm:"column:min_amount;not null;type:DECIMAL(65,0);default:0" json:"min_amount"`
+	RequiredWeight int       `gorm:"column:required_weight;not null;default:2" json:"required_weight"`
+	ApproverLevels string    `gorm:"column:approver_levels;not null;type:SET('none','partial','full');default:partial,full" json:"approver_levels"`
+	RejectWeight   int       `gorm:"column:reject_weight;not null;default:2" json:"reject_weight"`
+	VetoLevels     string    `gorm:"column:veto_levels;not null;type:SET('none','partial','full');default:full" json:"veto_levels"`
+	CreateBy       string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
+	CreateAddr     string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
+	CreatedTime    time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedBy      string    `gorm:"column:updated_by;type:VARCHAR(64)" json:"updated_by"`
+	UpdatedAddr    string    `gorm:"column:updated_addr;type:VARCHAR(64)" json:"updated_addr"`
+	UpdatedTime    time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+}
+
+func (ApprovalPolicy) TableName() string {
+	return "approval_policy"
+}
+
+// AllowsLevel reports whether management members of permissionLevel may
+// approve under the policy.
+func (p *ApprovalPolicy) AllowsLevel(permissionLevel string) bool {
+	return containsLevel(p.ApproverLevels, permissionLevel)
+}
+
+// VetoesLevel reports whether a rejection from permissionLevel alone
+// rejects the workflow.
+func (p *ApprovalPolicy) VetoesLevel(permissionLevel string) bool {
+	return containsLevel(p.VetoLevels, permissionLevel)
+}
+
+func containsLevel(levels, permissionLevel string) bool {
+	for _, level := range strings.Split(levels, ",") {
+		if strings.TrimSpace(level) == permissionLevel {
+			return true
+		}
+	}
+	return false
+}
+
+type ApprovalPolicyManager struct {
+	db *gorm.DB
+}
+
+func NewApprovalPolicyManager(db *gorm.DB) *ApprovalPolicyManager {
+	return &ApprovalPolicyManager{db: db}
+}
+
+func (m *ApprovalPolicyManager) Create(policy *ApprovalPolicy) error {
+	if err := m.db.Create(policy).Error; err != nil {
+		return fmt.Errorf("ApprovalPolicyManager Create: %w", err)
+	}
+	return nil
+}
+
+func (m *ApprovalPolicyManager) List() ([]ApprovalPolicy, error) {
+	var policies []ApprovalPolicy
+	err := m.db.Order("token_info_id ASC, min_amount ASC").Find(&policies).Error
+	if err != nil {
+		return nil, fmt.Errorf("ApprovalPolicyManager List: %w", err)
+	}
+	return policies, nil
+}
+
+// ListForToken returns the policies of the token plus the ones applying to
+// every token.
+func (m *ApprovalPolicyManager) ListForToken(tokenInfoID int) ([]ApprovalPolicy, error) {
+	var policies []ApprovalPolicy
+	err := m.db.Where("token_info_id IN ?", []int{0, tokenInfoID}).Find(&policies).Error
+	if err != nil {
+		return nil, fmt.Errorf("ApprovalPolicyManager ListForToken: %w", err)
+	}
+	return policies, nil
+}
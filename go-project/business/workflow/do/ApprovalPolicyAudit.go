@@ -0,0 +1,46 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const ApprovalPolicyActionCreate = "create"
+
+// ApprovalPolicyAudit records one change to the approval policies, with the
+// policy as it was written.
+type ApprovalPolicyAudit struct {
+	ID             int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	PolicyID       int       `gorm:"column:policy_id;not null;index:idx_policy_id" json:"policy_id"`
+	Action         string    `gorm:"column:action;not null;type:ENUM('create')" json:"action"`
+	TokenInfoID    int       `gorm:"column:token_info_id;not null;default:0" json:"token_info_id"`
+	MinAmount      string    `gorm:"column:min_amount;not null;type:DECIMAL(65,0);default:0" json:"min_amount"`
+	RequiredWeight int       `gorm:"column:required_weight;not null;default:0" json:"required_weight"`
+	ApproverLevels string    `gorm:"column:approver_levels;not null;type:VARCHAR(32);default:''" json:"approver_levels"`
+	RejectWeight   int       `gorm:"column:reject_weight;not null;default:0" json:"reject_weight"`
+	VetoLevels     string    `gorm:"column:veto_levels;not null;type:VARCHAR(32);default:''" json:"veto_levels"`
+	Reason         string    `gorm:"column:reason;not null;type:VARCHAR(512);default:''" json:"reason"`
+	OperatorAddr   string    `gorm:"column:operator_addr;not null;type:VARCHAR(64)" json:"operator_addr"`
+	CreatedTime    time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (ApprovalPolicyAudit) TableName() string {
+	return "approval_policy_audit"
+}
+
+type ApprovalPolicyAuditManager struct {
+	db *gorm.DB
+}
+
+func NewApprovalPolicyAuditManager(db *gorm.DB) *ApprovalPolicyAuditManager {
+	return &ApprovalPolicyAuditManager{db: db}
+}
+
+func (m *ApprovalPolicyAuditManager) Create(audit *ApprovalPolicyAudit) error {
+	if err := m.db.Create(audit).Error; err != nil {
+		return fmt.Errorf("ApprovalPolicyAuditManager Create: %w", err)
+	}
+	return nil
+}
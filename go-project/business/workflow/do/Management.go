@@ -2,17 +2,30 @@ package do
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PermissionLevelNone    = "none"
+	PermissionLevelPartial = "partial"
+	PermissionLevelFull    = "full"
+
+	ManagementStatusActive   = "active"
+	ManagementStatusInactive = "inactive"
 )
 
 type Management struct {
 	ID              int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
 	Name            string    `gorm:"column:name;not null;type:VARCHAR(100)" json:"name"`
 	PermissionLevel string    `gorm:"column:permission_level;type:ENUM('none','partial','full');default:none" json:"permission_level"`
-	Addr            string    `gorm:"column:addr;not null;type:VARCHAR(64)" json:"addr"`
+	Weight          int       `gorm:"column:weight;not null;default:1" json:"weight"`
+	Addr            string    `gorm:"column:addr;not null;type:VARCHAR(64);uniqueIndex:uk_addr" json:"addr"`
 	AnvilInfo       string    `gorm:"column:anvil_info;not null;type:VARCHAR(64)" json:"anvil_info"`
+	Status          string    `gorm:"column:status;not null;type:ENUM('active','inactive');default:active" json:"status"` // inactive members keep their past votes but cannot act
 	CreateBy        string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
 	CreateAddr      string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
 	CreatedTime     time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
@@ -33,9 +46,84 @@ func NewManagementManager(db *gorm.DB) *ManagementManager {
 	return &ManagementManager{db: db}
 }
 
+// List returns every member, inactive ones included.
+func (m *ManagementManager) List() ([]Management, error) {
+	var managements []Management
+	if err := m.db.Order("id ASC").Find(&managements).Error; err != nil {
+		return nil, fmt.Errorf("ManagementManager List: %w", err)
+	}
+	return managements, nil
+}
+
+// GetByIDForUpdate locks the member row until the transaction ends.
+func (m *ManagementManager) GetByIDForUpdate(id int) (*Management, error) {
+	var management Management
+	err := m.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&management, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ManagementManager GetByIDForUpdate: %w", err)
+	}
+	return &management, nil
+}
+
+func (m *ManagementManager) GetByAddr(addr string) (*Management, error) {
+	var management Management
+	err := m.db.Where("addr = ?", addr).First(&management).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ManagementManager GetByAddr: %w", err)
+	}
+	return &management, nil
+}
+
+func (m *ManagementManager) Create(management *Management) error {
+	if err := m.db.Create(management).Error; err != nil {
+		return fmt.Errorf("ManagementManager Create: %w", err)
+	}
+	return nil
+}
+
+// UpdateMembership saves the permission level, weight and status of the
+// member.
+func (m *ManagementManager) UpdateMembership(management *Management) error {
+	err := m.db.Model(management).Select("permission_level", "weight", "status", "updated_by", "updated_addr").Updates(management).Error
+	if err != nil {
+		return fmt.Errorf("ManagementManager UpdateMembership: %w", err)
+	}
+	return nil
+}
+
+// ListActiveFullForUpdate locks the active members with full permission
+// until the transaction ends, in id order.
+func (m *ManagementManager) ListActiveFullForUpdate() ([]Management, error) {
+	var managements []Management
+	err := m.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("permission_level = ? AND status = ?", PermissionLevelFull, ManagementStatusActive).
+		Order("id ASC").
+		Find(&managements).Error
+	if err != nil {
+		return nil, fmt.Errorf("ManagementManager ListActiveFullForUpdate: %w", err)
+	}
+	return managements, nil
+}
+
+// ListByAddrs returns the active management members among addrs.
+func (m *ManagementManager) ListByAddrs(addrs []string) ([]Management, error) {
+	var managements []Management
+	if len(addrs) == 0 {
+		return managements, nil
+	}
+	err := m.db.Where("addr IN ? AND status = ?", addrs, ManagementStatusActive).Find(&managements).Error
+	return managements, err
+}
+
 func (m *ManagementManager) HasFullPermission(addr string) (bool, error) {
 	var management Management
-	result := m.db.Where("addr = ? AND permission_level = ?", addr, "full").First(&management)
+	result := m.db.Where("addr = ? AND permission_level = ? AND status = ?", addr, PermissionLevelFull, ManagementStatusActive).First(&management)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return false, nil
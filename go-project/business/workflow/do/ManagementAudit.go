@@ -0,0 +1,64 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ManagementActionCreate     = "create"
+	ManagementActionUpdate     = "update"
+	ManagementActionDeactivate = "deactivate"
+)
+
+// ManagementAudit records one change to a management member, with the
+// member's permission before and after it.
+type ManagementAudit struct {
+	ID           int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	ManagementID int       `gorm:"column:management_id;not null;index:idx_management_id" json:"management_id"`
+	Addr         string    `gorm:"column:addr;not null;type:VARCHAR(64)" json:"addr"`
+	Action       string    `gorm:"column:action;not null;type:ENUM('create','update','deactivate')" json:"action"`
+	OldLevel     string    `gorm:"column:old_level;not null;type:VARCHAR(16);default:''" json:"old_level"`
+	NewLevel     string    `gorm:"column:new_level;not null;type:VARCHAR(16);default:''" json:"new_level"`
+	OldWeight    int       `gorm:"column:old_weight;not null;default:0" json:"old_weight"`
+	NewWeight    int       `gorm:"column:new_weight;not null;default:0" json:"new_weight"`
+	OldStatus    string    `gorm:"column:old_status;not null;type:VARCHAR(16);default:''" json:"old_status"`
+	NewStatus    string    `gorm:"column:new_status;not null;type:VARCHAR(16);default:''" json:"new_status"`
+	Reason       string    `gorm:"column:reason;not null;type:VARCHAR(512);default:''" json:"reason"`
+	OperatorAddr string    `gorm:"column:operator_addr;not null;type:VARCHAR(64)" json:"operator_addr"`
+	CreatedTime  time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (ManagementAudit) TableName() string {
+	return "management_audit"
+}
+
+type ManagementAuditManager struct {
+	db *gorm.DB
+}
+
+func NewManagementAuditManager(db *gorm.DB) *ManagementAuditManager {
+	return &ManagementAuditManager{db: db}
+}
+
+func (m *ManagementAuditManager) Create(audit *ManagementAudit) error {
+	if err := m.db.Create(audit).Error; err != nil {
+		return fmt.Errorf("ManagementAuditManager Create: %w", err)
+	}
+	return nil
+}
+
+// List returns the audit trail, newest first, optionally of one member.
+func (m *ManagementAuditManager) List(managementID int, limit int) ([]ManagementAudit, error) {
+	var audits []ManagementAudit
+	query := m.db.Order("id DESC").Limit(limit)
+	if managementID > 0 {
+		query = query.Where("management_id = ?", managementID)
+	}
+	if err := query.Find(&audits).Error; err != nil {
+		return nil, fmt.Errorf("ManagementAuditManager List: %w", err)
+	}
+	return audits, nil
+}
@@ -1,6 +1,7 @@
 package do
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,9 +9,11 @@ import (
 
 type WorkFlowApprove struct {
 	ID          int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	WorkflowID  int       `gorm:"column:workflow_id" json:"workflow_id"`
-	ApproveAddr string    `gorm:"column:approve_addr;not null;type:VARCHAR(64)" json:"approve_addr"`
+	WorkflowID  int       `gorm:"column:workflow_id;uniqueIndex:uk_workflow_approve_addr" json:"workflow_id"`
+	ApproveAddr string    `gorm:"column:approve_addr;not null;type:VARCHAR(64);uniqueIndex:uk_workflow_approve_addr" json:"approve_addr"`
 	Status      string    `gorm:"column:status;type:ENUM('approved','rejected');default:rejected" json:"status"`
+	Deadline    uint64    `gorm:"column:deadline;not null;default:0" json:"deadline"`
+	Signature   string    `gorm:"column:signature;not null;type:VARCHAR(132);default:''" json:"signature"` // EIP-712, hex
 	ApproveTime time.Time `gorm:"column:approve_time" json:"approve_time"`
 	CreateBy    string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
 	CreateAddr  string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
@@ -36,6 +39,36 @@ func (m *WorkFlowApproveManager) Create(approve *WorkFlowApprove) error {
 	return m.db.Create(approve).Error
 }
 
+func (m *WorkFlowApproveManager) GetByWorkflowIDAndAddr(workflowID int, addr string) (*WorkFlowApprove, error) {
+	var approve WorkFlowApprove
+	err := m.db.Where("workflow_id = ? AND approve_addr = ?", workflowID, addr).First(&approve).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &approve, nil
+}
+
+// ListByWorkflowID returns every vote on a workflow in the order cast.
+func (m *WorkFlowApproveManager) ListByWorkflowID(workflowID int) ([]WorkFlowApprove, error) {
+	var approves []WorkFlowApprove
+	err := m.db.Where("workflow_id = ?", workflowID).Order("id ASC").Find(&approves).Error
+	return approves, err
+}
+
+// ListAddressesByStatus returns the addresses that voted status on the
+// workflow.
+func (m *WorkFlowApproveManager) ListAddressesByStatus(workflowID int, status string) ([]string, error) {
+	var addrs []string
+	err := m.db.Model(&WorkFlowApprove{}).
+		Where("workflow_id = ? AND status = ?", workflowID, status).
+		Distinct().
+		Pluck("approve_addr", &addrs).Error
+	return addrs, err
+}
+
 func (m *WorkFlowApproveManager) CountUniqueApprovedAddresses(workflowID int) (int64, error) {
 	var count int64
 	err := m.db.Model(&WorkFlowApprove{}).
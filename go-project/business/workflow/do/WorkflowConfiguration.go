@@ -0,0 +1,64 @@
+package do
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ConfigCodeEthFinalizeNum     = "eth_finalize_num"
+	ConfigCodeScanStartBlockNum  = "scan_start_block_num"
+	ConfigCodeScanSingleQuantity = "scan_single_quantity"
+)
+
+type WorkflowConfiguration struct {
+	ID          int    `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	Code        string `gorm:"column:code;not null;index;type:VARCHAR(64)" json:"code"`
+	Value       string `gorm:"column:value;not null;type:VARCHAR(64)" json:"value"`
+	Description string `gorm:"column:description;not null;type:VARCHAR(1024)" json:"description"`
+}
+
+func (WorkflowConfiguration) TableName() string {
+	return "workflow_configuration"
+}
+
+type WorkflowConfigurationManager struct {
+	db *gorm.DB
+}
+
+func NewWorkflowConfigurationManager(db *gorm.DB) *WorkflowConfigurationManager {
+	return &WorkflowConfigurationManager{db: db}
+}
+
+func (m *WorkflowConfigurationManager) GetByCode(code string) (*WorkflowConfiguration, error) {
+	var configuration WorkflowConfiguration
+	err := m.db.Where("code = ?", code).First(&configuration).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("WorkflowConfigurationManager GetByCode: %w", err)
+	}
+	return &configuration, nil
+}
+
+// GetUint64 returns the numeric value stored under code, or defaultValue
+// when the row does not exist.
+func (m *WorkflowConfigurationManager) GetUint64(code string, defaultValue uint64) (uint64, error) {
+	configuration, err := m.GetByCode(code)
+	if err != nil {
+		return 0, err
+	}
+	if configuration == nil {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.ParseUint(configuration.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("workflow_configuration %s is not a number: %w", code, err)
+	}
+	return value, nil
+}
@@ -2,36 +2,119 @@ package do
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
-	WorkFlowStatusPending  = "pending"
-	WorkFlowStatusApproved = "approved"
-	WorkFlowStatusRejected = "rejected"
+	WorkFlowStatusPending   = "pending"
+	WorkFlowStatusApproved  = "approved"
+	WorkFlowStatusRejected  = "rejected"
+	WorkFlowStatusCancelled = "cancelled"
+	WorkFlowStatusExpired   = "expired"
+	WorkFlowStatusPaid      = "paid"
+	WorkFlowStatusFailed    = "failed"
 )
 
+const (
+	ScheduleTypeNone = "none" // paid once, as soon as approved
+	ScheduleTypeOnce = "once" // paid once at next_run_time
+	ScheduleTypeCron = "cron" // paid on every fire of schedule_cron
+)
+
+const (
+	ScheduleStatusNone      = "none"
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCancelled = "cancelled"
+	ScheduleStatusCompleted = "completed"
+)
+
+const (
+	WorkFlowSortID          = "id"
+	WorkFlowSortCreatedTime = "created_time"
+	WorkFlowSortUpdatedTime = "updated_time"
+	WorkFlowSortAmount      = "amount"
+	WorkFlowSortName        = "workflow_name"
+)
+
+// WorkFlowSortColumns whitelists the columns the page API may sort by.
+var WorkFlowSortColumns = map[string]string{
+	WorkFlowSortID:          "id",
+	WorkFlowSortCreatedTime: "created_time",
+	WorkFlowSortUpdatedTime: "updated_time",
+	WorkFlowSortAmount:      "amount",
+	WorkFlowSortName:        "workflow_name",
+}
+
+// WorkFlowInfoQuery holds the optional filters and the order of the
+// workflow page API, zero values are ignored.
+type WorkFlowInfoQuery struct {
+	Status      string
+	ToAddr      string
+	CreateAddr  string
+	TokenInfoID int
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Name        string // substring of workflow_name
+	SortBy      string // key of WorkFlowSortColumns, default id
+	Desc        bool
+	After       *WorkFlowInfoCursor
+}
+
+// WorkFlowInfoCursor is the position of the last row of the previous page:
+// its sort column value and id.
+type WorkFlowInfoCursor struct {
+	Value any
+	ID    int
+}
+
 type WorkFlowInfo struct {
-	ID           int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
-	WorkflowName string    `gorm:"column:workflow_name;not null;type:VARCHAR(128)" json:"workflow_name"`
-	ToAddr       string    `gorm:"column:to_addr;not null;type:VARCHAR(64)" json:"to_addr"`
-	TokenInfoID  int       `gorm:"column:token_info_id;not null" json:"token_info_id"`
-	Description  string    `gorm:"column:description;not null;type:VARCHAR(1024)" json:"description"`
-	Status       string    `gorm:"column:status;type:ENUM('pending','approved','rejected');default:pending" json:"status"`
-	CreateBy     string    `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
-	CreateAddr   string    `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
-	CreatedTime  time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
-	UpdatedBy    string    `gorm:"column:updated_by;type:VARCHAR(64)" json:"updated_by"`
-	UpdatedAddr  string    `gorm:"column:updated_addr;type:VARCHAR(64)" json:"updated_addr"`
-	UpdatedTime  time.Time `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
+	ID              int        `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	WorkflowName    string     `gorm:"column:workflow_name;not null;type:VARCHAR(128)" json:"workflow_name"`
+	ToAddr          string     `gorm:"column:to_addr;not null;type:VARCHAR(64)" json:"to_addr"`
+	TokenInfoID     int        `gorm:"column:token_info_id;not null" json:"token_info_id"`
+	Amount          string     `gorm:"column:amount;not null;type:DECIMAL(65,0);default:0" json:"amount"` // token base units
+	Description     string     `gorm:"column:description;not null;type:VARCHAR(1024)" json:"description"`
+	Status          string     `gorm:"column:status;type:ENUM('pending','approved','rejected','cancelled','expired','paid','failed');default:pending" json:"status"`
+	ScheduleType    string     `gorm:"column:schedule_type;not null;type:ENUM('none','once','cron');default:none" json:"schedule_type"`
+	ScheduleCron    string     `gorm:"column:schedule_cron;not null;type:VARCHAR(64);default:''" json:"schedule_cron"` // five fields, evaluated in UTC
+	ScheduleStatus  string     `gorm:"column:schedule_status;not null;type:ENUM('none','active','paused','cancelled','completed');default:none;index:idx_schedule_due" json:"schedule_status"`
+	NextRunTime     *time.Time `gorm:"column:next_run_time;index:idx_schedule_due" json:"next_run_time"`
+	ScheduleEndTime *time.Time `gorm:"column:schedule_end_time" json:"schedule_end_time"`    // no cron run after this
+	MaxRuns         int        `gorm:"column:max_runs;not null;default:0" json:"max_runs"`   // 0 = until cancelled or the end time
+	RunCount        int        `gorm:"column:run_count;not null;default:0" json:"run_count"` // payouts created so far
+	CreateBy        string     `gorm:"column:create_by;not null;type:VARCHAR(64)" json:"create_by"`
+	CreateAddr      string     `gorm:"column:create_addr;not null;type:VARCHAR(64)" json:"create_addr"`
+	CreatedTime     time.Time  `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+	UpdatedBy       string     `gorm:"column:updated_by;type:VARCHAR(64)" json:"updated_by"`
+	UpdatedAddr     string     `gorm:"column:updated_addr;type:VARCHAR(64)" json:"updated_addr"`
+	UpdatedTime     time.Time  `gorm:"column:updated_time;default:CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP" json:"updated_time"`
 }
 
 func (WorkFlowInfo) TableName() string {
 	return "workflow_info"
 }
 
+// IsScheduled reports whether the workflow pays at a later time or
+// repeatedly rather than once on approval.
+func (w *WorkFlowInfo) IsScheduled() bool {
+	return w.ScheduleType != "" && w.ScheduleType != ScheduleTypeNone
+}
+
+// ScheduleDue reports whether a run of the schedule is due at now. Runs
+// follow each other: the next one is only created once the previous payout
+// was paid and the workflow is not failed.
+func (w *WorkFlowInfo) ScheduleDue(now time.Time) bool {
+	if w.ScheduleStatus != ScheduleStatusActive || w.NextRunTime == nil || w.NextRunTime.After(now) {
+		return false
+	}
+	return w.Status == WorkFlowStatusPaid || (w.Status == WorkFlowStatusApproved && w.RunCount == 0)
+}
+
 type WorkFlowInfoManager struct {
 	db *gorm.DB
 }
@@ -56,22 +139,137 @@ func (m *WorkFlowInfoManager) GetByID(id int) (*WorkFlowInfo, error) {
 	return &info, nil
 }
 
-func (m *WorkFlowInfoManager) Page(offset, limit uint64) ([]WorkFlowInfo, error) {
+// GetByIDForUpdate locks the row until the surrounding transaction ends.
+func (m *WorkFlowInfoManager) GetByIDForUpdate(id int) (*WorkFlowInfo, error) {
+	var info WorkFlowInfo
+	err := m.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&info).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ListPendingCreatedBefore returns up to limit pending workflows created
+// before the given time, oldest first.
+func (m *WorkFlowInfoManager) ListPendingCreatedBefore(createdBefore time.Time, limit int) ([]WorkFlowInfo, error) {
 	var infos []WorkFlowInfo
-	err := m.db.Offset(int(offset)).Limit(int(limit)).Find(&infos).Error
+	err := m.db.Where("status = ? AND created_time < ?", WorkFlowStatusPending, createdBefore).
+		Order("id ASC").
+		Limit(limit).
+		Find(&infos).Error
 	return infos, err
 }
 
-func (m *WorkFlowInfoManager) Count() (uint64, error) {
+// ListDueSchedules returns up to limit workflows with a schedule run due at
+// now, see ScheduleDue, earliest first.
+func (m *WorkFlowInfoManager) ListDueSchedules(now time.Time, limit int) ([]WorkFlowInfo, error) {
+	var infos []WorkFlowInfo
+	err := m.db.Where("schedule_status = ? AND next_run_time <= ?", ScheduleStatusActive, now).
+		Where("status = ? OR (status = ? AND run_count = 0)", WorkFlowStatusPaid, WorkFlowStatusApproved).
+		Order("next_run_time ASC, id ASC").
+		Limit(limit).
+		Find(&infos).Error
+	return infos, err
+}
+
+// Page returns one page of workflows matching query. With query.After set
+// the page starts after that row (keyset pagination) and offset is ignored.
+func (m *WorkFlowInfoManager) Page(query *WorkFlowInfoQuery, offset, limit uint64) ([]WorkFlowInfo, error) {
+	column, ok := WorkFlowSortColumns[query.SortBy]
+	if !ok {
+		column = "id"
+	}
+	direction := "ASC"
+	if query.Desc {
+		direction = "DESC"
+	}
+
+	db := m.filter(query)
+	if query.After != nil {
+		db = keysetAfter(db, query.SortBy, column, query.Desc, query.After)
+	} else {
+		db = db.Offset(int(offset))
+	}
+	order := column + " " + direction
+	if column != "id" {
+		order += ", id " + direction
+	}
+
+	var infos []WorkFlowInfo
+	err := db.Order(order).Limit(int(limit)).Find(&infos).Error
+	return infos, err
+}
+
+func (m *WorkFlowInfoManager) Count(query *WorkFlowInfoQuery) (uint64, error) {
 	var count int64
-	err := m.db.Model(&WorkFlowInfo{}).Count(&count).Error
+	err := m.filter(query).Count(&count).Error
 	return uint64(count), err
 }
 
+func (m *WorkFlowInfoManager) filter(query *WorkFlowInfoQuery) *gorm.DB {
+	db := m.db.Model(&WorkFlowInfo{})
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.ToAddr != "" {
+		db = db.Where("to_addr = ?", query.ToAddr)
+	}
+	if query.CreateAddr != "" {
+		db = db.Where("create_addr = ?", query.CreateAddr)
+	}
+	if query.TokenInfoID > 0 {
+		db = db.Where("token_info_id = ?", query.TokenInfoID)
+	}
+	if !query.CreatedFrom.IsZero() {
+		db = db.Where("created_time >= ?", query.CreatedFrom)
+	}
+	if !query.CreatedTo.IsZero() {
+		db = db.Where("created_time <= ?", query.CreatedTo)
+	}
+	if query.Name != "" {
+		db = db.Where("workflow_name LIKE ?", "%"+escapeLike(query.Name)+"%")
+	}
+	return db
+}
+
+// keysetAfter restricts db to the rows that sort after cursor, ties on the
+// sort column broken by id.
+func keysetAfter(db *gorm.DB, sortBy, column string, desc bool, cursor *WorkFlowInfoCursor) *gorm.DB {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if column == "id" {
+		return db.Where("id "+op+" ?", cursor.ID)
+	}
+	placeholder := "?"
+	if sortBy == WorkFlowSortAmount {
+		// compare as DECIMAL, a string operand would be compared as DOUBLE
+		placeholder = "CAST(? AS DECIMAL(65,0))"
+	}
+	return db.Where(
+		"("+column+" "+op+" "+placeholder+" OR ("+column+" = "+placeholder+" AND id "+op+" ?))",
+		cursor.Value, cursor.Value, cursor.ID,
+	)
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
 func (m *WorkFlowInfoManager) Update(workflow *WorkFlowInfo) error {
 	return m.db.Save(workflow).Error
 }
 
+func (m *WorkFlowInfoManager) CountByTokenInfoID(tokenInfoID int) (int64, error) {
+	var count int64
+	err := m.db.Model(&WorkFlowInfo{}).Where("token_info_id = ?", tokenInfoID).Count(&count).Error
+	return count, err
+}
+
 //func (m *WorkFlowInfoManager) GetPendingWorkflows(limit int) ([]*WorkFlowInfo, error) {
 //	var workflows []*WorkFlowInfo
 //	err := m.db.Where("status = ?", WorkFlowStatusApproved).
@@ -0,0 +1,47 @@
+package do
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkflowStatusHistory records one status transition of a workflow.
+type WorkflowStatusHistory struct {
+	ID           int       `gorm:"primaryKey;autoIncrement;column:id" json:"id"`
+	WorkflowID   int       `gorm:"column:workflow_id;not null;index:idx_workflow_id" json:"workflow_id"`
+	FromStatus   string    `gorm:"column:from_status;not null;type:VARCHAR(16)" json:"from_status"`
+	ToStatus     string    `gorm:"column:to_status;not null;type:VARCHAR(16)" json:"to_status"`
+	Reason       string    `gorm:"column:reason;not null;type:VARCHAR(512);default:''" json:"reason"`
+	OperatorAddr string    `gorm:"column:operator_addr;not null;type:VARCHAR(64)" json:"operator_addr"`
+	CreatedTime  time.Time `gorm:"column:created_time;default:CURRENT_TIMESTAMP" json:"created_time"`
+}
+
+func (WorkflowStatusHistory) TableName() string {
+	return "workflow_status_history"
+}
+
+type WorkflowStatusHistoryManager struct {
+	db *gorm.DB
+}
+
+func NewWorkflowStatusHistoryManager(db *gorm.DB) *WorkflowStatusHistoryManager {
+	return &WorkflowStatusHistoryManager{db: db}
+}
+
+func (m *WorkflowStatusHistoryManager) Create(history *WorkflowStatusHistory) error {
+	if err := m.db.Create(history).Error; err != nil {
+		return fmt.Errorf("WorkflowStatusHistoryManager Create: %w", err)
+	}
+	return nil
+}
+
+func (m *WorkflowStatusHistoryManager) ListByWorkflowID(workflowID int) ([]WorkflowStatusHistory, error) {
+	var histories []WorkflowStatusHistory
+	err := m.db.Where("workflow_id = ?", workflowID).Order("id ASC").Find(&histories).Error
+	if err != nil {
+		return nil, fmt.Errorf("WorkflowStatusHistoryManager ListByWorkflowID: %w", err)
+	}
+	return histories, nil
+}
@@ -1,14 +1,189 @@
 package dto
 
+import (
+	"time"
+
+	tokendo "go-project/business/token/do"
+	"go-project/business/workflow/do"
+	"go-project/common/types"
+)
+
 type WorkflowInfoCreateDTO struct {
 	WorkflowName string `json:"workflow_name" binding:"required,max=128"`
 	ToAddr       string `json:"to_addr" binding:"required,max=64"`
+	TokenInfoID  int    `json:"token_info_id" binding:"required"`
+	Amount       string `json:"amount" binding:"required,max=80"` // human readable, e.g. "12.5"
 	Description  string `json:"description" binding:"max=1024"`
+	// Schedule pays the workflow later or repeatedly instead of on approval
+	Schedule *WorkflowScheduleDTO `json:"schedule"`
+}
+
+// WorkflowScheduleDTO makes a workflow pay once at RunAt, or on every fire
+// of Cron (five fields, UTC) from RunAt on, until EndTime or MaxRuns runs.
+type WorkflowScheduleDTO struct {
+	Type    string     `json:"type" binding:"required,oneof=once cron"`
+	RunAt   *time.Time `json:"run_at"` // RFC 3339
+	Cron    string     `json:"cron" binding:"max=64"`
+	EndTime *time.Time `json:"end_time"`
+	MaxRuns int        `json:"max_runs" binding:"min=0"`
+}
+
+// WorkflowScheduleActionDTO pauses, resumes or cancels the schedule of a
+// workflow.
+type WorkflowScheduleActionDTO struct {
+	WorkflowID int    `json:"workflow_id" binding:"required"`
+	Reason     string `json:"reason" binding:"max=512"`
 }
 
+// WorkflowPageDTO filters and orders the workflow page. Cursor, the
+// nextCursor of the previous response, switches to keyset pagination and
+// makes pageNum irrelevant.
+type WorkflowPageDTO struct {
+	types.PageReq
+	Status      string         `form:"status" binding:"omitempty,oneof=pending approved rejected cancelled expired paid failed"`
+	ToAddr      string         `form:"to_addr" binding:"omitempty,max=64"`
+	CreateAddr  string         `form:"create_addr" binding:"omitempty,max=64"`
+	TokenInfoID int            `form:"token_info_id" binding:"min=0"`
+	CreatedFrom time.Time      `form:"created_from"` // RFC 3339
+	CreatedTo   time.Time      `form:"created_to"`
+	Name        string         `form:"name" binding:"omitempty,max=128"`
+	SortBy      string         `form:"sort_by" binding:"omitempty,oneof=id created_time updated_time amount workflow_name"`
+	SortType    types.SortType `form:"sort_type" binding:"omitempty,oneof=asc desc"`
+	Cursor      string         `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// WorkFlowApprovalDTO is a vote signed by the logged-in approver. Signature is the
+// EIP-712 signature over workflow id, decision, deadline and chain id, see
+// eth.ApprovalTypedData. Deadline is in unix seconds.
 type WorkFlowApprovalDTO struct {
 	WorkflowID     int    `json:"workflow_id" binding:"required"`
 	ApprovalStatus string `json:"approval_status" binding:"required,oneof=approved rejected"`
-	ApproverID     string `json:"approver_id" binding:"required"`
-	ApproverAddr   string `json:"approver_addr" binding:"required"`
+	Deadline       uint64 `json:"deadline" binding:"required"`
+	Signature      string `json:"signature" binding:"required,max=132"`
+}
+
+type WorkFlowApprovalTypedDataDTO struct {
+	WorkflowID     int    `form:"workflow_id" binding:"required"`
+	ApprovalStatus string `form:"approval_status" binding:"required,oneof=approved rejected"`
+	Deadline       uint64 `form:"deadline" binding:"required"`
+}
+
+type WorkFlowCancelDTO struct {
+	WorkflowID int    `json:"workflow_id" binding:"required"`
+	Reason     string `json:"reason" binding:"max=512"`
+}
+
+type WorkFlowHistoryDTO struct {
+	WorkflowID int `form:"workflow_id" binding:"required"`
+}
+
+// ApprovalPolicyCreateDTO adds a policy. MinAmount is in token base units;
+// TokenInfoID 0 applies the policy to every token.
+type ApprovalPolicyCreateDTO struct {
+	Name           string   `json:"name" binding:"required,max=100"`
+	TokenInfoID    int      `json:"token_info_id" binding:"min=0"`
+	MinAmount      string   `json:"min_amount" binding:"omitempty,numeric,max=65"`
+	RequiredWeight int      `json:"required_weight" binding:"required,min=1"`
+	ApproverLevels []string `json:"approver_levels" binding:"required,min=1,dive,oneof=none partial full"`
+	RejectWeight   int      `json:"reject_weight" binding:"min=0"`
+	VetoLevels     []string `json:"veto_levels" binding:"dive,oneof=none partial full"`
+	Reason         string   `json:"reason" binding:"max=512"`
+}
+
+type ManagementCreateDTO struct {
+	Name            string `json:"name" binding:"required,max=100"`
+	Addr            string `json:"addr" binding:"required,max=64"`
+	PermissionLevel string `json:"permission_level" binding:"required,oneof=none partial full"`
+	Weight          int    `json:"weight" binding:"omitempty,min=1"` // default 1
+	AnvilInfo       string `json:"anvil_info" binding:"max=64"`
+	Reason          string `json:"reason" binding:"max=512"`
+}
+
+// ManagementUpdateDTO changes the permission level and optionally the
+// approval weight of a member.
+type ManagementUpdateDTO struct {
+	ID              int    `json:"id" binding:"required"`
+	PermissionLevel string `json:"permission_level" binding:"required,oneof=none partial full"`
+	Weight          int    `json:"weight" binding:"omitempty,min=1"`
+	Reason          string `json:"reason" binding:"max=512"`
+}
+
+type ManagementDeactivateDTO struct {
+	ID     int    `json:"id" binding:"required"`
+	Reason string `json:"reason" binding:"max=512"`
+}
+
+type ManagementAuditDTO struct {
+	ManagementID int `form:"management_id" binding:"min=0"` // 0 = every member
+	Limit        int `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type WorkflowDetailDTO struct {
+	ID int `uri:"id" binding:"required,min=1"`
+}
+
+// WorkflowDetailResp is a workflow with its votes, status history and
+// every payout attempt.
+type WorkflowDetailResp struct {
+	Workflow      do.WorkFlowInfo            `json:"workflow"`
+	Token         *tokendo.TokenInfo         `json:"token"`
+	Approvals     []do.WorkFlowApprove       `json:"approvals"`
+	StatusHistory []do.WorkflowStatusHistory `json:"status_history"`
+	Payouts       []PayoutDetail             `json:"payouts"`
+	ScannedBlock  uint64                     `json:"scanned_block"` // the scanner's safe head, safe confirmations are counted up to it
+}
+
+type PayoutDetail struct {
+	tokendo.TokenTransferLog
+	Transactions []PayoutTxDetail `json:"transactions"`
+}
+
+// PayoutTxDetail is one broadcast transaction of a payout and, once the
+// scanner has seen it, its receipt.
+type PayoutTxDetail struct {
+	TxHash        string  `json:"tx_hash"`
+	TxType        string  `json:"tx_type"`
+	Nonce         uint64  `json:"nonce"`
+	Mined         bool    `json:"mined"`
+	ReceiptStatus *uint64 `json:"receipt_status"` // 1 success, 0 reverted, null until mined
+	BlockNumber   *uint64 `json:"block_number"`
+	GasUsed       *uint64 `json:"gas_used"`
+	// SafeConfirmations counts blocks up to the scanner's safe head, which
+	// trails the chain tip by eth_finalize_num blocks; the transaction has
+	// about that many more confirmations on the node.
+	SafeConfirmations uint64 `json:"safe_confirmations"`
+}
+
+// PayoutPageDTO lists payouts waiting for an operator. An empty status
+// lists failed and dead-lettered payouts.
+type PayoutPageDTO struct {
+	types.PageReq
+	Status string `form:"status" binding:"omitempty,oneof=failed dead_letter cancelled"`
+}
+
+type PayoutDTO struct {
+	ID int `uri:"id" binding:"required,min=1"`
+}
+
+// PayoutResp is a payout with its transactions and attempt history.
+type PayoutResp struct {
+	tokendo.TokenTransferLog
+	Transactions []tokendo.TokenTransferTx      `json:"transactions"`
+	Attempts     []tokendo.TokenTransferAttempt `json:"attempts"`
+}
+
+// PayoutRetryDTO sends a failed or dead-lettered payout again. The gas
+// fields replace the estimated values for every following attempt, in wei;
+// empty keeps the estimate.
+type PayoutRetryDTO struct {
+	ID                   int    `json:"id" binding:"required"`
+	GasLimit             uint64 `json:"gas_limit"`
+	MaxFeePerGas         string `json:"max_fee_per_gas" binding:"omitempty,numeric,max=78"`
+	MaxPriorityFeePerGas string `json:"max_priority_fee_per_gas" binding:"omitempty,numeric,max=78"`
+	Reason               string `json:"reason" binding:"max=512"`
+}
+
+type PayoutCancelDTO struct {
+	ID     int    `json:"id" binding:"required"`
+	Reason string `json:"reason" binding:"required,max=512"`
 }
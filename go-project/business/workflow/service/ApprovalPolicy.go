@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+)
+
+func (service *Service) ListApprovalPolicies() ([]do.ApprovalPolicy, error) {
+	return do.NewApprovalPolicyManager(service.db).List()
+}
+
+// CreateApprovalPolicy adds a policy. Policies decide who may approve
+// payouts, so only active full-permission members may add them.
+func (service *Service) CreateApprovalPolicy(input *dto.ApprovalPolicyCreateDTO, callerAddr string) (*do.ApprovalPolicy, error) {
+	minAmount := input.MinAmount
+	if minAmount == "" {
+		minAmount = "0"
+	}
+	if value, ok := new(big.Int).SetString(minAmount, 10); !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("invalid min_amount %q", input.MinAmount)
+	}
+
+	if input.RejectWeight == 0 {
+		input.RejectWeight = input.RequiredWeight
+	}
+	policy := &do.ApprovalPolicy{
+		Name:           input.Name,
+		TokenInfoID:    input.TokenInfoID,
+		MinAmount:      minAmount,
+		RequiredWeight: input.RequiredWeight,
+		ApproverLevels: strings.Join(input.ApproverLevels, ","),
+		RejectWeight:   input.RejectWeight,
+		VetoLevels:     strings.Join(input.VetoLevels, ","),
+		CreateBy:       callerAddr,
+		CreateAddr:     callerAddr,
+	}
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := requireFullPermission(tx, callerAddr); err != nil {
+			return err
+		}
+		if err := do.NewApprovalPolicyManager(tx).Create(policy); err != nil {
+			return err
+		}
+		return recordApprovalPolicyChange(tx, do.ApprovalPolicyActionCreate, policy, callerAddr, input.Reason)
+	})
+	if err != nil {
+		service.logger.Error("CreateApprovalPolicy", zap.Error(err))
+		return nil, err
+	}
+	return policy, nil
+}
+
+func recordApprovalPolicyChange(db *gorm.DB, action string, policy *do.ApprovalPolicy, operatorAddr, reason string) error {
+	return do.NewApprovalPolicyAuditManager(db).Create(&do.ApprovalPolicyAudit{
+		PolicyID:       policy.ID,
+		Action:         action,
+		TokenInfoID:    policy.TokenInfoID,
+		MinAmount:      policy.MinAmount,
+		RequiredWeight: policy.RequiredWeight,
+		ApproverLevels: policy.ApproverLevels,
+		RejectWeight:   policy.RejectWeight,
+		VetoLevels:     policy.VetoLevels,
+		Reason:         reason,
+		OperatorAddr:   operatorAddr,
+	})
+}
+
+// approvalPolicyFor loads the policy governing the workflow.
+func approvalPolicyFor(db *gorm.DB, workflow *do.WorkFlowInfo) (*do.ApprovalPolicy, error) {
+	amount, ok := new(big.Int).SetString(workflow.Amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("workflow %d has invalid amount %q", workflow.ID, workflow.Amount)
+	}
+	policies, err := do.NewApprovalPolicyManager(db).ListForToken(workflow.TokenInfoID)
+	if err != nil {
+		return nil, err
+	}
+	return selectApprovalPolicy(policies, workflow.TokenInfoID, amount), nil
+}
+
+// selectApprovalPolicy picks the highest amount tier the workflow reaches,
+// preferring policies of the workflow's token over global ones. Without a
+// match the old fixed quorum of two partial or full approvers applies.
+func selectApprovalPolicy(policies []do.ApprovalPolicy, tokenInfoID int, amount *big.Int) *do.ApprovalPolicy {
+	var selected *do.ApprovalPolicy
+	var selectedMin *big.Int
+	for i := range policies {
+		policy := &policies[i]
+		if policy.TokenInfoID != 0 && policy.TokenInfoID != tokenInfoID {
+			continue
+		}
+		minAmount, ok := new(big.Int).SetString(policy.MinAmount, 10)
+		if !ok || minAmount.Cmp(amount) > 0 {
+			continue
+		}
+		if selected != nil {
+			if selected.TokenInfoID != 0 && policy.TokenInfoID == 0 {
+				continue
+			}
+			if selected.TokenInfoID == policy.TokenInfoID && minAmount.Cmp(selectedMin) <= 0 {
+				continue
+			}
+		}
+		selected = policy
+		selectedMin = minAmount
+	}
+	if selected == nil {
+		return &do.ApprovalPolicy{
+			Name:           "default",
+			MinAmount:      "0",
+			RequiredWeight: do.DefaultRequiredWeight,
+			ApproverLevels: "partial,full",
+			RejectWeight:   do.DefaultRequiredWeight,
+			VetoLevels:     "full",
+		}
+	}
+	return selected
+}
+
+// approvedWeight sums the weights of the voters the policy accepts. It is
+// used for approving and rejecting votes alike.
+func approvedWeight(policy *do.ApprovalPolicy, approvers []do.Management) int {
+	weight := 0
+	for _, approver := range approvers {
+		if policy.AllowsLevel(approver.PermissionLevel) {
+			weight += approver.Weight
+		}
+	}
+	return weight
+}
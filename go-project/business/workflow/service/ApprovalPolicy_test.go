@@ -0,0 +1,65 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+
+	"go-project/business/workflow/do"
+)
+
+func TestSelectApprovalPolicy(t *testing.T) {
+	policies := []do.ApprovalPolicy{
+		{ID: 1, TokenInfoID: 0, MinAmount: "0", RequiredWeight: 2},
+		{ID: 2, TokenInfoID: 0, MinAmount: "1000", RequiredWeight: 3},
+		{ID: 3, TokenInfoID: 7, MinAmount: "0", RequiredWeight: 1},
+		{ID: 4, TokenInfoID: 7, MinAmount: "500", RequiredWeight: 4},
+		{ID: 5, TokenInfoID: 8, MinAmount: "0", RequiredWeight: 9},
+	}
+
+	cases := []struct {
+		name        string
+		tokenInfoID int
+		amount      int64
+		expectedID  int
+	}{
+		{"global base tier", 1, 10, 1},
+		{"global high tier", 1, 1000, 2},
+		{"token policy wins over global", 7, 2000, 4},
+		{"token low tier", 7, 499, 3},
+	}
+	for _, c := range cases {
+		policy := selectApprovalPolicy(policies, c.tokenInfoID, big.NewInt(c.amount))
+		if policy.ID != c.expectedID {
+			t.Errorf("%s: expected policy %d, got %d", c.name, c.expectedID, policy.ID)
+		}
+	}
+}
+
+func TestSelectApprovalPolicy_Default(t *testing.T) {
+	policy := selectApprovalPolicy(nil, 1, big.NewInt(1))
+	if policy.RequiredWeight != do.DefaultRequiredWeight || !policy.AllowsLevel("partial") || policy.AllowsLevel("none") {
+		t.Fatalf("Unexpected default policy %+v", policy)
+	}
+}
+
+func TestApprovedWeight(t *testing.T) {
+	policy := &do.ApprovalPolicy{RequiredWeight: 3, ApproverLevels: "partial,full"}
+	approvers := []do.Management{
+		{Addr: "0x1", PermissionLevel: "full", Weight: 2},
+		{Addr: "0x2", PermissionLevel: "partial", Weight: 1},
+		{Addr: "0x3", PermissionLevel: "none", Weight: 5},
+	}
+	if weight := approvedWeight(policy, approvers); weight != 3 {
+		t.Fatalf("Expected weight 3, got %d", weight)
+	}
+}
+
+func TestApprovalPolicyVetoLevels(t *testing.T) {
+	policy := &do.ApprovalPolicy{ApproverLevels: "partial", VetoLevels: "full"}
+	if !policy.VetoesLevel("full") || policy.VetoesLevel("partial") {
+		t.Fatalf("Unexpected veto levels for %+v", policy)
+	}
+	if policy.AllowsLevel("full") {
+		t.Fatalf("full must not count towards approval for %+v", policy)
+	}
+}
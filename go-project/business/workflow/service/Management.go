@@ -0,0 +1,208 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+)
+
+const defaultManagementAuditLimit = 100
+
+func (service *Service) ListManagements() ([]do.Management, error) {
+	return do.NewManagementManager(service.db).List()
+}
+
+func (service *Service) ListManagementAudits(input *dto.ManagementAuditDTO) ([]do.ManagementAudit, error) {
+	limit := input.Limit
+	if limit == 0 {
+		limit = defaultManagementAuditLimit
+	}
+	return do.NewManagementAuditManager(service.db).List(input.ManagementID, limit)
+}
+
+// CreateManagement adds an approver. Only active full-permission members may
+// change the management table.
+func (service *Service) CreateManagement(input *dto.ManagementCreateDTO, callerAddr string) (*do.Management, error) {
+	if !common.IsHexAddress(input.Addr) {
+		return nil, fmt.Errorf("invalid addr %q", input.Addr)
+	}
+	weight := input.Weight
+	if weight == 0 {
+		weight = 1
+	}
+	management := &do.Management{
+		Name:            input.Name,
+		PermissionLevel: input.PermissionLevel,
+		Weight:          weight,
+		Addr:            common.HexToAddress(input.Addr).Hex(),
+		AnvilInfo:       input.AnvilInfo,
+		Status:          do.ManagementStatusActive,
+		CreateBy:        callerAddr,
+		CreateAddr:      callerAddr,
+	}
+
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		if err := requireFullPermission(tx, callerAddr); err != nil {
+			return err
+		}
+		managementManager := do.NewManagementManager(tx)
+		existing, err := managementManager.GetByAddr(management.Addr)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fmt.Errorf("%s is already member %d (%s)", management.Addr, existing.ID, existing.Status)
+		}
+		if err := managementManager.Create(management); err != nil {
+			return err
+		}
+		return recordManagementChange(tx, do.ManagementActionCreate, &do.Management{}, management, callerAddr, input.Reason)
+	})
+	if err != nil {
+		service.logger.Error("CreateManagement", zap.Error(err))
+		return nil, err
+	}
+	return management, nil
+}
+
+// UpdateManagementPermission changes the permission level and weight of an
+// active member.
+func (service *Service) UpdateManagementPermission(input *dto.ManagementUpdateDTO, callerAddr string) (*do.Management, error) {
+	var management *do.Management
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		var before do.Management
+		var err error
+		management, before, err = lockActiveMember(tx, input.ID, callerAddr)
+		if err != nil {
+			return err
+		}
+
+		management.PermissionLevel = input.PermissionLevel
+		if input.Weight > 0 {
+			management.Weight = input.Weight
+		}
+		if err := ensureFullMemberRemains(tx, &before, management); err != nil {
+			return err
+		}
+		management.UpdatedBy = callerAddr
+		management.UpdatedAddr = callerAddr
+		if err := do.NewManagementManager(tx).UpdateMembership(management); err != nil {
+			return err
+		}
+		return recordManagementChange(tx, do.ManagementActionUpdate, &before, management, callerAddr, input.Reason)
+	})
+	if err != nil {
+		service.logger.Error("UpdateManagementPermission", zap.Error(err))
+		return nil, err
+	}
+	return management, nil
+}
+
+// DeactivateManagement removes a member from approvals. The row is kept so
+// its past votes still resolve.
+func (service *Service) DeactivateManagement(input *dto.ManagementDeactivateDTO, callerAddr string) (*do.Management, error) {
+	var management *do.Management
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		var before do.Management
+		var err error
+		management, before, err = lockActiveMember(tx, input.ID, callerAddr)
+		if err != nil {
+			return err
+		}
+
+		management.Status = do.ManagementStatusInactive
+		if err := ensureFullMemberRemains(tx, &before, management); err != nil {
+			return err
+		}
+		management.UpdatedBy = callerAddr
+		management.UpdatedAddr = callerAddr
+		if err := do.NewManagementManager(tx).UpdateMembership(management); err != nil {
+			return err
+		}
+		return recordManagementChange(tx, do.ManagementActionDeactivate, &before, management, callerAddr, input.Reason)
+	})
+	if err != nil {
+		service.logger.Error("DeactivateManagement", zap.Error(err))
+		return nil, err
+	}
+	return management, nil
+}
+
+func requireFullPermission(db *gorm.DB, callerAddr string) error {
+	hasFullPermission, err := do.NewManagementManager(db).HasFullPermission(callerAddr)
+	if err != nil {
+		return fmt.Errorf("check permission error: %w", err)
+	}
+	if !hasFullPermission {
+		return fmt.Errorf("%w: %s", NotAllowedToManageError, callerAddr)
+	}
+	return nil
+}
+
+// lockActiveMember checks the caller may manage members, then locks member
+// id and returns it together with a copy of its current state. The full
+// members are locked first, always in id order, so that concurrent changes
+// queue up behind each other in ensureFullMemberRemains instead of
+// deadlocking.
+func lockActiveMember(db *gorm.DB, id int, callerAddr string) (*do.Management, do.Management, error) {
+	if err := requireFullPermission(db, callerAddr); err != nil {
+		return nil, do.Management{}, err
+	}
+	managementManager := do.NewManagementManager(db)
+	if _, err := managementManager.ListActiveFullForUpdate(); err != nil {
+		return nil, do.Management{}, err
+	}
+	management, err := managementManager.GetByIDForUpdate(id)
+	if err != nil {
+		return nil, do.Management{}, err
+	}
+	if management == nil || management.Status != do.ManagementStatusActive {
+		return nil, do.Management{}, fmt.Errorf("%w: %d", ManagementNotFoundError, id)
+	}
+	return management, *management, nil
+}
+
+// ensureFullMemberRemains refuses a change that would leave no active member
+// with full permission, since nobody could manage the table afterwards. The
+// full members are counted with a locking read: a plain read would see the
+// transaction's snapshot, missing a concurrent demotion of another one.
+func ensureFullMemberRemains(db *gorm.DB, before, after *do.Management) error {
+	if !losesFullPermission(before, after) {
+		return nil
+	}
+	fullMembers, err := do.NewManagementManager(db).ListActiveFullForUpdate()
+	if err != nil {
+		return err
+	}
+	if len(fullMembers) <= 1 {
+		return fmt.Errorf("%w: %s", LastFullMemberError, before.Addr)
+	}
+	return nil
+}
+
+func losesFullPermission(before, after *do.Management) bool {
+	wasFull := before.Status == do.ManagementStatusActive && before.PermissionLevel == do.PermissionLevelFull
+	isFull := after.Status == do.ManagementStatusActive && after.PermissionLevel == do.PermissionLevelFull
+	return wasFull && !isFull
+}
+
+func recordManagementChange(db *gorm.DB, action string, before, after *do.Management, operatorAddr, reason string) error {
+	return do.NewManagementAuditManager(db).Create(&do.ManagementAudit{
+		ManagementID: after.ID,
+		Addr:         after.Addr,
+		Action:       action,
+		OldLevel:     before.PermissionLevel,
+		NewLevel:     after.PermissionLevel,
+		OldWeight:    before.Weight,
+		NewWeight:    after.Weight,
+		OldStatus:    before.Status,
+		NewStatus:    after.Status,
+		Reason:       reason,
+		OperatorAddr: operatorAddr,
+	})
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"go-project/business/workflow/do"
+)
+
+func TestLosesFullPermission(t *testing.T) {
+	full := &do.Management{PermissionLevel: do.PermissionLevelFull, Status: do.ManagementStatusActive}
+	partial := &do.Management{PermissionLevel: do.PermissionLevelPartial, Status: do.ManagementStatusActive}
+	inactiveFull := &do.Management{PermissionLevel: do.PermissionLevelFull, Status: do.ManagementStatusInactive}
+
+	cases := []struct {
+		name          string
+		before, after *do.Management
+		want          bool
+	}{
+		{"demoted", full, partial, true},
+		{"deactivated", full, inactiveFull, true},
+		{"unchanged full", full, full, false},
+		{"promoted", partial, full, false},
+		{"partial deactivated", partial, &do.Management{PermissionLevel: do.PermissionLevelPartial, Status: do.ManagementStatusInactive}, false},
+	}
+	for _, tc := range cases {
+		if got := losesFullPermission(tc.before, tc.after); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
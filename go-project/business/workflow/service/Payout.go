@@ -0,0 +1,286 @@
+package service
+
+import (
+	"fmt"
+	"math/big"
+
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	scando "go-project/business/scan/do"
+	do2 "go-project/business/token/do"
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+	"go-project/common/types"
+)
+
+// stuckPayoutStatuses are the payout statuses an operator can act on.
+var stuckPayoutStatuses = []string{do2.StatusFailed, do2.StatusDeadLetter}
+
+// PagePayouts lists payouts waiting for an operator, newest first.
+func (service *Service) PagePayouts(req *dto.PayoutPageDTO) (*types.GenericPageResp[do2.TokenTransferLog], error) {
+	if req.PageNum == 0 {
+		req.PageNum = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 10
+	}
+	if req.PageSize > maxPageSize {
+		req.PageSize = maxPageSize
+	}
+	statuses := stuckPayoutStatuses
+	if req.Status != "" {
+		statuses = []string{req.Status}
+	}
+
+	transferLogManager := do2.NewTokenTransferLogManager(service.db)
+	list, err := transferLogManager.PageByStatuses(statuses, (req.PageNum-1)*req.PageSize, req.PageSize)
+	if err != nil {
+		service.logger.Error("PagePayouts PageByStatuses", zap.Error(err))
+		return nil, err
+	}
+	total, err := transferLogManager.CountByStatuses(statuses)
+	if err != nil {
+		service.logger.Error("PagePayouts CountByStatuses", zap.Error(err))
+		return nil, err
+	}
+
+	return &types.GenericPageResp[do2.TokenTransferLog]{
+		PageResp: types.PageResp{
+			PageNum:   req.PageNum,
+			PageSize:  req.PageSize,
+			Total:     total,
+			TotalPage: (total + req.PageSize - 1) / req.PageSize,
+		},
+		List: list,
+	}, nil
+}
+
+// GetPayout loads a payout with its transactions and attempt history.
+func (service *Service) GetPayout(id int) (*dto.PayoutResp, error) {
+	transferLog, err := do2.NewTokenTransferLogManager(service.db).GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if transferLog == nil {
+		return nil, fmt.Errorf("%w: %d", PayoutNotFoundError, id)
+	}
+
+	resp := &dto.PayoutResp{TokenTransferLog: *transferLog}
+	resp.Transactions, err = do2.NewTokenTransferTxManager(service.db).ListByTransferLogID(id)
+	if err != nil {
+		return nil, err
+	}
+	resp.Attempts, err = do2.NewTokenTransferAttemptManager(service.db).ListByTransferLogID(id)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RetryPayout puts a failed or dead-lettered payout back in line with a
+// fresh retry budget and reopens its workflow. Only full-permission members
+// may retry payouts, and only when none of its transactions was mined
+// successfully: a payout failed for lack of a matching Transfer event, e.g.
+// of a fee-on-transfer token, did move funds.
+func (service *Service) RetryPayout(input *dto.PayoutRetryDTO, callerAddr string) (*do2.TokenTransferLog, error) {
+	if err := checkRetryOverrides(input); err != nil {
+		return nil, err
+	}
+
+	var transferLog *do2.TokenTransferLog
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transferLog, err = lockStuckPayout(tx, input.ID, callerAddr)
+		if err != nil {
+			return err
+		}
+
+		if err := requireNoFundsMoved(tx, transferLog); err != nil {
+			return err
+		}
+
+		// the old transaction failed or was never mined, a new one is signed
+		transferLog.Status = do2.StatusPending
+		transferLog.TransactionHash = ""
+		transferLog.BatchID = 0
+		transferLog.RetryCount = 0
+		transferLog.FailureReason = ""
+		transferLog.GasLimitOverride = input.GasLimit
+		transferLog.MaxFeePerGasOverride = input.MaxFeePerGas
+		transferLog.PriorityFeeOverride = input.MaxPriorityFeePerGas
+		err = movePayout(tx, transferLog, do2.StatusPending, map[string]interface{}{
+			"transaction_hash":         "",
+			"batch_id":                 0,
+			"retry_count":              0,
+			"failure_reason":           "",
+			"gas_limit_override":       transferLog.GasLimitOverride,
+			"max_fee_per_gas_override": transferLog.MaxFeePerGasOverride,
+			"priority_fee_override":    transferLog.PriorityFeeOverride,
+			"updated_by":               callerAddr,
+			"updated_addr":             callerAddr,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := recordPayoutAction(tx, transferLog, do2.AttemptOutcomeRetried, callerAddr, input.Reason); err != nil {
+			return err
+		}
+		return TransitionByID(tx, transferLog.WorkflowID, do.WorkFlowStatusApproved, callerAddr, payoutReason("payout retried", input.Reason))
+	})
+	if err != nil {
+		service.logger.Error("RetryPayout", zap.Error(err))
+		return nil, err
+	}
+	return transferLog, nil
+}
+
+// CancelPayout gives up a failed or dead-lettered payout for good and
+// cancels its workflow with any schedule. Only full-permission members may
+// cancel payouts.
+func (service *Service) CancelPayout(input *dto.PayoutCancelDTO, callerAddr string) (*do2.TokenTransferLog, error) {
+	var transferLog *do2.TokenTransferLog
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transferLog, err = lockStuckPayout(tx, input.ID, callerAddr)
+		if err != nil {
+			return err
+		}
+
+		transferLog.Status = do2.StatusCancelled
+		err = movePayout(tx, transferLog, do2.StatusCancelled, map[string]interface{}{
+			"updated_by":   callerAddr,
+			"updated_addr": callerAddr,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := recordPayoutAction(tx, transferLog, do2.AttemptOutcomeCancelled, callerAddr, input.Reason); err != nil {
+			return err
+		}
+		err = TransitionByID(tx, transferLog.WorkflowID, do.WorkFlowStatusCancelled, callerAddr, payoutReason("payout cancelled", input.Reason))
+		if err != nil {
+			return err
+		}
+		return cancelScheduleOf(tx, transferLog.WorkflowID, callerAddr)
+	})
+	if err != nil {
+		service.logger.Error("CancelPayout", zap.Error(err))
+		return nil, err
+	}
+	return transferLog, nil
+}
+
+// lockStuckPayout checks the caller may act on payouts, then locks payout
+// id, which has to be failed or dead-lettered.
+func lockStuckPayout(db *gorm.DB, id int, callerAddr string) (*do2.TokenTransferLog, error) {
+	if err := requireFullPermission(db, callerAddr); err != nil {
+		return nil, err
+	}
+	transferLog, err := do2.NewTokenTransferLogManager(db).GetByIDForUpdate(id)
+	if err != nil {
+		return nil, err
+	}
+	if transferLog == nil {
+		return nil, fmt.Errorf("%w: %d", PayoutNotFoundError, id)
+	}
+	if transferLog.Status != do2.StatusFailed && transferLog.Status != do2.StatusDeadLetter {
+		return nil, fmt.Errorf("%w: payout %d is %s", PayoutNotStuckError, id, transferLog.Status)
+	}
+	return transferLog, nil
+}
+
+// requireNoFundsMoved refuses a payout one of whose transactions is indexed
+// with a successful receipt.
+func requireNoFundsMoved(db *gorm.DB, transferLog *do2.TokenTransferLog) error {
+	sentTxs, err := do2.NewTokenTransferTxManager(db).ListByTransferLogID(transferLog.ID)
+	if err != nil {
+		return err
+	}
+	txHashes := []string{}
+	if transferLog.TransactionHash != "" {
+		txHashes = append(txHashes, transferLog.TransactionHash)
+	}
+	for _, sentTx := range sentTxs {
+		txHashes = append(txHashes, sentTx.TxHash)
+	}
+	infos, err := scando.NewTransactionInfoManager(db).ListByTxHashes(txHashes)
+	if err != nil {
+		return err
+	}
+	if txHash := successfulTx(infos); txHash != "" {
+		return fmt.Errorf("%w: payout %d transaction %s was mined successfully, check the transfer on chain and cancel the payout instead",
+			PayoutFundsMovedError, transferLog.ID, txHash)
+	}
+	return nil
+}
+
+// successfulTx returns the hash of the first transaction with a successful
+// receipt, or "" when all of them reverted.
+func successfulTx(infos []scando.TransactionInfo) string {
+	for _, info := range infos {
+		if info.Status == ethtypes.ReceiptStatusSuccessful {
+			return info.TxHash
+		}
+	}
+	return ""
+}
+
+func movePayout(db *gorm.DB, transferLog *do2.TokenTransferLog, to string, columns map[string]interface{}) error {
+	moved, err := do2.NewTokenTransferLogManager(db).MoveStatus(transferLog.ID, stuckPayoutStatuses, to, columns)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return fmt.Errorf("%w: payout %d changed concurrently", PayoutNotStuckError, transferLog.ID)
+	}
+	return nil
+}
+
+func recordPayoutAction(db *gorm.DB, transferLog *do2.TokenTransferLog, outcome, callerAddr, reason string) error {
+	attempt := do2.NewTokenTransferAttempt(transferLog, outcome, callerAddr)
+	attempt.Error = reason
+	return do2.NewTokenTransferAttemptManager(db).Create(attempt)
+}
+
+// checkRetryOverrides validates the gas overrides of a retry. Fields left
+// empty clear earlier overrides.
+func checkRetryOverrides(input *dto.PayoutRetryDTO) error {
+	if input.GasLimit != 0 && input.GasLimit < params.TxGas {
+		return fmt.Errorf("gas_limit %d is below %d", input.GasLimit, params.TxGas)
+	}
+	maxFee, err := positiveWei("max_fee_per_gas", input.MaxFeePerGas)
+	if err != nil {
+		return err
+	}
+	priorityFee, err := positiveWei("max_priority_fee_per_gas", input.MaxPriorityFeePerGas)
+	if err != nil {
+		return err
+	}
+	if maxFee != nil && priorityFee != nil && priorityFee.Cmp(maxFee) > 0 {
+		return fmt.Errorf("max_priority_fee_per_gas %s exceeds max_fee_per_gas %s", priorityFee, maxFee)
+	}
+	return nil
+}
+
+func positiveWei(field, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	wei, ok := new(big.Int).SetString(value, 10)
+	if !ok || wei.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid %s %q", field, value)
+	}
+	return wei, nil
+}
+
+func payoutReason(action, reason string) string {
+	if reason == "" {
+		return action
+	}
+	return action + ": " + reason
+}
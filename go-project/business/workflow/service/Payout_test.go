@@ -0,0 +1,53 @@
+package service
+
+import (
+	"testing"
+
+	scando "go-project/business/scan/do"
+	"go-project/business/workflow/dto"
+)
+
+func TestCheckRetryOverrides(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   dto.PayoutRetryDTO
+		wantErr bool
+	}{
+		{"no overrides", dto.PayoutRetryDTO{ID: 1}, false},
+		{"all overrides", dto.PayoutRetryDTO{ID: 1, GasLimit: 90000, MaxFeePerGas: "30000000000", MaxPriorityFeePerGas: "2000000000"}, false},
+		{"gas limit below intrinsic gas", dto.PayoutRetryDTO{ID: 1, GasLimit: 20000}, true},
+		{"zero max fee", dto.PayoutRetryDTO{ID: 1, MaxFeePerGas: "0"}, true},
+		{"tip above max fee", dto.PayoutRetryDTO{ID: 1, MaxFeePerGas: "1000", MaxPriorityFeePerGas: "2000"}, true},
+		{"tip only", dto.PayoutRetryDTO{ID: 1, MaxPriorityFeePerGas: "2000"}, false},
+	}
+	for _, tc := range cases {
+		err := checkRetryOverrides(&tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestPayoutReason(t *testing.T) {
+	if got := payoutReason("payout retried", ""); got != "payout retried" {
+		t.Errorf("Unexpected reason %q", got)
+	}
+	if got := payoutReason("payout cancelled", "recipient closed"); got != "payout cancelled: recipient closed" {
+		t.Errorf("Unexpected reason %q", got)
+	}
+}
+
+func TestSuccessfulTx(t *testing.T) {
+	reverted := scando.TransactionInfo{TxHash: "0x01", Status: 0}
+	mined := scando.TransactionInfo{TxHash: "0x02", Status: 1}
+	if got := successfulTx(nil); got != "" {
+		t.Errorf("Expected no transaction for a payout never mined, got %q", got)
+	}
+	if got := successfulTx([]scando.TransactionInfo{reverted}); got != "" {
+		t.Errorf("Expected no transaction for a reverted payout, got %q", got)
+	}
+	// an earlier attempt reverted, a later one went through
+	if got := successfulTx([]scando.TransactionInfo{reverted, mined}); got != "0x02" {
+		t.Errorf("Expected the successful transaction, got %q", got)
+	}
+}
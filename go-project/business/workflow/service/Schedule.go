@@ -0,0 +1,285 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	do2 "go-project/business/token/do"
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+	"go-project/util/cron"
+)
+
+// scheduleOperator is recorded as operator of the scheduler's changes.
+const scheduleOperator = "WorkflowScheduler"
+
+// applySchedule sets up the schedule of a new workflow. A workflow without
+// one is paid as soon as it is approved.
+func applySchedule(workflow *do.WorkFlowInfo, input *dto.WorkflowScheduleDTO, now time.Time) error {
+	if input == nil {
+		workflow.ScheduleType = do.ScheduleTypeNone
+		workflow.ScheduleStatus = do.ScheduleStatusNone
+		return nil
+	}
+	now = now.UTC()
+
+	var next time.Time
+	switch input.Type {
+	case do.ScheduleTypeOnce:
+		if input.RunAt == nil || !input.RunAt.After(now) {
+			return fmt.Errorf("%w: run_at must be in the future", InvalidScheduleError)
+		}
+		next = input.RunAt.UTC()
+		workflow.MaxRuns = 1
+	case do.ScheduleTypeCron:
+		schedule, err := cron.Parse(input.Cron)
+		if err != nil {
+			return fmt.Errorf("%w: %v", InvalidScheduleError, err)
+		}
+		start := now
+		if input.RunAt != nil && input.RunAt.After(now) {
+			start = input.RunAt.UTC()
+		}
+		next = schedule.Next(start)
+		if next.IsZero() {
+			return fmt.Errorf("%w: cron %q never fires", InvalidScheduleError, input.Cron)
+		}
+		if input.EndTime != nil && next.After(*input.EndTime) {
+			return fmt.Errorf("%w: end_time is before the first run at %s", InvalidScheduleError, next.Format(time.RFC3339))
+		}
+		workflow.ScheduleCron = input.Cron
+		workflow.ScheduleEndTime = input.EndTime
+		workflow.MaxRuns = input.MaxRuns
+	default:
+		return fmt.Errorf("%w: unknown type %q", InvalidScheduleError, input.Type)
+	}
+
+	workflow.ScheduleType = input.Type
+	workflow.ScheduleStatus = do.ScheduleStatusActive
+	workflow.NextRunTime = &next
+	return nil
+}
+
+// advanceSchedule moves the schedule past the run just created. Cron runs
+// missed while the service was down or the previous payout was in flight
+// are paid once, not caught up one by one.
+func advanceSchedule(workflow *do.WorkFlowInfo, now time.Time) error {
+	if workflow.ScheduleType != do.ScheduleTypeCron || (workflow.MaxRuns > 0 && workflow.RunCount >= workflow.MaxRuns) {
+		completeSchedule(workflow)
+		return nil
+	}
+	schedule, err := cron.Parse(workflow.ScheduleCron)
+	if err != nil {
+		return fmt.Errorf("workflow %d: %w", workflow.ID, err)
+	}
+	next := schedule.Next(now.UTC())
+	if next.IsZero() || (workflow.ScheduleEndTime != nil && next.After(*workflow.ScheduleEndTime)) {
+		completeSchedule(workflow)
+		return nil
+	}
+	workflow.NextRunTime = &next
+	return nil
+}
+
+func completeSchedule(workflow *do.WorkFlowInfo) {
+	workflow.ScheduleStatus = do.ScheduleStatusCompleted
+	workflow.NextRunTime = nil
+}
+
+// RunDueSchedules creates the payouts of schedule runs due at now and
+// returns how many were created.
+func (service *Service) RunDueSchedules(now time.Time, limit int) (int, error) {
+	workflows, err := do.NewWorkFlowInfoManager(service.db).ListDueSchedules(now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("list due schedules error: %w", err)
+	}
+
+	created := 0
+	for _, workflow := range workflows {
+		var ran bool
+		err := service.db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			ran, err = runSchedule(tx, workflow.ID, now)
+			return err
+		})
+		if err != nil {
+			return created, err
+		}
+		if ran {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// runSchedule creates the payout of the due run of a workflow. The
+// workflow is locked and checked again, another scheduler may have run it
+// already; uk_workflow_run rejects a second payout for the same run.
+func runSchedule(tx *gorm.DB, workflowID int, now time.Time) (bool, error) {
+	workflow, err := do.NewWorkFlowInfoManager(tx).GetByIDForUpdate(workflowID)
+	if err != nil {
+		return false, fmt.Errorf("get workflow %d error: %w", workflowID, err)
+	}
+	if workflow == nil || !workflow.ScheduleDue(now) {
+		return false, nil
+	}
+	tokenInfo, err := do2.NewTokenInfoManager(tx).GetByID(workflow.TokenInfoID)
+	if err != nil {
+		return false, err
+	}
+	if tokenInfo == nil {
+		return false, fmt.Errorf("token info %d not found", workflow.TokenInfoID)
+	}
+
+	run := workflow.RunCount + 1
+	transferLog := newTokenTransferLog(workflow, tokenInfo)
+	transferLog.ScheduleRun = run
+	if err := do2.NewTokenTransferLogManager(tx).Create(transferLog); err != nil {
+		return false, fmt.Errorf("create TokenTransferLog error: %w", err)
+	}
+
+	workflow.RunCount = run
+	if err := advanceSchedule(workflow, now); err != nil {
+		return false, err
+	}
+	if workflow.Status == do.WorkFlowStatusPaid {
+		// the previous run was paid, the workflow waits for this one
+		return true, Transition(tx, workflow, do.WorkFlowStatusApproved, scheduleOperator, fmt.Sprintf("scheduled run %d due", run))
+	}
+	workflow.UpdatedBy = scheduleOperator
+	workflow.UpdatedAddr = scheduleOperator
+	workflow.UpdatedTime = time.Now()
+	return true, do.NewWorkFlowInfoManager(tx).Update(workflow)
+}
+
+// PauseSchedule stops creating payouts for a workflow until it is resumed.
+// Only its creator or a member with full permission may pause it.
+func (service *Service) PauseSchedule(input *dto.WorkflowScheduleActionDTO, callerAddr string) (*do.WorkFlowInfo, error) {
+	return service.changeSchedule(input, callerAddr, []string{do.ScheduleStatusActive}, func(workflow *do.WorkFlowInfo, tx *gorm.DB) error {
+		workflow.ScheduleStatus = do.ScheduleStatusPaused
+		return nil
+	})
+}
+
+// ResumeSchedule resumes a paused schedule. Cron runs that fell into the
+// pause are skipped, a one-off payout that became due meanwhile runs right
+// away.
+func (service *Service) ResumeSchedule(input *dto.WorkflowScheduleActionDTO, callerAddr string) (*do.WorkFlowInfo, error) {
+	return service.changeSchedule(input, callerAddr, []string{do.ScheduleStatusPaused}, func(workflow *do.WorkFlowInfo, tx *gorm.DB) error {
+		workflow.ScheduleStatus = do.ScheduleStatusActive
+		now := time.Now()
+		if workflow.ScheduleType == do.ScheduleTypeCron && workflow.NextRunTime != nil && workflow.NextRunTime.Before(now) {
+			return advanceSchedule(workflow, now)
+		}
+		return nil
+	})
+}
+
+// CancelSchedule stops the schedule for good, payouts already created are
+// still paid. A workflow cancelled before its first run is cancelled as
+// well.
+func (service *Service) CancelSchedule(input *dto.WorkflowScheduleActionDTO, callerAddr string) (*do.WorkFlowInfo, error) {
+	return service.changeSchedule(input, callerAddr, []string{do.ScheduleStatusActive, do.ScheduleStatusPaused}, func(workflow *do.WorkFlowInfo, tx *gorm.DB) error {
+		workflow.ScheduleStatus = do.ScheduleStatusCancelled
+		workflow.NextRunTime = nil
+		if workflow.RunCount == 0 && canTransition(workflow.Status, do.WorkFlowStatusCancelled) {
+			return Transition(tx, workflow, do.WorkFlowStatusCancelled, callerAddr, payoutReason("schedule cancelled", input.Reason))
+		}
+		return nil
+	})
+}
+
+// changeSchedule locks the workflow, checks the caller may manage its
+// schedule and that the schedule is in one of from, then applies change
+// and saves the workflow.
+func (service *Service) changeSchedule(input *dto.WorkflowScheduleActionDTO, callerAddr string, from []string,
+	change func(workflow *do.WorkFlowInfo, tx *gorm.DB) error) (*do.WorkFlowInfo, error) {
+	var workflow *do.WorkFlowInfo
+	err := service.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		workflow, err = do.NewWorkFlowInfoManager(tx).GetByIDForUpdate(input.WorkflowID)
+		if err != nil {
+			return fmt.Errorf("getById error: %w", err)
+		}
+		if workflow == nil {
+			return fmt.Errorf("%w: %d", WorkflowNotFoundError, input.WorkflowID)
+		}
+		if !workflow.IsScheduled() {
+			return fmt.Errorf("%w: %d", NotScheduledError, workflow.ID)
+		}
+		if err := requireCreatorOrFullPermission(tx, workflow, callerAddr, ScheduleNotAllowedError); err != nil {
+			return err
+		}
+		if !containsStatus(from, workflow.ScheduleStatus) {
+			return fmt.Errorf("%w: schedule of workflow %d is %s", ScheduleStatusError, workflow.ID, workflow.ScheduleStatus)
+		}
+
+		status := workflow.Status
+		if err := change(workflow, tx); err != nil {
+			return err
+		}
+		if workflow.Status != status {
+			// saved with the transition
+			return nil
+		}
+		workflow.UpdatedBy = callerAddr
+		workflow.UpdatedAddr = callerAddr
+		workflow.UpdatedTime = time.Now()
+		return do.NewWorkFlowInfoManager(tx).Update(workflow)
+	})
+	if err != nil {
+		service.logger.Error("changeSchedule", zap.Int("WorkflowID", input.WorkflowID), zap.Error(err))
+		return nil, err
+	}
+	service.logger.Info("schedule changed", zap.Int("WorkflowID", workflow.ID), zap.String("status", workflow.ScheduleStatus),
+		zap.String("operator", callerAddr), zap.String("reason", input.Reason))
+	return workflow, nil
+}
+
+// requireCreatorOrFullPermission lets the creator of workflow or a member
+// with full permission through and fails with denied otherwise.
+func requireCreatorOrFullPermission(db *gorm.DB, workflow *do.WorkFlowInfo, callerAddr string, denied error) error {
+	if strings.EqualFold(workflow.CreateAddr, callerAddr) {
+		return nil
+	}
+	hasFullPermission, err := do.NewManagementManager(db).HasFullPermission(callerAddr)
+	if err != nil {
+		return fmt.Errorf("check permission error: %w", err)
+	}
+	if !hasFullPermission {
+		return fmt.Errorf("%w: %s", denied, callerAddr)
+	}
+	return nil
+}
+
+func containsStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelScheduleOf stops the schedule of a workflow whose payout an
+// operator gave up, no later run should pay it.
+func cancelScheduleOf(tx *gorm.DB, workflowID int, operatorAddr string) error {
+	workflowManager := do.NewWorkFlowInfoManager(tx)
+	workflow, err := workflowManager.GetByIDForUpdate(workflowID)
+	if err != nil || workflow == nil {
+		return err
+	}
+	if workflow.ScheduleStatus != do.ScheduleStatusActive && workflow.ScheduleStatus != do.ScheduleStatusPaused {
+		return nil
+	}
+	workflow.ScheduleStatus = do.ScheduleStatusCancelled
+	workflow.NextRunTime = nil
+	workflow.UpdatedBy = operatorAddr
+	workflow.UpdatedAddr = operatorAddr
+	workflow.UpdatedTime = time.Now()
+	return workflowManager.Update(workflow)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+)
+
+func TestApplySchedule(t *testing.T) {
+	now := time.Date(2024, 10, 2, 21, 35, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	endOfYear := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		input    *dto.WorkflowScheduleDTO
+		wantNext time.Time
+		wantErr  bool
+	}{
+		{"no schedule", nil, time.Time{}, false},
+		{"once", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeOnce, RunAt: &later}, later, false},
+		{"once in the past", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeOnce, RunAt: &earlier}, time.Time{}, true},
+		{"once without run_at", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeOnce}, time.Time{}, true},
+		{"monthly", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeCron, Cron: "0 9 1 * *", EndTime: &endOfYear}, time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC), false},
+		{"monthly from run_at", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeCron, Cron: "0 9 1 * *", RunAt: &endOfYear}, time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), false},
+		{"invalid cron", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeCron, Cron: "0 9 1 *"}, time.Time{}, true},
+		{"ends before first run", &dto.WorkflowScheduleDTO{Type: do.ScheduleTypeCron, Cron: "0 9 1 * *", EndTime: &later}, time.Time{}, true},
+	}
+	for _, tc := range cases {
+		workflow := &do.WorkFlowInfo{}
+		err := applySchedule(workflow, tc.input, now)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+		}
+		if err != nil {
+			if !errors.Is(err, InvalidScheduleError) {
+				t.Fatalf("%s: expected InvalidScheduleError, got %v", tc.name, err)
+			}
+			continue
+		}
+		if tc.input == nil {
+			if workflow.IsScheduled() || workflow.NextRunTime != nil {
+				t.Fatalf("%s: expected no schedule, got %+v", tc.name, workflow)
+			}
+			continue
+		}
+		if workflow.ScheduleStatus != do.ScheduleStatusActive || workflow.NextRunTime == nil || !workflow.NextRunTime.Equal(tc.wantNext) {
+			t.Fatalf("%s: expected active schedule at %s, got %s at %v", tc.name, tc.wantNext, workflow.ScheduleStatus, workflow.NextRunTime)
+		}
+	}
+}
+
+func TestAdvanceSchedule(t *testing.T) {
+	now := time.Date(2024, 11, 1, 9, 0, 5, 0, time.UTC)
+	end := time.Date(2024, 12, 15, 0, 0, 0, 0, time.UTC)
+
+	monthly := &do.WorkFlowInfo{ScheduleType: do.ScheduleTypeCron, ScheduleCron: "0 9 1 * *", ScheduleStatus: do.ScheduleStatusActive, ScheduleEndTime: &end, RunCount: 1}
+	if err := advanceSchedule(monthly, now); err != nil {
+		t.Fatalf("advanceSchedule failed: %v", err)
+	}
+	if monthly.ScheduleStatus != do.ScheduleStatusActive || !monthly.NextRunTime.Equal(time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected next run on Dec 1, got %s at %v", monthly.ScheduleStatus, monthly.NextRunTime)
+	}
+	// the run after Dec 1 is past the end time
+	if err := advanceSchedule(monthly, *monthly.NextRunTime); err != nil {
+		t.Fatalf("advanceSchedule failed: %v", err)
+	}
+	if monthly.ScheduleStatus != do.ScheduleStatusCompleted || monthly.NextRunTime != nil {
+		t.Fatalf("Expected completed schedule, got %s at %v", monthly.ScheduleStatus, monthly.NextRunTime)
+	}
+
+	limited := &do.WorkFlowInfo{ScheduleType: do.ScheduleTypeCron, ScheduleCron: "* * * * *", ScheduleStatus: do.ScheduleStatusActive, MaxRuns: 2, RunCount: 2}
+	if err := advanceSchedule(limited, now); err != nil || limited.ScheduleStatus != do.ScheduleStatusCompleted {
+		t.Fatalf("Expected completed after max runs, got %s %v", limited.ScheduleStatus, err)
+	}
+
+	once := &do.WorkFlowInfo{ScheduleType: do.ScheduleTypeOnce, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &now, MaxRuns: 1, RunCount: 1}
+	if err := advanceSchedule(once, now); err != nil || once.ScheduleStatus != do.ScheduleStatusCompleted {
+		t.Fatalf("Expected completed one-off schedule, got %s %v", once.ScheduleStatus, err)
+	}
+}
+
+func TestScheduleDue(t *testing.T) {
+	now := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	cases := []struct {
+		name     string
+		workflow do.WorkFlowInfo
+		due      bool
+	}{
+		{"first run", do.WorkFlowInfo{Status: do.WorkFlowStatusApproved, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &past}, true},
+		{"not yet", do.WorkFlowInfo{Status: do.WorkFlowStatusApproved, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &future}, false},
+		{"pending approval", do.WorkFlowInfo{Status: do.WorkFlowStatusPending, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &past}, false},
+		{"previous run paid", do.WorkFlowInfo{Status: do.WorkFlowStatusPaid, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &past, RunCount: 1}, true},
+		{"previous run in flight", do.WorkFlowInfo{Status: do.WorkFlowStatusApproved, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &past, RunCount: 1}, false},
+		{"previous run failed", do.WorkFlowInfo{Status: do.WorkFlowStatusFailed, ScheduleStatus: do.ScheduleStatusActive, NextRunTime: &past, RunCount: 1}, false},
+		{"paused", do.WorkFlowInfo{Status: do.WorkFlowStatusApproved, ScheduleStatus: do.ScheduleStatusPaused, NextRunTime: &past}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.workflow.ScheduleDue(now); got != tc.due {
+			t.Errorf("%s: expected due %v, got %v", tc.name, tc.due, got)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/do"
+)
+
+// transitions lists the statuses each status may move to. A payout that a
+// reorg puts back to pending or an operator retries reopens its paid or
+// failed workflow, one the operator gives up cancels it. The next run of a
+// schedule reopens its paid workflow, a schedule cancelled before its
+// first run cancels its approved workflow.
+var transitions = map[string][]string{
+	do.WorkFlowStatusPending: {
+		do.WorkFlowStatusApproved,
+		do.WorkFlowStatusRejected,
+		do.WorkFlowStatusCancelled,
+		do.WorkFlowStatusExpired,
+	},
+	do.WorkFlowStatusApproved: {do.WorkFlowStatusPaid, do.WorkFlowStatusFailed, do.WorkFlowStatusCancelled},
+	do.WorkFlowStatusPaid:     {do.WorkFlowStatusApproved},
+	do.WorkFlowStatusFailed:   {do.WorkFlowStatusApproved, do.WorkFlowStatusCancelled},
+}
+
+// IllegalTransitionError is returned when a workflow cannot move from its
+// current status to the requested one.
+type IllegalTransitionError struct {
+	WorkflowID int
+	From       string
+	To         string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("workflow %d cannot move from %s to %s", e.WorkflowID, e.From, e.To)
+}
+
+var (
+	WorkflowNotFoundError   = errors.New("WorkflowNotFoundError")
+	WorkflowNotPendingError = errors.New("WorkflowNotPendingError")
+	AlreadyVotedError       = errors.New("AlreadyVotedError")
+	NotAllowedToVoteError   = errors.New("NotAllowedToVoteError")
+	NotAllowedToCancelError = errors.New("NotAllowedToCancelError")
+	InvalidSignatureError   = errors.New("InvalidSignatureError")
+	NotAllowedToManageError = errors.New("NotAllowedToManageError")
+	ManagementNotFoundError = errors.New("ManagementNotFoundError")
+	LastFullMemberError     = errors.New("LastFullMemberError")
+	PayoutNotFoundError     = errors.New("PayoutNotFoundError")
+	PayoutNotStuckError     = errors.New("PayoutNotStuckError")
+	PayoutFundsMovedError   = errors.New("PayoutFundsMovedError")
+	InvalidPageRequestError = errors.New("InvalidPageRequestError")
+	InvalidScheduleError    = errors.New("InvalidScheduleError")
+	NotScheduledError       = errors.New("NotScheduledError")
+	ScheduleStatusError     = errors.New("ScheduleStatusError")
+	ScheduleNotAllowedError = errors.New("ScheduleNotAllowedError")
+)
+
+func canTransition(from, to string) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition validates and applies a status change of workflow and records
+// it in workflow_status_history. db is usually the caller's transaction.
+func Transition(db *gorm.DB, workflow *do.WorkFlowInfo, to, operatorAddr, reason string) error {
+	from := workflow.Status
+	if !canTransition(from, to) {
+		return &IllegalTransitionError{WorkflowID: workflow.ID, From: from, To: to}
+	}
+
+	workflow.Status = to
+	workflow.UpdatedBy = operatorAddr
+	workflow.UpdatedAddr = operatorAddr
+	workflow.UpdatedTime = time.Now()
+	if err := do.NewWorkFlowInfoManager(db).Update(workflow); err != nil {
+		return fmt.Errorf("update workflow %d status error: %w", workflow.ID, err)
+	}
+
+	return recordTransition(db, workflow.ID, from, to, operatorAddr, reason)
+}
+
+// TransitionByID locks the workflow and applies Transition. It is meant for
+// jobs that only know the workflow id, such as payout settlement.
+func TransitionByID(db *gorm.DB, workflowID int, to, operatorAddr, reason string) error {
+	workflow, err := do.NewWorkFlowInfoManager(db).GetByIDForUpdate(workflowID)
+	if err != nil {
+		return fmt.Errorf("get workflow %d error: %w", workflowID, err)
+	}
+	if workflow == nil {
+		return fmt.Errorf("%w: %d", WorkflowNotFoundError, workflowID)
+	}
+	if workflow.Status == to {
+		return nil
+	}
+	return Transition(db, workflow, to, operatorAddr, reason)
+}
+
+func (service *Service) ListStatusHistory(workflowID int) ([]do.WorkflowStatusHistory, error) {
+	return do.NewWorkflowStatusHistoryManager(service.db).ListByWorkflowID(workflowID)
+}
+
+func recordTransition(db *gorm.DB, workflowID int, from, to, operatorAddr, reason string) error {
+	if len(reason) > 512 {
+		reason = reason[:512]
+	}
+	return do.NewWorkflowStatusHistoryManager(db).Create(&do.WorkflowStatusHistory{
+		WorkflowID:   workflowID,
+		FromStatus:   from,
+		ToStatus:     to,
+		Reason:       reason,
+		OperatorAddr: operatorAddr,
+		CreatedTime:  time.Now(),
+	})
+}
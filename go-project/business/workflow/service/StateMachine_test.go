@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-project/business/workflow/do"
+)
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{do.WorkFlowStatusPending, do.WorkFlowStatusApproved, true},
+		{do.WorkFlowStatusPending, do.WorkFlowStatusRejected, true},
+		{do.WorkFlowStatusPending, do.WorkFlowStatusCancelled, true},
+		{do.WorkFlowStatusPending, do.WorkFlowStatusExpired, true},
+		{do.WorkFlowStatusPending, do.WorkFlowStatusPaid, false},
+		{do.WorkFlowStatusApproved, do.WorkFlowStatusPaid, true},
+		{do.WorkFlowStatusApproved, do.WorkFlowStatusFailed, true},
+		{do.WorkFlowStatusApproved, do.WorkFlowStatusRejected, false},
+		{do.WorkFlowStatusApproved, do.WorkFlowStatusCancelled, true},
+		{do.WorkFlowStatusRejected, do.WorkFlowStatusApproved, false},
+		{do.WorkFlowStatusCancelled, do.WorkFlowStatusPending, false},
+		{do.WorkFlowStatusExpired, do.WorkFlowStatusApproved, false},
+		{do.WorkFlowStatusPaid, do.WorkFlowStatusApproved, true},
+		{do.WorkFlowStatusFailed, do.WorkFlowStatusCancelled, true},
+		{do.WorkFlowStatusPaid, do.WorkFlowStatusCancelled, false},
+	}
+	for _, c := range cases {
+		if got := canTransition(c.from, c.to); got != c.allowed {
+			t.Errorf("%s -> %s: expected %v, got %v", c.from, c.to, c.allowed, got)
+		}
+	}
+}
+
+func TestTransition_IllegalReturnsTypedError(t *testing.T) {
+	workflow := &do.WorkFlowInfo{ID: 3, Status: do.WorkFlowStatusRejected}
+
+	// rejected transitions are checked before any database access
+	err := Transition(nil, workflow, do.WorkFlowStatusApproved, "0x0", "")
+	var illegal *IllegalTransitionError
+	if !errors.As(err, &illegal) {
+		t.Fatalf("Expected IllegalTransitionError, got %v", err)
+	}
+	if illegal.WorkflowID != 3 || illegal.From != do.WorkFlowStatusRejected || illegal.To != do.WorkFlowStatusApproved {
+		t.Fatalf("Unexpected error fields %+v", illegal)
+	}
+	if workflow.Status != do.WorkFlowStatusRejected {
+		t.Fatalf("Status must not change on illegal transition, got %s", workflow.Status)
+	}
+}
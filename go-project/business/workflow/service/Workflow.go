@@ -1,19 +1,30 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	do2 "go-project/business/token/do"
 	"go-project/business/workflow/do"
 	"go-project/business/workflow/dto"
+	"go-project/chain/eth"
+	globalconst "go-project/common"
 	"go-project/common/types"
 	"go-project/main/log"
+	"go-project/util/amount"
 )
 
+// approvalSignatureMaxTtl bounds how far ahead an approval deadline may be.
+const approvalSignatureMaxTtl = time.Hour
+
 type Service struct {
 	logger *log.ZapLogger
 	db     *gorm.DB
@@ -26,12 +37,19 @@ func NewService(logger *log.ZapLogger, db *gorm.DB) *Service {
 	}
 }
 
-func (service *Service) CreateWorkFlowService(dto *dto.WorkflowInfoCreateDTO) (*do.WorkFlowInfo, error) {
+// CreateWorkFlowService creates a workflow on behalf of the authenticated
+// callerAddr; it starts approved when the caller has full permission.
+func (service *Service) CreateWorkFlowService(dto *dto.WorkflowInfoCreateDTO, callerAddr string) (*do.WorkFlowInfo, error) {
 	var newWorkflow *do.WorkFlowInfo
 
 	err := service.db.Transaction(func(tx *gorm.DB) error {
+		tokenInfo, baseUnits, err := service.resolvePayout(tx, dto)
+		if err != nil {
+			return err
+		}
+
 		managementManager := do.NewManagementManager(tx)
-		hasFullPermission, err := managementManager.HasFullPermission(dto.ToAddr)
+		hasFullPermission, err := managementManager.HasFullPermission(callerAddr)
 		if err != nil {
 			return fmt.Errorf("check permission error: %w", err)
 		}
@@ -44,46 +62,36 @@ func (service *Service) CreateWorkFlowService(dto *dto.WorkflowInfoCreateDTO) (*
 		newWorkflow = &do.WorkFlowInfo{
 			WorkflowName: dto.WorkflowName,
 			ToAddr:       dto.ToAddr,
-			TokenInfoID:  1,
+			TokenInfoID:  tokenInfo.ID,
+			Amount:       baseUnits.String(),
 			Description:  dto.Description,
 			Status:       status,
-			CreateBy:     dto.ToAddr,
-			CreateAddr:   dto.ToAddr,
+			CreateBy:     callerAddr,
+			CreateAddr:   callerAddr,
 			CreatedTime:  time.Now(),
 		}
+		if err := applySchedule(newWorkflow, dto.Schedule, time.Now()); err != nil {
+			return err
+		}
 
 		workflowManager := do.NewWorkFlowInfoManager(tx)
 		err = workflowManager.Create(newWorkflow)
 		if err != nil {
 			return fmt.Errorf("CreateWorkFlow create error: %w", err)
 		}
-
+		reason := "created"
 		if status == do.WorkFlowStatusApproved {
-			tokenInfoManager := do2.NewTokenInfoManager(tx)
-			tokenInfo, err := tokenInfoManager.GetByID(1)
-			if err != nil {
-				service.logger.Error("CreateWorkFlow tokenInfoManager GetByID", zap.Error(err))
-				return err
-			}
-
-			tokenTransferLog := &do2.TokenTransferLog{
-				TokenInfoID:     newWorkflow.TokenInfoID,
-				WorkflowID:      newWorkflow.ID,
-				FromAddress:     "0x0",
-				ToAddress:       newWorkflow.ToAddr,
-				ContractAddress: tokenInfo.ContractAddress,
-				Amount:          0,
-				TransferData:    "",
-				Status:          do2.StatusPending,
-				RetryCount:      0,
-				TransactionHash: "",
-				CreateBy:        newWorkflow.CreateBy,
-				CreateAddr:      newWorkflow.CreateAddr,
-				CreatedTime:     time.Now(),
-			}
+			reason = "created by full permission member"
+		}
+		err = recordTransition(tx, newWorkflow.ID, "", status, callerAddr, reason)
+		if err != nil {
+			return err
+		}
 
+		// scheduled workflows are paid by RunDueSchedules
+		if status == do.WorkFlowStatusApproved && !newWorkflow.IsScheduled() {
 			tokenTransferLogManager := do2.NewTokenTransferLogManager(tx)
-			err = tokenTransferLogManager.Create(tokenTransferLog)
+			err = tokenTransferLogManager.Create(newTokenTransferLog(newWorkflow, tokenInfo))
 			if err != nil {
 				return fmt.Errorf("create TokenTransferLog error: %w", err)
 			}
@@ -99,13 +107,65 @@ func (service *Service) CreateWorkFlowService(dto *dto.WorkflowInfoCreateDTO) (*
 	return newWorkflow, nil
 }
 
-func (service *Service) PageWorkFlowList(req types.GenericPageReq[dto.WorkflowInfoCreateDTO]) (*types.GenericPageResp[do.WorkFlowInfo], error) {
+// ResolvePayout loads the workflow's token and converts the human readable
+// amount into token base units.
+func (service *Service) ResolvePayout(input *dto.WorkflowInfoCreateDTO) (*do2.TokenInfo, *big.Int, error) {
+	return service.resolvePayout(service.db, input)
+}
+
+func (service *Service) resolvePayout(db *gorm.DB, input *dto.WorkflowInfoCreateDTO) (*do2.TokenInfo, *big.Int, error) {
+	toAddr, err := payoutRecipient(input.ToAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	input.ToAddr = toAddr
+
+	tokenInfo, err := do2.NewTokenInfoManager(db).GetByID(input.TokenInfoID)
+	if err != nil {
+		service.logger.Error("resolvePayout tokenInfoManager GetByID", zap.Error(err))
+		return nil, nil, err
+	}
+	if tokenInfo == nil {
+		return nil, nil, fmt.Errorf("token info %d not found", input.TokenInfoID)
+	}
+
+	baseUnits, err := amount.ToBaseUnits(input.Amount, tokenInfo.Decimals)
+	if err != nil {
+		return nil, nil, err
+	}
+	if baseUnits.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("amount must be greater than 0")
+	}
+	return tokenInfo, baseUnits, nil
+}
+
+// payoutRecipient checks addr is an address and returns it checksummed. The
+// dispatcher pays whatever HexToAddress makes of to_addr, so a typo must
+// not get that far.
+func payoutRecipient(addr string) (string, error) {
+	if !common.IsHexAddress(addr) {
+		return "", fmt.Errorf("invalid to_addr %q", addr)
+	}
+	return common.HexToAddress(addr).Hex(), nil
+}
+
+// PageWorkFlowList returns one page of filtered workflows, newest first
+// unless another order is requested.
+func (service *Service) PageWorkFlowList(req *dto.WorkflowPageDTO) (*types.GenericPageResp[do.WorkFlowInfo], error) {
 	if req.PageNum == 0 {
 		req.PageNum = 1
 	}
 	if req.PageSize == 0 {
 		req.PageSize = 10
 	}
+	if req.PageSize > maxPageSize {
+		req.PageSize = maxPageSize
+	}
+
+	query, err := workflowQuery(req)
+	if err != nil {
+		return nil, err
+	}
 
 	resp := &types.GenericPageResp[do.WorkFlowInfo]{
 		PageResp: types.PageResp{
@@ -117,98 +177,252 @@ func (service *Service) PageWorkFlowList(req types.GenericPageReq[dto.WorkflowIn
 	offset := (resp.PageNum - 1) * resp.PageSize
 
 	workflowManager := do.NewWorkFlowInfoManager(service.db)
-	list, err := workflowManager.Page(offset, resp.PageSize)
+	list, err := workflowManager.Page(query, offset, resp.PageSize)
 	if err != nil {
 		service.logger.Error("PageWorkFlowList Page", zap.Any("err", err))
 		return nil, err
 	}
 
-	total, err := workflowManager.Count()
+	total, err := workflowManager.Count(query)
 	if err != nil {
 		service.logger.Error("PageWorkFlowList Count", zap.Any("err", err))
 		return nil, err
 	}
 
 	resp.List = list
+	resp.Total = total
 	resp.TotalPage = (total + resp.PageSize - 1) / resp.PageSize
+	if uint64(len(list)) == resp.PageSize {
+		resp.NextCursor = encodeWorkflowCursor(query.SortBy, &list[len(list)-1])
+	}
 	return resp, nil
 }
 
-func (service *Service) ApproveWorkFlow(input *dto.WorkFlowApprovalDTO) error {
+// ApproveWorkFlow records one vote per approver and moves the workflow to
+// approved or rejected once the policy's quorum or a veto is reached. The
+// vote must be signed by the authenticated callerAddr.
+func (service *Service) ApproveWorkFlow(input *dto.WorkFlowApprovalDTO, callerAddr string) error {
+	approverAddr, err := verifyApprovalSignature(input, callerAddr, time.Now())
+	if err != nil {
+		return err
+	}
+
 	return service.db.Transaction(func(tx *gorm.DB) error {
-		workflowManager := do.NewWorkFlowInfoManager(tx)
-		workflow, err := workflowManager.GetByID(input.WorkflowID)
+		workflow, err := do.NewWorkFlowInfoManager(tx).GetByIDForUpdate(input.WorkflowID)
 		if err != nil {
 			return fmt.Errorf("getById error: %w", err)
 		}
 		if workflow == nil {
-			return fmt.Errorf("getById is null")
+			return fmt.Errorf("%w: %d", WorkflowNotFoundError, input.WorkflowID)
+		}
+		if workflow.Status != do.WorkFlowStatusPending {
+			return fmt.Errorf("%w: workflow %d is %s", WorkflowNotPendingError, workflow.ID, workflow.Status)
+		}
+
+		policy, err := approvalPolicyFor(tx, workflow)
+		if err != nil {
+			service.logger.Error("ApproveWorkFlow approvalPolicyFor", zap.Error(err))
+			return err
+		}
+		managementManager := do.NewManagementManager(tx)
+		approvers, err := managementManager.ListByAddrs([]string{approverAddr})
+		if err != nil {
+			return fmt.Errorf("get approver error: %w", err)
+		}
+		if len(approvers) == 0 || !(policy.AllowsLevel(approvers[0].PermissionLevel) || policy.VetoesLevel(approvers[0].PermissionLevel)) {
+			return fmt.Errorf("%w: %s under policy %s", NotAllowedToVoteError, approverAddr, policy.Name)
+		}
+		approver := approvers[0]
+
+		workflowApproveManager := do.NewWorkFlowApproveManager(tx)
+		existing, err := workflowApproveManager.GetByWorkflowIDAndAddr(workflow.ID, approverAddr)
+		if err != nil {
+			return fmt.Errorf("get vote error: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("%w: %s already voted %s", AlreadyVotedError, approverAddr, existing.Status)
 		}
 
 		approve := &do.WorkFlowApprove{
 			WorkflowID:  input.WorkflowID,
-			ApproveAddr: input.ApproverAddr,
+			ApproveAddr: approverAddr,
 			Status:      input.ApprovalStatus,
+			Deadline:    input.Deadline,
+			Signature:   input.Signature,
 			ApproveTime: time.Now(),
-			CreateBy:    input.ApproverAddr,
-			CreateAddr:  input.ApproverAddr,
+			CreateBy:    approverAddr,
+			CreateAddr:  approverAddr,
 			CreatedTime: time.Now(),
 		}
-
-		workflowApproveManager := do.NewWorkFlowApproveManager(tx)
 		err = workflowApproveManager.Create(approve)
 		if err != nil {
 			service.logger.Error("Create WorkFlowApprove error", zap.Error(err))
 			return err
 		}
 
-		count, err := workflowApproveManager.CountUniqueApprovedAddresses(input.WorkflowID)
-		if err != nil {
-			service.logger.Error("Count approved addresses error", zap.Error(err))
-			return err
-		}
-
-		if count >= 2 {
-			workflow.Status = do.WorkFlowStatusApproved
-			workflow.UpdatedBy = input.ApproverAddr
-			workflow.UpdatedAddr = input.ApproverAddr
-			workflow.UpdatedTime = time.Now()
-
-			err = workflowManager.Update(workflow)
-			if err != nil {
-				service.logger.Error("Update workflow status error", zap.Error(err))
-				return err
+		if input.ApprovalStatus == do.WorkFlowStatusRejected {
+			if policy.VetoesLevel(approver.PermissionLevel) {
+				return Transition(tx, workflow, do.WorkFlowStatusRejected, approverAddr, fmt.Sprintf("vetoed by %s", approverAddr))
 			}
-			tokenInfoManager := do2.NewTokenInfoManager(tx)
-			tokenInfo, err := tokenInfoManager.GetByID(1)
+			rejectedWeight, err := votedWeight(tx, policy, workflow.ID, do.WorkFlowStatusRejected)
 			if err != nil {
-				service.logger.Error("CreateWorkFlow tokenInfoManager GetByID", zap.Error(err))
 				return err
 			}
-
-			tokenTransferLog := &do2.TokenTransferLog{
-				TokenInfoID:     workflow.TokenInfoID,
-				WorkflowID:      workflow.ID,
-				FromAddress:     "0x0",
-				ToAddress:       workflow.ToAddr,
-				ContractAddress: tokenInfo.ContractAddress,
-				Amount:          0,
-				TransferData:    "",
-				Status:          do2.StatusPending,
-				RetryCount:      0,
-				TransactionHash: "",
-				CreateBy:        workflow.CreateBy,
-				CreateAddr:      workflow.CreateAddr,
-				CreatedTime:     time.Now(),
+			if rejectedWeight >= policy.RejectWeight {
+				return Transition(tx, workflow, do.WorkFlowStatusRejected, approverAddr, fmt.Sprintf("reject weight %d reached policy %s", rejectedWeight, policy.Name))
 			}
+			return nil
+		}
 
-			tokenTransferLogManager := do2.NewTokenTransferLogManager(tx)
-			err = tokenTransferLogManager.Create(tokenTransferLog)
-			if err != nil {
-				return fmt.Errorf("create TokenTransferLog error: %w", err)
-			}
+		approvedWeight, err := votedWeight(tx, policy, workflow.ID, do.WorkFlowStatusApproved)
+		if err != nil {
+			return err
+		}
+		if approvedWeight < policy.RequiredWeight {
+			return nil
 		}
 
+		err = Transition(tx, workflow, do.WorkFlowStatusApproved, approverAddr, fmt.Sprintf("approve weight %d reached policy %s", approvedWeight, policy.Name))
+		if err != nil {
+			service.logger.Error("Update workflow status error", zap.Error(err))
+			return err
+		}
+		if workflow.IsScheduled() {
+			// paid by RunDueSchedules when its first run is due
+			return nil
+		}
+		tokenInfo, err := do2.NewTokenInfoManager(tx).GetByID(workflow.TokenInfoID)
+		if err != nil {
+			service.logger.Error("ApproveWorkFlow tokenInfoManager GetByID", zap.Error(err))
+			return err
+		}
+		if tokenInfo == nil {
+			return fmt.Errorf("token info %d not found", workflow.TokenInfoID)
+		}
+
+		err = do2.NewTokenTransferLogManager(tx).Create(newTokenTransferLog(workflow, tokenInfo))
+		if err != nil {
+			return fmt.Errorf("create TokenTransferLog error: %w", err)
+		}
 		return nil
 	})
 }
+
+// ApprovalTypedData is the message an approver signs for input.
+func (service *Service) ApprovalTypedData(input *dto.WorkFlowApprovalTypedDataDTO) apitypes.TypedData {
+	return eth.ApprovalTypedData(input.WorkflowID, input.ApprovalStatus, input.Deadline, globalconst.ChainId)
+}
+
+// verifyApprovalSignature recovers the signer of the vote and checks it is
+// the caller. The signed deadline must not have passed and may be at most
+// approvalSignatureMaxTtl away, so a captured signature cannot be submitted
+// later; the vote itself is single use through uk_workflow_approve_addr. It
+// returns the checksummed approver address.
+func verifyApprovalSignature(input *dto.WorkFlowApprovalDTO, callerAddr string, now time.Time) (string, error) {
+	if !common.IsHexAddress(callerAddr) {
+		return "", fmt.Errorf("%w: invalid caller address", InvalidSignatureError)
+	}
+	deadline := time.Unix(int64(input.Deadline), 0)
+	if deadline.Before(now) {
+		return "", fmt.Errorf("%w: signature expired at %s", InvalidSignatureError, deadline.Format(time.RFC3339))
+	}
+	if deadline.After(now.Add(approvalSignatureMaxTtl)) {
+		return "", fmt.Errorf("%w: deadline %s is more than %s away", InvalidSignatureError, deadline.Format(time.RFC3339), approvalSignatureMaxTtl)
+	}
+	signature, err := hexutil.Decode(input.Signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidSignatureError, err)
+	}
+	typedData := eth.ApprovalTypedData(input.WorkflowID, input.ApprovalStatus, input.Deadline, globalconst.ChainId)
+	recovered, err := eth.RecoverTypedDataSigner(typedData, signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", InvalidSignatureError, err)
+	}
+	if recovered != common.HexToAddress(callerAddr) {
+		return "", fmt.Errorf("%w: signed by %s, not %s", InvalidSignatureError, recovered.Hex(), callerAddr)
+	}
+	return recovered.Hex(), nil
+}
+
+// votedWeight sums the policy weight of the members that voted status.
+func votedWeight(db *gorm.DB, policy *do.ApprovalPolicy, workflowID int, status string) (int, error) {
+	addrs, err := do.NewWorkFlowApproveManager(db).ListAddressesByStatus(workflowID, status)
+	if err != nil {
+		return 0, fmt.Errorf("list %s votes error: %w", status, err)
+	}
+	voters, err := do.NewManagementManager(db).ListByAddrs(addrs)
+	if err != nil {
+		return 0, fmt.Errorf("get voters error: %w", err)
+	}
+	return approvedWeight(policy, voters), nil
+}
+
+// CancelWorkFlow withdraws a pending workflow. Only its creator or a member
+// with full permission may cancel it.
+func (service *Service) CancelWorkFlow(input *dto.WorkFlowCancelDTO, callerAddr string) error {
+	return service.db.Transaction(func(tx *gorm.DB) error {
+		workflow, err := do.NewWorkFlowInfoManager(tx).GetByIDForUpdate(input.WorkflowID)
+		if err != nil {
+			return fmt.Errorf("getById error: %w", err)
+		}
+		if workflow == nil {
+			return fmt.Errorf("%w: %d", WorkflowNotFoundError, input.WorkflowID)
+		}
+
+		if err := requireCreatorOrFullPermission(tx, workflow, callerAddr, NotAllowedToCancelError); err != nil {
+			return err
+		}
+		if workflow.Status == do.WorkFlowStatusApproved {
+			// an approved workflow is being paid, only a schedule that
+			// has not run yet may cancel it, see CancelSchedule
+			return &IllegalTransitionError{WorkflowID: workflow.ID, From: workflow.Status, To: do.WorkFlowStatusCancelled}
+		}
+
+		return Transition(tx, workflow, do.WorkFlowStatusCancelled, callerAddr, input.Reason)
+	})
+}
+
+// ExpirePendingWorkflows moves workflows that stayed pending since before
+// createdBefore to expired and returns how many were expired.
+func (service *Service) ExpirePendingWorkflows(createdBefore time.Time, limit int) (int, error) {
+	workflows, err := do.NewWorkFlowInfoManager(service.db).ListPendingCreatedBefore(createdBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("list pending workflows error: %w", err)
+	}
+
+	expired := 0
+	for _, workflow := range workflows {
+		err := service.db.Transaction(func(tx *gorm.DB) error {
+			return TransitionByID(tx, workflow.ID, do.WorkFlowStatusExpired, "WorkflowExpirer", "no decision before deadline")
+		})
+		var illegal *IllegalTransitionError
+		if errors.As(err, &illegal) {
+			// decided while we were looking
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// newTokenTransferLog builds the pending payout of an approved workflow.
+func newTokenTransferLog(workflow *do.WorkFlowInfo, tokenInfo *do2.TokenInfo) *do2.TokenTransferLog {
+	return &do2.TokenTransferLog{
+		TokenInfoID:     workflow.TokenInfoID,
+		WorkflowID:      workflow.ID,
+		FromAddress:     "0x0",
+		ToAddress:       workflow.ToAddr,
+		ContractAddress: tokenInfo.ContractAddress,
+		Amount:          workflow.Amount,
+		TransferData:    "",
+		Status:          do2.StatusPending,
+		RetryCount:      0,
+		TransactionHash: "",
+		CreateBy:        workflow.CreateBy,
+		CreateAddr:      workflow.CreateAddr,
+		CreatedTime:     time.Now(),
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	scando "go-project/business/scan/do"
+	do2 "go-project/business/token/do"
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+)
+
+// GetWorkflowDetail loads a workflow with its votes, status history and
+// payouts. Receipt data comes from the transactions the scanner indexed.
+func (service *Service) GetWorkflowDetail(id int) (*dto.WorkflowDetailResp, error) {
+	workflow, err := do.NewWorkFlowInfoManager(service.db).GetByID(id)
+	if err != nil {
+		service.logger.Error("GetWorkflowDetail GetByID", zap.Error(err))
+		return nil, err
+	}
+	if workflow == nil {
+		return nil, fmt.Errorf("%w: %d", WorkflowNotFoundError, id)
+	}
+
+	resp := &dto.WorkflowDetailResp{Workflow: *workflow}
+	resp.Token, err = do2.NewTokenInfoManager(service.db).GetByID(workflow.TokenInfoID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Approvals, err = do.NewWorkFlowApproveManager(service.db).ListByWorkflowID(id)
+	if err != nil {
+		return nil, fmt.Errorf("list approvals error: %w", err)
+	}
+	resp.StatusHistory, err = do.NewWorkflowStatusHistoryManager(service.db).ListByWorkflowID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	transferLogs, err := do2.NewTokenTransferLogManager(service.db).ListByWorkflowID(id)
+	if err != nil {
+		return nil, err
+	}
+	transferLogIDs := make([]int, 0, len(transferLogs))
+	var txHashes []string
+	for _, transferLog := range transferLogs {
+		transferLogIDs = append(transferLogIDs, transferLog.ID)
+		if transferLog.TransactionHash != "" {
+			txHashes = append(txHashes, transferLog.TransactionHash)
+		}
+	}
+	sentTxs, err := do2.NewTokenTransferTxManager(service.db).ListByTransferLogIDs(transferLogIDs)
+	if err != nil {
+		return nil, err
+	}
+	sentByLog := make(map[int][]do2.TokenTransferTx, len(transferLogs))
+	for _, tx := range sentTxs {
+		sentByLog[tx.TokenTransferLogID] = append(sentByLog[tx.TokenTransferLogID], tx)
+		txHashes = append(txHashes, tx.TxHash)
+	}
+
+	mined, err := scando.NewTransactionInfoManager(service.db).ListByTxHashes(txHashes)
+	if err != nil {
+		return nil, fmt.Errorf("list transactions error: %w", err)
+	}
+	minedByHash := make(map[string]*scando.TransactionInfo, len(mined))
+	for i := range mined {
+		minedByHash[mined[i].TxHash] = &mined[i]
+	}
+	resp.ScannedBlock, err = scando.NewBlockInfoManager(service.db).GetLatestBlockNumber()
+	if err != nil {
+		return nil, fmt.Errorf("get scanned block error: %w", err)
+	}
+
+	resp.Payouts = make([]dto.PayoutDetail, 0, len(transferLogs))
+	for _, transferLog := range transferLogs {
+		resp.Payouts = append(resp.Payouts, dto.PayoutDetail{
+			TokenTransferLog: transferLog,
+			Transactions:     payoutTransactions(&transferLog, sentByLog[transferLog.ID], minedByHash, resp.ScannedBlock),
+		})
+	}
+	return resp, nil
+}
+
+// payoutTransactions lists the transactions sent for a payout with their
+// receipts. Payouts sent before transactions were recorded separately only
+// have the hash on the transfer log.
+func payoutTransactions(transferLog *do2.TokenTransferLog, sent []do2.TokenTransferTx, minedByHash map[string]*scando.TransactionInfo, scannedBlock uint64) []dto.PayoutTxDetail {
+	details := make([]dto.PayoutTxDetail, 0, len(sent)+1)
+	seen := make(map[string]bool, len(sent))
+	for _, tx := range sent {
+		seen[tx.TxHash] = true
+		details = append(details, payoutTxDetail(tx.TxHash, tx.TxType, tx.Nonce, minedByHash, scannedBlock))
+	}
+	if transferLog.TransactionHash != "" && !seen[transferLog.TransactionHash] {
+		details = append(details, payoutTxDetail(transferLog.TransactionHash, do2.TxTypeOriginal, 0, minedByHash, scannedBlock))
+	}
+	return details
+}
+
+func payoutTxDetail(txHash, txType string, nonce uint64, minedByHash map[string]*scando.TransactionInfo, scannedBlock uint64) dto.PayoutTxDetail {
+	detail := dto.PayoutTxDetail{TxHash: txHash, TxType: txType, Nonce: nonce}
+	txInfo, ok := minedByHash[txHash]
+	if !ok {
+		return detail
+	}
+	detail.Mined = true
+	detail.Nonce = txInfo.Nonce
+	detail.ReceiptStatus = &txInfo.Status
+	detail.BlockNumber = &txInfo.BlockNumber
+	detail.GasUsed = &txInfo.GasUsed
+	detail.SafeConfirmations = confirmations(scannedBlock, txInfo.BlockNumber)
+	return detail
+}
+
+// confirmations counts the blocks from blockNumber up to head, the
+// inclusion block itself included, so a transaction in the head block has
+// one.
+func confirmations(head, blockNumber uint64) uint64 {
+	if head < blockNumber {
+		return 0
+	}
+	return head - blockNumber + 1
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+
+	scando "go-project/business/scan/do"
+	do2 "go-project/business/token/do"
+)
+
+func TestConfirmations(t *testing.T) {
+	cases := []struct {
+		head, block, want uint64
+	}{
+		{100, 100, 1},
+		{105, 100, 6},
+		{99, 100, 0},
+	}
+	for _, tc := range cases {
+		if got := confirmations(tc.head, tc.block); got != tc.want {
+			t.Errorf("confirmations(%d, %d): expected %d, got %d", tc.head, tc.block, tc.want, got)
+		}
+	}
+}
+
+func TestPayoutTransactions(t *testing.T) {
+	transferLog := &do2.TokenTransferLog{ID: 1, TransactionHash: "0xspeedup"}
+	sent := []do2.TokenTransferTx{
+		{TxHash: "0xoriginal", TxType: do2.TxTypeOriginal, Nonce: 7},
+		{TxHash: "0xspeedup", TxType: do2.TxTypeSpeedUp, Nonce: 7},
+	}
+	minedByHash := map[string]*scando.TransactionInfo{
+		"0xspeedup": {TxHash: "0xspeedup", BlockNumber: 10, Status: 1, GasUsed: 21000, Nonce: 7},
+	}
+
+	details := payoutTransactions(transferLog, sent, minedByHash, 12)
+	if len(details) != 2 {
+		t.Fatalf("Expected 2 transactions, got %d", len(details))
+	}
+	if details[0].Mined || details[0].ReceiptStatus != nil {
+		t.Errorf("Expected the replaced transaction unmined, got %+v", details[0])
+	}
+	speedUp := details[1]
+	if !speedUp.Mined || *speedUp.ReceiptStatus != 1 || *speedUp.BlockNumber != 10 || speedUp.SafeConfirmations != 3 {
+		t.Errorf("Unexpected speed up detail %+v", speedUp)
+	}
+
+	legacy := payoutTransactions(&do2.TokenTransferLog{TransactionHash: "0xlegacy"}, nil, minedByHash, 12)
+	if len(legacy) != 1 || legacy[0].TxHash != "0xlegacy" {
+		t.Errorf("Expected the transfer log hash when no transactions were recorded, got %+v", legacy)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+	"go-project/common/types"
+)
+
+const maxPageSize = 200
+
+// workflowCursor is the JSON inside the opaque page cursor.
+type workflowCursor struct {
+	SortBy string `json:"s"`
+	Value  string `json:"v,omitempty"`
+	ID     int    `json:"id"`
+}
+
+// workflowQuery turns the page request into the manager's query.
+func workflowQuery(req *dto.WorkflowPageDTO) (*do.WorkFlowInfoQuery, error) {
+	query := &do.WorkFlowInfoQuery{
+		Status:      req.Status,
+		ToAddr:      checksumAddress(req.ToAddr),
+		CreateAddr:  checksumAddress(req.CreateAddr),
+		TokenInfoID: req.TokenInfoID,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		Name:        req.Name,
+		SortBy:      req.SortBy,
+	}
+	switch req.SortType {
+	case "", types.SortTypeDesc:
+		query.Desc = true
+	case types.SortTypeAsc:
+	default:
+		return nil, fmt.Errorf("%w: unsupported sort_type %q", InvalidPageRequestError, req.SortType)
+	}
+	if query.SortBy == "" {
+		query.SortBy = do.WorkFlowSortID
+	}
+	if _, ok := do.WorkFlowSortColumns[query.SortBy]; !ok {
+		return nil, fmt.Errorf("%w: unsupported sort_by %q", InvalidPageRequestError, req.SortBy)
+	}
+	if req.Cursor != "" {
+		after, err := decodeWorkflowCursor(req.Cursor, query.SortBy)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", InvalidPageRequestError, err)
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+func encodeWorkflowCursor(sortBy string, last *do.WorkFlowInfo) string {
+	cursor := workflowCursor{SortBy: sortBy, ID: last.ID}
+	switch sortBy {
+	case do.WorkFlowSortCreatedTime:
+		cursor.Value = last.CreatedTime.Format(time.RFC3339Nano)
+	case do.WorkFlowSortUpdatedTime:
+		cursor.Value = last.UpdatedTime.Format(time.RFC3339Nano)
+	case do.WorkFlowSortAmount:
+		cursor.Value = last.Amount
+	case do.WorkFlowSortName:
+		cursor.Value = last.WorkflowName
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeWorkflowCursor parses a cursor, which is only valid with the sort
+// it was issued for.
+func decodeWorkflowCursor(encoded, sortBy string) (*do.WorkFlowInfoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var cursor workflowCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if cursor.SortBy != sortBy {
+		return nil, fmt.Errorf("invalid cursor: issued for sort_by %q, not %q", cursor.SortBy, sortBy)
+	}
+
+	after := &do.WorkFlowInfoCursor{ID: cursor.ID}
+	switch sortBy {
+	case do.WorkFlowSortCreatedTime, do.WorkFlowSortUpdatedTime:
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		after.Value = value
+	case do.WorkFlowSortAmount:
+		if _, ok := new(big.Int).SetString(cursor.Value, 10); !ok {
+			return nil, fmt.Errorf("invalid cursor: amount %q", cursor.Value)
+		}
+		after.Value = cursor.Value
+	case do.WorkFlowSortName:
+		after.Value = cursor.Value
+	}
+	return after, nil
+}
+
+// checksumAddress normalises a hex address filter to the checksummed form
+// addresses are stored in; anything else is kept as given.
+func checksumAddress(addr string) string {
+	if !common.IsHexAddress(addr) {
+		return addr
+	}
+	return common.HexToAddress(addr).Hex()
+}
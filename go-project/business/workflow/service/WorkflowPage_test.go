@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go-project/business/workflow/do"
+	"go-project/business/workflow/dto"
+	"go-project/common/types"
+)
+
+func TestWorkflowCursor_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 10, 2, 21, 35, 0, 0, time.UTC)
+	last := &do.WorkFlowInfo{ID: 42, WorkflowName: "payroll", Amount: "1500000", CreatedTime: created, UpdatedTime: created}
+
+	cases := map[string]any{
+		do.WorkFlowSortID:          nil,
+		do.WorkFlowSortCreatedTime: created,
+		do.WorkFlowSortUpdatedTime: created,
+		do.WorkFlowSortAmount:      "1500000",
+		do.WorkFlowSortName:        "payroll",
+	}
+	for sortBy, want := range cases {
+		after, err := decodeWorkflowCursor(encodeWorkflowCursor(sortBy, last), sortBy)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", sortBy, err)
+		}
+		if after.ID != 42 {
+			t.Errorf("%s: expected id 42, got %d", sortBy, after.ID)
+		}
+		if value, ok := after.Value.(time.Time); ok {
+			if !value.Equal(want.(time.Time)) {
+				t.Errorf("%s: expected %v, got %v", sortBy, want, value)
+			}
+		} else if after.Value != want {
+			t.Errorf("%s: expected %v, got %v", sortBy, want, after.Value)
+		}
+	}
+}
+
+func TestWorkflowCursor_Rejects(t *testing.T) {
+	last := &do.WorkFlowInfo{ID: 1, Amount: "10"}
+	if _, err := decodeWorkflowCursor(encodeWorkflowCursor(do.WorkFlowSortAmount, last), do.WorkFlowSortID); err == nil {
+		t.Error("Expected a cursor of another sort to be rejected")
+	}
+	if _, err := decodeWorkflowCursor("not base64!", do.WorkFlowSortID); err == nil {
+		t.Error("Expected a malformed cursor to be rejected")
+	}
+	forged := encodeWorkflowCursor(do.WorkFlowSortAmount, &do.WorkFlowInfo{ID: 1, Amount: "1 OR 1=1"})
+	if _, err := decodeWorkflowCursor(forged, do.WorkFlowSortAmount); err == nil {
+		t.Error("Expected a non numeric amount cursor to be rejected")
+	}
+}
+
+func TestWorkflowQuery_Defaults(t *testing.T) {
+	query, err := workflowQuery(&dto.WorkflowPageDTO{ToAddr: "0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266"})
+	if err != nil {
+		t.Fatalf("workflowQuery: %v", err)
+	}
+	if query.SortBy != do.WorkFlowSortID || !query.Desc {
+		t.Errorf("Expected newest first by id, got %s desc=%v", query.SortBy, query.Desc)
+	}
+	if query.ToAddr != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Errorf("Expected checksummed to_addr, got %s", query.ToAddr)
+	}
+
+	query, err = workflowQuery(&dto.WorkflowPageDTO{SortBy: do.WorkFlowSortAmount, SortType: types.SortTypeAsc})
+	if err != nil {
+		t.Fatalf("workflowQuery: %v", err)
+	}
+	if query.SortBy != do.WorkFlowSortAmount || query.Desc {
+		t.Errorf("Expected amount asc, got %s desc=%v", query.SortBy, query.Desc)
+	}
+}
+
+func TestWorkflowQuery_RejectsInvalidRequest(t *testing.T) {
+	cases := []*dto.WorkflowPageDTO{
+		{SortType: "descending"},
+		{SortBy: "password"},
+		{Cursor: "not base64!"},
+	}
+	for _, req := range cases {
+		if _, err := workflowQuery(req); !errors.Is(err, InvalidPageRequestError) {
+			t.Errorf("Expected InvalidPageRequestError for %+v, got %v", req, err)
+		}
+	}
+}
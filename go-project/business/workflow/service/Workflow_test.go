@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"go-project/business/workflow/dto"
+	"go-project/chain/eth"
+	globalconst "go-project/common"
+)
+
+func signedApproval(t *testing.T, workflowID int, decision string, deadline uint64) (*dto.WorkFlowApprovalDTO, string) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(eth.ApprovalTypedData(workflowID, decision, deadline, globalconst.ChainId))
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	return &dto.WorkFlowApprovalDTO{
+		WorkflowID:     workflowID,
+		ApprovalStatus: decision,
+		Deadline:       deadline,
+		Signature:      hexutil.Encode(signature),
+	}, address
+}
+
+var approvalNow = time.Unix(1700000000, 0)
+
+func TestVerifyApprovalSignature(t *testing.T) {
+	input, address := signedApproval(t, 5, "approved", 1700000600)
+
+	approverAddr, err := verifyApprovalSignature(input, address, approvalNow)
+	if err != nil {
+		t.Fatalf("Expected valid signature, got %v", err)
+	}
+	if approverAddr != address {
+		t.Fatalf("Expected %s, got %s", address, approverAddr)
+	}
+}
+
+func TestVerifyApprovalSignature_Rejects(t *testing.T) {
+	impersonated, _ := signedApproval(t, 5, "approved", 1700000600)
+
+	changedDecision, changedDecisionAddr := signedApproval(t, 5, "approved", 1700000600)
+	changedDecision.ApprovalStatus = "rejected"
+
+	otherWorkflow, otherWorkflowAddr := signedApproval(t, 5, "approved", 1700000600)
+	otherWorkflow.WorkflowID = 6
+
+	malformed, malformedAddr := signedApproval(t, 5, "approved", 1700000600)
+	malformed.Signature = "0x1234"
+
+	extended, extendedAddr := signedApproval(t, 5, "approved", 1700000600)
+	extended.Deadline = 1700000900
+
+	expired, expiredAddr := signedApproval(t, 5, "approved", 1699999999)
+
+	tooFar, tooFarAddr := signedApproval(t, 5, "approved", 1700007201)
+
+	for name, tc := range map[string]struct {
+		input      *dto.WorkFlowApprovalDTO
+		callerAddr string
+	}{
+		"signed by someone else": {impersonated, "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"},
+		"changed decision":       {changedDecision, changedDecisionAddr},
+		"other workflow":         {otherWorkflow, otherWorkflowAddr},
+		"malformed signature":    {malformed, malformedAddr},
+		"extended deadline":      {extended, extendedAddr},
+		"expired deadline":       {expired, expiredAddr},
+		"deadline too far":       {tooFar, tooFarAddr},
+	} {
+		if _, err := verifyApprovalSignature(tc.input, tc.callerAddr, approvalNow); !errors.Is(err, InvalidSignatureError) {
+			t.Errorf("%s: expected InvalidSignatureError, got %v", name, err)
+		}
+	}
+}
+
+func TestPayoutRecipient(t *testing.T) {
+	got, err := payoutRecipient("0xf39fd6e51aad88f6f4ce6ab8827279cfffb92266")
+	if err != nil || got != "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266" {
+		t.Fatalf("Expected the checksummed address, got %q %v", got, err)
+	}
+	for _, addr := range []string{"", "0xf39fd6e51aad88f6f4ce6ab8827279cfffb9226", "f39fd6e51aad88f6f4ce6ab8827279cfffb92266zz", "alice.eth"} {
+		if _, err := payoutRecipient(addr); err == nil {
+			t.Errorf("Expected %q to be rejected", addr)
+		}
+	}
+}
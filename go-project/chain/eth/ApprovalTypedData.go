@@ -0,0 +1,73 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	approvalDomainName    = "WorkflowApproval"
+	approvalDomainVersion = "1"
+	approvalPrimaryType   = "Approval"
+)
+
+var approvalTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+	},
+	approvalPrimaryType: {
+		{Name: "workflowId", Type: "uint256"},
+		{Name: "decision", Type: "string"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+// ApprovalTypedData is the EIP-712 message an approver signs to vote
+// decision on a workflow. deadline is the unix time in seconds after which
+// the signature is refused. It can be passed as is to eth_signTypedData_v4.
+func ApprovalTypedData(workflowID int, decision string, deadline uint64, chainID int64) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types:       approvalTypes,
+		PrimaryType: approvalPrimaryType,
+		Domain: apitypes.TypedDataDomain{
+			Name:    approvalDomainName,
+			Version: approvalDomainVersion,
+			ChainId: math.NewHexOrDecimal256(chainID),
+		},
+		Message: apitypes.TypedDataMessage{
+			"workflowId": (*math.HexOrDecimal256)(big.NewInt(int64(workflowID))),
+			"decision":   decision,
+			"deadline":   (*math.HexOrDecimal256)(new(big.Int).SetUint64(deadline)),
+		},
+	}
+}
+
+// RecoverTypedDataSigner returns the address that produced signature over
+// typedData. Both 0/1 and 27/28 recovery ids are accepted.
+func RecoverTypedDataSigner(typedData apitypes.TypedData, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("签名长度错误: %d", len(signature))
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("计算typed data哈希失败: %w", err)
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	copy(sig, signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复签名地址失败: %w", err)
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
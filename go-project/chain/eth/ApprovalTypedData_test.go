@@ -0,0 +1,63 @@
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+func signTypedData(t *testing.T, typedData apitypes.TypedData, hexKey string) ([]byte, *PrivateKeySigner) {
+	t.Helper()
+	signer, err := NewPrivateKeySigner(hexKey)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		t.Fatalf("Failed to hash typed data: %v", err)
+	}
+	signature, err := crypto.Sign(hash, signer.key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	// wallets return v as 27/28
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, signer
+}
+
+func TestRecoverTypedDataSigner(t *testing.T) {
+	typedData := ApprovalTypedData(12, "approved", 1700000000, 31337)
+	signature, signer := signTypedData(t, typedData, testOwnerPrvKey)
+
+	recovered, err := RecoverTypedDataSigner(typedData, signature)
+	if err != nil {
+		t.Fatalf("Failed to recover signer: %v", err)
+	}
+	if recovered != signer.Address() {
+		t.Fatalf("Expected %s, got %s", signer.Address().Hex(), recovered.Hex())
+	}
+}
+
+func TestRecoverTypedDataSigner_TamperedMessage(t *testing.T) {
+	signature, signer := signTypedData(t, ApprovalTypedData(12, "approved", 1, 31337), testOwnerPrvKey)
+
+	tampered := []apitypes.TypedData{
+		ApprovalTypedData(13, "approved", 1, 31337),
+		ApprovalTypedData(12, "rejected", 1, 31337),
+		ApprovalTypedData(12, "approved", 2, 31337),
+		ApprovalTypedData(12, "approved", 1, 1),
+	}
+	for i, typedData := range tampered {
+		recovered, err := RecoverTypedDataSigner(typedData, signature)
+		if err == nil && recovered == signer.Address() {
+			t.Errorf("case %d: signature must not verify for a different message", i)
+		}
+	}
+}
+
+func TestRecoverTypedDataSigner_BadLength(t *testing.T) {
+	if _, err := RecoverTypedDataSigner(ApprovalTypedData(1, "approved", 1, 31337), []byte{1, 2, 3}); err == nil {
+		t.Fatal("Expected error for short signature")
+	}
+}
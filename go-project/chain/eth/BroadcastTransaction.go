@@ -0,0 +1,170 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
+
+	globalconst "go-project/common"
+)
+
+// NonceTooLowError means the node already has a mined transaction with the
+// nonce of the one being broadcast, so it can never be mined. Check for a
+// receipt before treating it as dropped, it may be the one that was mined.
+var NonceTooLowError = errors.New("NonceTooLowError")
+
+// TransferOptions replaces the estimated gas limit and fees of a transfer,
+// e.g. when an operator retries a payout that kept failing. Zero values
+// keep the estimate.
+type TransferOptions struct {
+	GasLimit             uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// PrepareERC20Transfer signs, without broadcasting, a transfer of amount
+// tokens to toAddress. The caller persists the result and then calls
+// Broadcast, or Discard when the result could not be persisted.
+func (s *BusinessService) PrepareERC20Transfer(
+	ctx context.Context,
+	toAddress string,
+	contractAddress string,
+	amount *big.Int,
+	opts TransferOptions,
+) (*TransferResult, error) {
+	if err := s.requireERC20Balance(ctx, contractAddress, amount); err != nil {
+		return nil, err
+	}
+	data := erc20TransferData(common.HexToAddress(toAddress), amount)
+	return s.prepareTransfer(ctx, common.HexToAddress(contractAddress), big.NewInt(0), data, opts)
+}
+
+// PrepareNativeTransfer signs, without broadcasting, a transfer of amount
+// wei of the native asset to toAddress.
+func (s *BusinessService) PrepareNativeTransfer(
+	ctx context.Context,
+	toAddress string,
+	amount *big.Int,
+	opts TransferOptions,
+) (*TransferResult, error) {
+	if err := s.requireNativeBalance(ctx, amount); err != nil {
+		return nil, err
+	}
+	return s.prepareTransfer(ctx, common.HexToAddress(toAddress), amount, nil, opts)
+}
+
+// prepareTransfer reserves a nonce and signs the transaction. The nonce is
+// released when signing fails and stays reserved otherwise.
+func (s *BusinessService) prepareTransfer(ctx context.Context, to common.Address, value *big.Int, data []byte, opts TransferOptions) (*TransferResult, error) {
+	from := s.signer.Address()
+
+	suggested, err := suggestDynamicFee(s.ethClient, s.feeConfig)
+	if err != nil {
+		return nil, err
+	}
+	fee := overrideFee(suggested, opts)
+	gasLimit := opts.GasLimit
+	if gasLimit == 0 {
+		gasLimit, err = estimateGasLimit(ctx, s.ethClient, s.feeConfig, from, &to, value, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	nonce, err := s.reserveNonce(from)
+	if err != nil {
+		return nil, fmt.Errorf("获取nonce失败: %w", err)
+	}
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(globalconst.ChainId),
+		Nonce:     nonce,
+		GasTipCap: fee.GasTipCap,
+		GasFeeCap: fee.GasFeeCap,
+		Gas:       gasLimit,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
+	signedTx, rawTx, err := s.sign(ctx, tx)
+	if err != nil {
+		s.releaseNonce(from, nonce)
+		return nil, err
+	}
+
+	return &TransferResult{
+		TxHash:               signedTx.Hash().Hex(),
+		RawTx:                rawTx,
+		Data:                 data,
+		Nonce:                nonce,
+		GasLimit:             gasLimit,
+		MaxFeePerGas:         fee.GasFeeCap,
+		MaxPriorityFeePerGas: fee.GasTipCap,
+	}, nil
+}
+
+// overrideFee applies the fee overrides of opts. The tip never exceeds the
+// fee cap, nodes reject such transactions.
+func overrideFee(suggested *DynamicFee, opts TransferOptions) *DynamicFee {
+	fee := &DynamicFee{GasTipCap: suggested.GasTipCap, GasFeeCap: suggested.GasFeeCap}
+	if opts.MaxFeePerGas != nil {
+		fee.GasFeeCap = opts.MaxFeePerGas
+	}
+	if opts.MaxPriorityFeePerGas != nil {
+		fee.GasTipCap = opts.MaxPriorityFeePerGas
+	}
+	if fee.GasTipCap.Cmp(fee.GasFeeCap) > 0 {
+		fee.GasTipCap = fee.GasFeeCap
+	}
+	return fee
+}
+
+// Broadcast sends a persisted signed transaction. It can be called again
+// for the same transaction, a node that already has it is not an error.
+// Once broadcast is attempted the nonce is marked sent, the persisted
+// transaction holds it until it is mined or dropped.
+func (s *BusinessService) Broadcast(ctx context.Context, result *TransferResult) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	err := broadcastError(s.ethClient.SendRawTransaction(result.RawTx))
+	if errors.Is(err, NonceTooLowError) {
+		return err
+	}
+	s.markNonceSent(s.signer.Address(), result.Nonce, common.HexToHash(result.TxHash))
+	if err != nil {
+		return err
+	}
+	s.log.Info("交易已广播", zap.String("txHash", result.TxHash), zap.Uint64("nonce", result.Nonce))
+	return nil
+}
+
+// Discard releases the nonce of a signed transaction that was neither
+// persisted nor broadcast.
+func (s *BusinessService) Discard(result *TransferResult) {
+	s.releaseNonce(s.signer.Address(), result.Nonce)
+}
+
+// broadcastError classifies the node's answer to eth_sendRawTransaction.
+// A transaction the node already knows counts as broadcast.
+func broadcastError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "already known"),
+		strings.Contains(message, "known transaction"),
+		strings.Contains(message, "already imported"):
+		return nil
+	case strings.Contains(message, "nonce too low"):
+		return fmt.Errorf("%w: %w", NonceTooLowError, err)
+	}
+	return fmt.Errorf("发送原始交易失败: %w", err)
+}
@@ -0,0 +1,101 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBroadcastError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantErr     bool
+		nonceTooLow bool
+	}{
+		{name: "sent", err: nil},
+		{name: "already known", err: errors.New("already known")},
+		{name: "known transaction", err: errors.New("known transaction: 0xabc")},
+		{name: "anvil already imported", err: errors.New("transaction already imported")},
+		{name: "nonce too low", err: errors.New("nonce too low: next nonce 8, tx nonce 7"), wantErr: true, nonceTooLow: true},
+		{name: "connection", err: errors.New("connection reset"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := broadcastError(tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if errors.Is(err, NonceTooLowError) != tt.nonceTooLow {
+				t.Fatalf("Expected NonceTooLowError %v, got %v", tt.nonceTooLow, err)
+			}
+		})
+	}
+}
+
+func TestBusinessService_PrepareThenBroadcast(t *testing.T) {
+	logger := newTestLogger(t)
+	store := &memoryNonceStore{status: map[uint64]string{}}
+	ethClient := &sendingEthClient{pending: 7}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	signer := &PrivateKeySigner{key: privateKey, address: crypto.PubkeyToAddress(privateKey.PublicKey)}
+	service := NewEthBusinessService(ethClient, nil, signer, NewNonceManager(ethClient, store, logger), NewFeeConfig(0, 0, 0, 0), logger)
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+
+	result, err := service.prepareTransfer(context.Background(), to, big.NewInt(1), nil, TransferOptions{})
+	if err != nil {
+		t.Fatalf("prepareTransfer failed: %v", err)
+	}
+	if ethClient.sent != 0 {
+		t.Fatalf("Expected nothing broadcast before persisting, got %d sends", ethClient.sent)
+	}
+	if result.RawTx == "" || result.Nonce != 7 || store.status[7] != "reserved" {
+		t.Fatalf("Expected signed tx holding reserved nonce 7, got nonce %d status %q", result.Nonce, store.status[7])
+	}
+
+	// a node that dropped the nonce to another transaction leaves it alone
+	ethClient.sendErr = errors.New("nonce too low")
+	if err := service.Broadcast(context.Background(), result); !errors.Is(err, NonceTooLowError) {
+		t.Fatalf("Expected NonceTooLowError, got %v", err)
+	}
+	if store.status[7] != "reserved" {
+		t.Fatalf("Expected nonce 7 to stay reserved, got %q", store.status[7])
+	}
+
+	// rebroadcasting a transaction the node already has succeeds
+	ethClient.sendErr = errors.New("already known")
+	if err := service.Broadcast(context.Background(), result); err != nil {
+		t.Fatalf("Broadcast failed: %v", err)
+	}
+	if store.status[7] != "sent" {
+		t.Fatalf("Expected nonce 7 to be sent, got %q", store.status[7])
+	}
+}
+
+func TestOverrideFee(t *testing.T) {
+	suggested := &DynamicFee{GasTipCap: gwei(2), GasFeeCap: gwei(30)}
+
+	fee := overrideFee(suggested, TransferOptions{})
+	if fee.GasFeeCap.Cmp(gwei(30)) != 0 || fee.GasTipCap.Cmp(gwei(2)) != 0 {
+		t.Fatalf("Expected the suggested fees, got tip %s cap %s", fee.GasTipCap, fee.GasFeeCap)
+	}
+
+	fee = overrideFee(suggested, TransferOptions{MaxFeePerGas: gwei(50), MaxPriorityFeePerGas: gwei(5)})
+	if fee.GasFeeCap.Cmp(gwei(50)) != 0 || fee.GasTipCap.Cmp(gwei(5)) != 0 {
+		t.Fatalf("Expected the overrides, got tip %s cap %s", fee.GasTipCap, fee.GasFeeCap)
+	}
+
+	// a lowered cap pulls the suggested tip down with it
+	fee = overrideFee(suggested, TransferOptions{MaxFeePerGas: gwei(1)})
+	if fee.GasFeeCap.Cmp(gwei(1)) != 0 || fee.GasTipCap.Cmp(gwei(1)) != 0 {
+		t.Fatalf("Expected tip capped at 1 gwei, got tip %s cap %s", fee.GasTipCap, fee.GasFeeCap)
+	}
+}
@@ -0,0 +1,82 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"go-project/abigo"
+)
+
+// DisperseAllowanceError means the signer has not approved the disperse
+// contract for enough tokens. The payouts can still be sent one by one.
+var DisperseAllowanceError = errors.New("DisperseAllowanceError")
+
+// DispersePayment is one recipient of a disperse call.
+type DispersePayment struct {
+	To     common.Address
+	Amount *big.Int
+}
+
+// PrepareDisperseERC20 signs, without broadcasting, a single call of the
+// disperse contract paying every payment in the token at contractAddress.
+// The contract moves the tokens with transferFrom, so the signer has to
+// approve it first (see test-erc20-project/script/DisperseDeployer.s.sol).
+func (s *BusinessService) PrepareDisperseERC20(
+	ctx context.Context,
+	disperseAddress common.Address,
+	contractAddress string,
+	payments []DispersePayment,
+	opts TransferOptions,
+) (*TransferResult, error) {
+	total := DisperseTotal(payments)
+	if err := s.requireERC20Balance(ctx, contractAddress, total); err != nil {
+		return nil, err
+	}
+
+	token := common.HexToAddress(contractAddress)
+	erc20Client, err := s.tokens.Client(token)
+	if err != nil {
+		return nil, err
+	}
+	allowance, err := erc20Client.Allowance(ctx, s.signer.Address(), disperseAddress)
+	if err != nil {
+		return nil, fmt.Errorf("获取授权额度失败: %w", err)
+	}
+	if allowance.Cmp(total) < 0 {
+		return nil, fmt.Errorf("%w: allowance %s, batch total %s", DisperseAllowanceError, allowance, total)
+	}
+
+	data, err := disperseTokenData(token, payments)
+	if err != nil {
+		return nil, err
+	}
+	return s.prepareTransfer(ctx, disperseAddress, big.NewInt(0), data, opts)
+}
+
+// DisperseTotal is the amount of tokens a disperse call moves.
+func DisperseTotal(payments []DispersePayment) *big.Int {
+	total := new(big.Int)
+	for _, payment := range payments {
+		total.Add(total, payment.Amount)
+	}
+	return total
+}
+
+// disperseTokenData encodes disperseToken(token, recipients, values).
+func disperseTokenData(token common.Address, payments []DispersePayment) ([]byte, error) {
+	parsed, err := abigo.DisperseMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	recipients := make([]common.Address, len(payments))
+	values := make([]*big.Int, len(payments))
+	for i, payment := range payments {
+		recipients[i] = payment.To
+		values[i] = payment.Amount
+	}
+	return parsed.Pack("disperseToken", token, recipients, values)
+}
@@ -0,0 +1,50 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"go-project/abigo"
+)
+
+func TestDisperseTokenData(t *testing.T) {
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	payments := []DispersePayment{
+		{To: common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f"), Amount: big.NewInt(100)},
+		{To: common.HexToAddress("0xa0Ee7A142d267C1f36714E4a8F75612F20a79720"), Amount: big.NewInt(250)},
+	}
+
+	data, err := disperseTokenData(token, payments)
+	if err != nil {
+		t.Fatalf("disperseTokenData failed: %v", err)
+	}
+
+	parsed, err := abigo.DisperseMetaData.GetAbi()
+	if err != nil {
+		t.Fatalf("Failed to parse disperse abi: %v", err)
+	}
+	method, err := parsed.MethodById(data[:4])
+	if err != nil || method.Name != "disperseToken" {
+		t.Fatalf("Expected disperseToken selector, got %v %v", method, err)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("Failed to unpack arguments: %v", err)
+	}
+	recipients := args[1].([]common.Address)
+	values := args[2].([]*big.Int)
+	if args[0].(common.Address) != token || len(recipients) != 2 || len(values) != 2 {
+		t.Fatalf("Unexpected arguments %v", args)
+	}
+	for i, payment := range payments {
+		if recipients[i] != payment.To || values[i].Cmp(payment.Amount) != 0 {
+			t.Fatalf("Payment %d: expected %s to %s, got %s to %s", i, payment.Amount, payment.To.Hex(), values[i], recipients[i].Hex())
+		}
+	}
+
+	if total := DisperseTotal(payments); total.Cmp(big.NewInt(350)) != 0 {
+		t.Fatalf("Expected total 350, got %s", total)
+	}
+}
@@ -0,0 +1,102 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	defaultBaseFeeMultiplier     = 2
+	defaultGasLimitMarginPercent = 20
+)
+
+// FeeConfig controls how EIP-1559 fees and gas limits are derived. A nil
+// cap means the suggested value is used as is.
+type FeeConfig struct {
+	MaxFeePerGas          *big.Int
+	MaxPriorityFeePerGas  *big.Int
+	BaseFeeMultiplier     int64
+	GasLimitMarginPercent uint64
+}
+
+// NewFeeConfig builds a FeeConfig from gwei caps as they appear in
+// config.yml. Zero values fall back to the defaults.
+func NewFeeConfig(maxFeeGwei, maxPriorityFeeGwei uint64, baseFeeMultiplier int64, gasLimitMarginPercent uint64) FeeConfig {
+	feeConfig := FeeConfig{
+		BaseFeeMultiplier:     baseFeeMultiplier,
+		GasLimitMarginPercent: gasLimitMarginPercent,
+	}
+	if maxFeeGwei > 0 {
+		feeConfig.MaxFeePerGas = new(big.Int).Mul(new(big.Int).SetUint64(maxFeeGwei), big.NewInt(params.GWei))
+	}
+	if maxPriorityFeeGwei > 0 {
+		feeConfig.MaxPriorityFeePerGas = new(big.Int).Mul(new(big.Int).SetUint64(maxPriorityFeeGwei), big.NewInt(params.GWei))
+	}
+	if feeConfig.BaseFeeMultiplier <= 0 {
+		feeConfig.BaseFeeMultiplier = defaultBaseFeeMultiplier
+	}
+	if feeConfig.GasLimitMarginPercent == 0 {
+		feeConfig.GasLimitMarginPercent = defaultGasLimitMarginPercent
+	}
+	return feeConfig
+}
+
+// DynamicFee holds the fee fields of a type-2 transaction.
+type DynamicFee struct {
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// suggestDynamicFee returns maxFeePerGas = baseFee*multiplier + tip, with the
+// tip and the total clamped to the configured caps.
+func suggestDynamicFee(ethClient EthClient, feeConfig FeeConfig) (*DynamicFee, error) {
+	header, err := ethClient.BlockHeaderByNumber(nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新区块头失败: %w", err)
+	}
+	if header.BaseFee == nil {
+		return nil, fmt.Errorf("节点不支持EIP-1559: 区块 %s 没有baseFee", header.Number)
+	}
+
+	tip, err := ethClient.SuggestGasTipCap()
+	if err != nil {
+		return nil, fmt.Errorf("获取gas tip失败: %w", err)
+	}
+	if feeConfig.MaxPriorityFeePerGas != nil && tip.Cmp(feeConfig.MaxPriorityFeePerGas) > 0 {
+		tip = new(big.Int).Set(feeConfig.MaxPriorityFeePerGas)
+	}
+
+	feeCap := new(big.Int).Mul(header.BaseFee, big.NewInt(feeConfig.BaseFeeMultiplier))
+	feeCap.Add(feeCap, tip)
+	if feeConfig.MaxFeePerGas != nil && feeCap.Cmp(feeConfig.MaxFeePerGas) > 0 {
+		if feeConfig.MaxFeePerGas.Cmp(header.BaseFee) < 0 {
+			return nil, fmt.Errorf("baseFee %s 超过最大gas费用上限 %s", header.BaseFee, feeConfig.MaxFeePerGas)
+		}
+		feeCap = new(big.Int).Set(feeConfig.MaxFeePerGas)
+	}
+	if tip.Cmp(feeCap) > 0 {
+		tip = new(big.Int).Set(feeCap)
+	}
+
+	return &DynamicFee{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+// estimateGasLimit asks the node for the gas of the call and adds the
+// configured safety margin.
+func estimateGasLimit(ctx context.Context, ethClient EthClient, feeConfig FeeConfig, from common.Address, to *common.Address, value *big.Int, data []byte) (uint64, error) {
+	gas, err := ethClient.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    to,
+		Value: value,
+		Data:  data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("估算gas失败: %w", err)
+	}
+	return gas + gas*feeConfig.GasLimitMarginPercent/100, nil
+}
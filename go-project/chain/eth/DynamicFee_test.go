@@ -0,0 +1,81 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+type feeEthClient struct {
+	EthClient
+	baseFee *big.Int
+	tip     *big.Int
+	gas     uint64
+}
+
+func (c *feeEthClient) BlockHeaderByNumber(*big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(1), BaseFee: c.baseFee}, nil
+}
+
+func (c *feeEthClient) SuggestGasTipCap() (*big.Int, error) {
+	return c.tip, nil
+}
+
+func (c *feeEthClient) EstimateGas(context.Context, ethereum.CallMsg) (uint64, error) {
+	return c.gas, nil
+}
+
+func gwei(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.GWei))
+}
+
+func TestSuggestDynamicFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseFee   *big.Int
+		tip       *big.Int
+		feeConfig FeeConfig
+		wantTip   *big.Int
+		wantCap   *big.Int
+	}{
+		{"uncapped", gwei(10), gwei(2), NewFeeConfig(0, 0, 0, 0), gwei(2), gwei(22)},
+		{"tip capped", gwei(10), gwei(8), NewFeeConfig(0, 3, 0, 0), gwei(3), gwei(23)},
+		{"fee capped", gwei(10), gwei(2), NewFeeConfig(15, 0, 0, 0), gwei(2), gwei(15)},
+		{"tip above fee cap", gwei(10), gwei(8), NewFeeConfig(12, 0, 0, 0), gwei(8), gwei(12)},
+		{"tip clamped to fee cap", gwei(1), gwei(8), NewFeeConfig(5, 0, 1, 0), gwei(5), gwei(5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fee, err := suggestDynamicFee(&feeEthClient{baseFee: tt.baseFee, tip: tt.tip}, tt.feeConfig)
+			if err != nil {
+				t.Fatalf("suggestDynamicFee failed: %v", err)
+			}
+			if fee.GasTipCap.Cmp(tt.wantTip) != 0 || fee.GasFeeCap.Cmp(tt.wantCap) != 0 {
+				t.Fatalf("Expected tip %s cap %s, got tip %s cap %s", tt.wantTip, tt.wantCap, fee.GasTipCap, fee.GasFeeCap)
+			}
+		})
+	}
+}
+
+func TestSuggestDynamicFee_BaseFeeAboveCap(t *testing.T) {
+	_, err := suggestDynamicFee(&feeEthClient{baseFee: gwei(20), tip: gwei(1)}, NewFeeConfig(15, 0, 0, 0))
+	if err == nil {
+		t.Fatal("Expected error when base fee exceeds the fee cap")
+	}
+}
+
+func TestEstimateGasLimit_AddsMargin(t *testing.T) {
+	to := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	gas, err := estimateGasLimit(context.Background(), &feeEthClient{gas: 50000}, NewFeeConfig(0, 0, 0, 25), common.Address{}, &to, big.NewInt(0), nil)
+	if err != nil {
+		t.Fatalf("estimateGasLimit failed: %v", err)
+	}
+	if gas != 62500 {
+		t.Fatalf("Expected gas 62500, got %d", gas)
+	}
+}
@@ -0,0 +1,95 @@
+package eth
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-project/abigo"
+)
+
+const (
+	Erc20EventTransfer = "transfer"
+	Erc20EventApproval = "approval"
+)
+
+// Erc20Event is a decoded Transfer or Approval log. For approvals From is
+// the owner and To is the spender.
+type Erc20Event struct {
+	EventType   string
+	Token       common.Address
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+	LogIndex    uint
+	BlockNumber uint64
+	BlockHash   common.Hash
+	TxHash      common.Hash
+}
+
+type Erc20EventDecoder struct {
+	filterer   *abigo.Testerc20Filterer
+	transferID common.Hash
+	approvalID common.Hash
+}
+
+func NewErc20EventDecoder() (*Erc20EventDecoder, error) {
+	parsed, err := abigo.Testerc20MetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	// the filterer is only used for its ABI, so it needs neither address nor backend
+	filterer, err := abigo.NewTesterc20Filterer(common.Address{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Erc20EventDecoder{
+		filterer:   filterer,
+		transferID: parsed.Events["Transfer"].ID,
+		approvalID: parsed.Events["Approval"].ID,
+	}, nil
+}
+
+// Decode returns nil, nil for logs that are not ERC-20 Transfer/Approval
+// events, including ERC-721 transfers which carry an extra indexed topic.
+func (d *Erc20EventDecoder) Decode(log types.Log) (*Erc20Event, error) {
+	if len(log.Topics) != 3 {
+		return nil, nil
+	}
+
+	event := &Erc20Event{
+		Token:       log.Address,
+		LogIndex:    log.Index,
+		BlockNumber: log.BlockNumber,
+		BlockHash:   log.BlockHash,
+		TxHash:      log.TxHash,
+	}
+
+	switch log.Topics[0] {
+	case d.transferID:
+		transfer, err := d.filterer.ParseTransfer(log)
+		if err != nil {
+			return nil, fmt.Errorf("parse Transfer log %s#%d: %w", log.TxHash.Hex(), log.Index, err)
+		}
+		event.EventType = Erc20EventTransfer
+		event.From = transfer.From
+		event.To = transfer.To
+		event.Value = transfer.Value
+	case d.approvalID:
+		approval, err := d.filterer.ParseApproval(log)
+		if err != nil {
+			return nil, fmt.Errorf("parse Approval log %s#%d: %w", log.TxHash.Hex(), log.Index, err)
+		}
+		event.EventType = Erc20EventApproval
+		event.From = approval.Owner
+		event.To = approval.Spender
+		event.Value = approval.Value
+	default:
+		return nil, nil
+	}
+
+	return event, nil
+}
@@ -0,0 +1,64 @@
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestErc20EventDecoder_Decode(t *testing.T) {
+	decoder, err := NewErc20EventDecoder()
+	if err != nil {
+		t.Fatalf("Failed to create decoder: %v", err)
+	}
+
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	from := common.HexToAddress("0xa0Ee7A142d267C1f36714E4a8F75612F20a79720")
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+	value := big.NewInt(123456)
+
+	transferLog := types.Log{
+		Address: token,
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data:  common.LeftPadBytes(value.Bytes(), 32),
+		Index: 3,
+	}
+	event, err := decoder.Decode(transferLog)
+	if err != nil {
+		t.Fatalf("Failed to decode Transfer: %v", err)
+	}
+	if event == nil || event.EventType != Erc20EventTransfer {
+		t.Fatalf("Expected transfer event, got %+v", event)
+	}
+	if event.Token != token || event.From != from || event.To != to || event.Value.Cmp(value) != 0 || event.LogIndex != 3 {
+		t.Fatalf("Unexpected transfer event: %+v", event)
+	}
+
+	approvalLog := transferLog
+	approvalLog.Topics = []common.Hash{
+		crypto.Keccak256Hash([]byte("Approval(address,address,uint256)")),
+		common.BytesToHash(from.Bytes()),
+		common.BytesToHash(to.Bytes()),
+	}
+	event, err = decoder.Decode(approvalLog)
+	if err != nil {
+		t.Fatalf("Failed to decode Approval: %v", err)
+	}
+	if event == nil || event.EventType != Erc20EventApproval || event.From != from || event.To != to {
+		t.Fatalf("Unexpected approval event: %+v", event)
+	}
+
+	nftLog := transferLog
+	nftLog.Topics = append(append([]common.Hash{}, transferLog.Topics...), common.BigToHash(big.NewInt(1)))
+	event, err = decoder.Decode(nftLog)
+	if err != nil || event != nil {
+		t.Fatalf("Expected ERC-721 transfer to be skipped, got %+v, %v", event, err)
+	}
+}
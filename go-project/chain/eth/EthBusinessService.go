@@ -2,7 +2,6 @@ package eth
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"math/big"
@@ -12,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
 	"go.uber.org/zap"
 
 	globalconst "go-project/common"
@@ -19,124 +19,303 @@ import (
 )
 
 type BusinessService struct {
-	ethClient   EthClient
-	erc20Client TestErc20Client
-	log         *log.ZapLogger
+	ethClient    EthClient
+	tokens       *TokenRegistry
+	signer       Signer
+	nonceManager *NonceManager
+	feeConfig    FeeConfig
+	log          *log.ZapLogger
 }
 
-func NewEthBusinessService(ethClient EthClient, erc20Client TestErc20Client, log *log.ZapLogger) *BusinessService {
+// NewEthBusinessService builds the payout service sending from signer's
+// account. nonceManager may be nil, in which case nonces are read from the
+// node on every send.
+func NewEthBusinessService(ethClient EthClient, tokens *TokenRegistry, signer Signer, nonceManager *NonceManager, feeConfig FeeConfig, log *log.ZapLogger) *BusinessService {
 	return &BusinessService{
-		ethClient:   ethClient,
-		erc20Client: erc20Client,
-		log:         log,
+		ethClient:    ethClient,
+		tokens:       tokens,
+		signer:       signer,
+		nonceManager: nonceManager,
+		feeConfig:    feeConfig,
+		log:          log,
 	}
 }
 
+// TransferResult describes the transaction sent by TransferERC20 or
+// TransferNative, or signed by PrepareERC20Transfer or
+// PrepareNativeTransfer. RawTx is only set for signed transactions.
+type TransferResult struct {
+	TxHash               string
+	RawTx                string
+	Data                 []byte
+	Nonce                uint64
+	GasLimit             uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
 var InsufficientBalanceError = errors.New("InsufficientBalanceError")
 
+// TxNotConfirmedError means the transaction was broadcast but not mined
+// while we waited. It must not be re-sent, the scanner settles it later.
+var TxNotConfirmedError = errors.New("TxNotConfirmedError")
+
+// SignerAddress is the account payouts are sent from.
+func (s *BusinessService) SignerAddress() common.Address {
+	return s.signer.Address()
+}
+
 func (s *BusinessService) TransferERC20(
 	ctx context.Context,
-	prvKey *ecdsa.PrivateKey,
-	fromAddress string,
 	toAddress string,
 	contractAddress string,
 	amount *big.Int,
-) (string, []byte, error) {
-	balance, err := s.checkBalance(ctx, fromAddress, contractAddress, amount)
+) (*TransferResult, error) {
+	if err := s.requireERC20Balance(ctx, contractAddress, amount); err != nil {
+		return nil, err
+	}
+	data := erc20TransferData(common.HexToAddress(toAddress), amount)
+	return s.transferWithRetry(ctx, "TransferERC20", common.HexToAddress(contractAddress), big.NewInt(0), data)
+}
+
+// TransferNative sends amount wei of the native asset to toAddress. The
+// signer must also hold enough to pay for the gas.
+func (s *BusinessService) TransferNative(
+	ctx context.Context,
+	toAddress string,
+	amount *big.Int,
+) (*TransferResult, error) {
+	if err := s.requireNativeBalance(ctx, amount); err != nil {
+		return nil, err
+	}
+	return s.transferWithRetry(ctx, "TransferNative", common.HexToAddress(toAddress), amount, nil)
+}
+
+func (s *BusinessService) requireERC20Balance(ctx context.Context, contractAddress string, amount *big.Int) error {
+	balance, err := s.checkBalance(ctx, s.signer.Address().Hex(), contractAddress, amount)
+	if err != nil {
+		return fmt.Errorf("检查余额失败: %w", err)
+	}
+	if balance.Cmp(amount) < 0 {
+		return InsufficientBalanceError
+	}
+	return nil
+}
+
+func (s *BusinessService) requireNativeBalance(ctx context.Context, amount *big.Int) error {
+	from := s.signer.Address()
+	balance, err := s.ethClient.BalanceAt(ctx, from, nil)
 	if err != nil {
-		return "", nil, fmt.Errorf("检查余额失败: %w", err)
+		return fmt.Errorf("检查余额失败: %w", err)
 	}
+	s.log.Info("当前原生币余额", zap.String("address", from.Hex()), zap.String("balance", balance.String()))
 	if balance.Cmp(amount) < 0 {
-		return "", nil, InsufficientBalanceError
+		return InsufficientBalanceError
 	}
+	return nil
+}
+
+// erc20TransferData encodes transfer(to, amount).
+func erc20TransferData(to common.Address, amount *big.Int) []byte {
+	transferFnSignature := []byte("transfer(address,uint256)")
+	hash := crypto.Keccak256(transferFnSignature)
+	methodID := hash[:4]
+	paddedAddress := common.LeftPadBytes(to.Bytes(), 32)
+	paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
+
+	var data []byte
+	data = append(data, methodID...)
+	data = append(data, paddedAddress...)
+	data = append(data, paddedAmount...)
+	return data
+}
 
+func (s *BusinessService) transferWithRetry(ctx context.Context, name string, to common.Address, value *big.Int, data []byte) (*TransferResult, error) {
 	maxRetries := 3
 	var lastErr error
-	var txHash string
-	var data []byte
+	var lastResult *TransferResult
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		hash, transferData, err := s.attemptTransferERC20(ctx, prvKey, fromAddress, toAddress, contractAddress, amount)
+		result, err := s.attemptTransfer(ctx, name, to, value, data)
 		if err == nil {
-			return hash, transferData, nil // 交易成功，返回交易哈希和data数组
+			return result, nil // 交易成功，返回交易信息
+		}
+
+		if errors.Is(err, TxNotConfirmedError) {
+			return result, err
 		}
 
 		lastErr = err
-		s.log.Error("TransferERC20 尝试失败，准备重试", zap.Int("尝试次数", attempt+1), zap.Error(err))
+		s.log.Error(name+" 尝试失败，准备重试", zap.Int("尝试次数", attempt+1), zap.Error(err))
 
 		if attempt < maxRetries-1 {
 			time.Sleep(3 * time.Second) // 在重试之前等待一段时间
 		}
-		txHash = hash       // 保存最后一次尝试的交易哈希
-		data = transferData // 保存最后一次尝试的data数组
+		lastResult = result // 保存最后一次尝试的交易信息
 	}
 
-	return txHash, data, fmt.Errorf("TransferERC20 在 %d 次尝试后失败: %w", maxRetries, lastErr)
+	return lastResult, fmt.Errorf("%s 在 %d 次尝试后失败: %w", name, maxRetries, lastErr)
 }
 
-func (s *BusinessService) attemptTransferERC20(
-	ctx context.Context,
-	prvKey *ecdsa.PrivateKey,
-	fromAddress string,
-	toAddress string,
-	contractAddress string,
-	amount *big.Int,
-) (string, []byte, error) {
-	from := common.HexToAddress(fromAddress)
-	to := common.HexToAddress(toAddress)
+func (s *BusinessService) attemptTransfer(ctx context.Context, name string, to common.Address, value *big.Int, data []byte) (*TransferResult, error) {
+	from := s.signer.Address()
+	result := &TransferResult{Data: data}
 
-	nonce, err := s.ethClient.TxCountByAddress(from)
+	fee, err := suggestDynamicFee(s.ethClient, s.feeConfig)
+	if err != nil {
+		return result, err
+	}
+	gasLimit, err := estimateGasLimit(ctx, s.ethClient, s.feeConfig, from, &to, value, data)
 	if err != nil {
-		return "", nil, fmt.Errorf("获取nonce失败: %w", err)
+		return result, err
 	}
+	result.GasLimit = gasLimit
+	result.MaxFeePerGas = fee.GasFeeCap
+	result.MaxPriorityFeePerGas = fee.GasTipCap
 
-	gasPrice, err := s.ethClient.SuggestGasPrice()
+	nonce, err := s.reserveNonce(from)
 	if err != nil {
-		return "", nil, fmt.Errorf("获取gas价格失败: %w", err)
+		return result, fmt.Errorf("获取nonce失败: %w", err)
 	}
+	result.Nonce = nonce
 
-	// 增加 gas 价格以提高交易成功率
-	adjustedGasPrice := new(big.Int).Mul(gasPrice, big.NewInt(120))
-	adjustedGasPrice = adjustedGasPrice.Div(adjustedGasPrice, big.NewInt(100))
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(globalconst.ChainId),
+		Nonce:     nonce,
+		GasTipCap: fee.GasTipCap,
+		GasFeeCap: fee.GasFeeCap,
+		Gas:       gasLimit,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
 
-	transferFnSignature := []byte("transfer(address,uint256)")
-	hash := crypto.Keccak256(transferFnSignature)
-	methodID := hash[:4]
-	paddedAddress := common.LeftPadBytes(to.Bytes(), 32)
-	paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
+	signedTx, err := s.signAndSend(ctx, from, tx)
+	if err != nil {
+		return result, err
+	}
+	result.TxHash = signedTx.Hash().Hex()
 
-	var data []byte
-	data = append(data, methodID...)
-	data = append(data, paddedAddress...)
-	data = append(data, paddedAmount...)
+	err = WaitForTransaction(ctx, s.ethClient, signedTx.Hash())
+	if err != nil {
+		return result, fmt.Errorf("等待交易确认失败: %w: %w", TxNotConfirmedError, err)
+	}
 
-	erc20Address := common.HexToAddress(contractAddress)
-	tx := types.NewTransaction(uint64(nonce), erc20Address, big.NewInt(0), 300000, adjustedGasPrice, data)
+	s.log.Info(name+" 交易成功", zap.String("txHash", result.TxHash),
+		zap.Uint64("gasLimit", gasLimit), zap.String("maxFeePerGas", fee.GasFeeCap.String()), zap.String("maxPriorityFeePerGas", fee.GasTipCap.String()))
+	return result, nil
+}
 
-	chainID := big.NewInt(globalconst.ChainId)
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), prvKey)
+// signAndSend signs and broadcasts tx. The nonce of tx must have been
+// reserved; it is marked sent on success and released on failure.
+func (s *BusinessService) signAndSend(ctx context.Context, from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	signedTx, _, err := s.signAndBroadcast(ctx, tx)
 	if err != nil {
-		return "", data, fmt.Errorf("签名交易失败: %w", err)
+		s.releaseNonce(from, tx.Nonce())
+		return nil, err
 	}
+	s.markNonceSent(from, signedTx.Nonce(), signedTx.Hash())
+	return signedTx, nil
+}
 
-	rawTxBytes, err := signedTx.MarshalBinary()
+func (s *BusinessService) signAndBroadcast(ctx context.Context, tx *types.Transaction) (*types.Transaction, string, error) {
+	signedTx, rawTxHex, err := s.sign(ctx, tx)
 	if err != nil {
-		return "", data, fmt.Errorf("序列化交易失败: %w", err)
+		return nil, "", err
 	}
-	rawTxHex := hexutil.Encode(rawTxBytes)
 
 	err = s.ethClient.SendRawTransaction(rawTxHex)
 	if err != nil {
-		return signedTx.Hash().Hex(), data, fmt.Errorf("发送原始交易失败: %w", err)
+		return nil, "", fmt.Errorf("发送原始交易失败: %w", err)
 	}
+	return signedTx, rawTxHex, nil
+}
 
-	err = WaitForTransaction(ctx, s.ethClient, signedTx.Hash())
+// sign signs tx and returns it hex encoded, ready for eth_sendRawTransaction.
+func (s *BusinessService) sign(ctx context.Context, tx *types.Transaction) (*types.Transaction, string, error) {
+	chainID := big.NewInt(globalconst.ChainId)
+	signedTx, err := s.signer.SignTx(ctx, tx, chainID)
+	if err != nil {
+		return nil, "", fmt.Errorf("签名交易失败: %w", err)
+	}
+
+	rawTxBytes, err := signedTx.MarshalBinary()
 	if err != nil {
-		return signedTx.Hash().Hex(), data, fmt.Errorf("等待交易确认失败: %w", err)
+		return nil, "", fmt.Errorf("序列化交易失败: %w", err)
 	}
+	return signedTx, hexutil.Encode(rawTxBytes), nil
+}
 
-	s.log.Info("TransferERC20 交易成功", zap.String("txHash", signedTx.Hash().Hex()))
-	return signedTx.Hash().Hex(), data, nil
+func (s *BusinessService) markNonceSent(from common.Address, nonce uint64, txHash common.Hash) {
+	if s.nonceManager != nil {
+		if err := s.nonceManager.MarkSent(from, nonce, txHash); err != nil {
+			s.log.Error("标记nonce已发送失败", zap.Uint64("nonce", nonce), zap.Error(err))
+		}
+	}
+}
+
+func (s *BusinessService) reserveNonce(from common.Address) (uint64, error) {
+	if s.nonceManager == nil {
+		nonce, err := s.ethClient.TxCountByAddress(from)
+		return uint64(nonce), err
+	}
+	return s.nonceManager.Reserve(from)
+}
+
+func (s *BusinessService) releaseNonce(from common.Address, nonce uint64) {
+	if s.nonceManager != nil {
+		s.nonceManager.Release(from, nonce)
+	}
+}
+
+// FillNonceGaps sends a zero-value self-transfer for every released or
+// abandoned nonce of the signer that blocks later pending transactions.
+func (s *BusinessService) FillNonceGaps(ctx context.Context) error {
+	if s.nonceManager == nil {
+		return nil
+	}
+	from := s.signer.Address()
+
+	gaps, err := s.nonceManager.StuckNonces(from)
+	if err != nil {
+		return fmt.Errorf("检测nonce空洞失败: %w", err)
+	}
+
+	for _, nonce := range gaps {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		claimed, err := s.nonceManager.Claim(from, nonce)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		fee, err := suggestDynamicFee(s.ethClient, s.feeConfig)
+		if err != nil {
+			s.releaseNonce(from, nonce)
+			return err
+		}
+
+		tx := types.NewTx(&types.DynamicFeeTx{
+			ChainID:   big.NewInt(globalconst.ChainId),
+			Nonce:     nonce,
+			GasTipCap: fee.GasTipCap,
+			GasFeeCap: fee.GasFeeCap,
+			Gas:       params.TxGas,
+			To:        &from,
+			Value:     big.NewInt(0),
+		})
+		signedTx, err := s.signAndSend(ctx, from, tx)
+		if err != nil {
+			return fmt.Errorf("填补nonce %d 失败: %w", nonce, err)
+		}
+		s.log.Info("填补nonce空洞", zap.String("address", from.Hex()), zap.Uint64("nonce", nonce), zap.String("txHash", signedTx.Hash().Hex()))
+	}
+
+	return nil
 }
 
 func WaitForTransaction(ctx context.Context, ethClient EthClient, txHash common.Hash) error {
@@ -169,14 +348,17 @@ func WaitForTransaction(ctx context.Context, ethClient EthClient, txHash common.
 
 func (s *BusinessService) checkBalance(ctx context.Context, fromAddress, contractAddress string, amount *big.Int) (*big.Int, error) {
 	from := common.HexToAddress(fromAddress)
-	//contract := common.HexToAddress(contractAddress)
 
-	balance, err := s.erc20Client.BalanceOf(ctx, from)
+	erc20Client, err := s.tokens.Client(common.HexToAddress(contractAddress))
+	if err != nil {
+		return nil, err
+	}
+	balance, err := erc20Client.BalanceOf(ctx, from)
 	if err != nil {
 		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
-	s.log.Info("当前余额", zap.String("address", fromAddress), zap.String("balance", balance.String()))
+	s.log.Info("当前余额", zap.String("address", fromAddress), zap.String("token", contractAddress), zap.String("balance", balance.String()))
 
 	return balance, nil
 }
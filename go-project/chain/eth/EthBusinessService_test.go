@@ -10,7 +10,6 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	globalconst "go-project/common"
 	"go-project/main/config"
 	"go-project/main/log"
@@ -24,7 +23,12 @@ func TestBusinessService_TransferERC20(t *testing.T) {
 	}
 	defer ethClient.Close()
 
-	erc20Client, err := NewTestErc20Client(ctx, "http://127.0.0.1:8545", globalconst.TEMP_TEST_ERC20_ADDRESS)
+	tokens, err := NewTokenRegistry(ctx, "http://127.0.0.1:8545")
+	if err != nil {
+		t.Fatalf("Failed to create TokenRegistry: %v", err)
+	}
+	defer tokens.Close()
+	erc20Client, err := tokens.Client(common.HexToAddress(globalconst.TEMP_TEST_ERC20_ADDRESS))
 	if err != nil {
 		t.Fatalf("Failed to create TestErc20Client: %v", err)
 	}
@@ -47,26 +51,25 @@ func TestBusinessService_TransferERC20(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create logger: %v", err)
 	}
-	businessService := NewEthBusinessService(ethClient, erc20Client, logger)
-
-	privateKey, err := crypto.HexToECDSA(globalconst.OWNER_PRV_KEY)
+	signer, err := NewPrivateKeySigner(testOwnerPrvKey)
 	if err != nil {
-		t.Fatalf("Failed to create private key: %v", err)
+		t.Fatalf("Failed to create signer: %v", err)
 	}
+	businessService := NewEthBusinessService(ethClient, tokens, signer, nil, NewFeeConfig(0, 0, 0, 0), logger)
 
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	fromAddress := signer.Address()
 	toAddress := common.HexToAddress(globalconst.TEMP_TO_ADDRESS)
 
 	printERC20Balance(t, ctx, erc20Client, fromAddress, "From (before)")
 	printERC20Balance(t, ctx, erc20Client, toAddress, "To (before)")
 
 	amount := big.NewInt(9 * 1e6)
-	txHash, transferData, err := businessService.TransferERC20(ctx, privateKey, fromAddress.Hex(), toAddress.Hex(), globalconst.TEMP_TEST_ERC20_ADDRESS, amount)
+	result, err := businessService.TransferERC20(ctx, toAddress.Hex(), globalconst.TEMP_TEST_ERC20_ADDRESS, amount)
 	if err != nil {
 		t.Fatalf("Failed to transfer ERC20: %v", err)
 	}
-	logger.Info("txHash", zap.String("txHash", txHash))
-	logger.Info("transferData", zap.String("transferData", hexutil.Encode(transferData)))
+	logger.Info("txHash", zap.String("txHash", result.TxHash))
+	logger.Info("transferData", zap.String("transferData", hexutil.Encode(result.Data)))
 
 	time.Sleep(5 * time.Second)
 
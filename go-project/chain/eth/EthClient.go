@@ -41,18 +41,23 @@ type EthClient interface {
 	BlockByNumberV2(ctx context.Context, number *big.Int) (*types.Block, error)
 	BlockByNumberV3(ctx context.Context, number *big.Int) (*types.Block, error)
 	BlockByNumberReturnJson(ctx context.Context, number *big.Int) (*types.Block, error)
-	// BlockHeaderByNumber(*big.Int) (*types.Header, error)
+	BlockHeaderByNumber(*big.Int) (*types.Header, error)
 	// LatestSafeBlockHeader() (*types.Header, error)
 	LatestFinalizedBlockHeader() (*types.Header, error)
 	BlockHeaderByBlockHash(common.Hash) (*types.Header, error)
 	BlockHeaderListByRange(*big.Int, *big.Int) ([]*types.Header, error)
+	BlockListByNumbers(ctx context.Context, numbers []*big.Int) ([]*types.Block, error)
+	BlockReceiptsByNumbers(ctx context.Context, numbers []*big.Int) ([][]*types.Receipt, error)
+	TxReceiptListByTxHashes(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error)
 
 	TxByTxHash(common.Hash) (*types.Transaction, error)
 
 	TxReceiptByTxHash(common.Hash) (*types.Receipt, error)
 	TxCountByAddress(common.Address) (hexutil.Uint64, error)
+	PendingTxCountByAddress(common.Address) (hexutil.Uint64, error)
 	SuggestGasPrice() (*big.Int, error)
 	SuggestGasTipCap() (*big.Int, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
 	SendRawTransaction(rawTx string) error
 
 	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
@@ -99,22 +104,22 @@ func DialEthClient(ctx context.Context, rpcUrl string) (EthClient, error) {
 	}, nil
 }
 
-//func (c *client) BlockHeaderByNumber(number *big.Int) (*types.Header, error) {
-//	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
-//	defer cancel()
-//
-//	var header *types.Header
-//	err := c.rpc.CallContext(ctxwt, &header, "eth_getBlockByNumber", toBlockNumArg(number), false)
-//	if err != nil {
-//		log.Error("Call eth_getBlockByNumber method fail", "err", err)
-//		return nil, err
-//	} else if header == nil {
-//		log.Warn("header not found")
-//		return nil, ethereum.NotFound
-//	}
-//
-//	return header, nil
-//}
+func (c *client) BlockHeaderByNumber(number *big.Int) (*types.Header, error) {
+	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+
+	var header *types.Header
+	err := c.rpc.CallContext(ctxwt, &header, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	if err != nil {
+		log.Error("Call eth_getBlockByNumber method fail", "err", err)
+		return nil, err
+	} else if header == nil {
+		log.Warn("header not found")
+		return nil, ethereum.NotFound
+	}
+
+	return header, nil
+}
 
 //
 //func (c *client) LatestSafeBlockHeader() (*types.Header, error) {
@@ -168,7 +173,7 @@ func (c *client) BlockHeaderByBlockHash(hash common.Hash) (*types.Header, error)
 }
 
 func (c *client) BlockHeaderListByRange(startHeight, endHeight *big.Int) ([]*types.Header, error) {
-	if startHeight.Cmp(endHeight) == 0 {
+	if startHeight.Cmp(endHeight) > 0 {
 		return []*types.Header{}, nil
 	}
 
@@ -207,6 +212,118 @@ func (c *client) BlockHeaderListByRange(startHeight, endHeight *big.Int) ([]*typ
 	return headers[:size], nil
 }
 
+type rpcBlock struct {
+	Hash         common.Hash          `json:"hash"`
+	Transactions []*types.Transaction `json:"transactions"`
+	Withdrawals  []*types.Withdrawal  `json:"withdrawals,omitempty"`
+}
+
+// BlockListByNumbers fetches full blocks (with transactions) for every number
+// in a single JSON-RPC batch. The result keeps the order of numbers.
+func (c *client) BlockListByNumbers(ctx context.Context, numbers []*big.Int) ([]*types.Block, error) {
+	raws := make([]json.RawMessage, len(numbers))
+	batchElems := make([]gethrpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		batchElems[i] = gethrpc.BatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{toBlockNumArg(number), true},
+			Result: &raws[i],
+		}
+	}
+
+	ctxwt, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+	if err := c.rpc.BatchCallContext(ctxwt, batchElems); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*types.Block, len(numbers))
+	for i, batchElem := range batchElems {
+		if batchElem.Error != nil {
+			return nil, fmt.Errorf("eth_getBlockByNumber %s: %w", numbers[i], batchElem.Error)
+		}
+		if len(raws[i]) == 0 || string(raws[i]) == "null" {
+			return nil, fmt.Errorf("eth_getBlockByNumber %s: %w", numbers[i], ethereum.NotFound)
+		}
+
+		var head *types.Header
+		if err := json.Unmarshal(raws[i], &head); err != nil {
+			return nil, fmt.Errorf("解析区块头失败 %s: %w", numbers[i], err)
+		}
+		var body rpcBlock
+		if err := json.Unmarshal(raws[i], &body); err != nil {
+			return nil, fmt.Errorf("解析区块交易失败 %s: %w", numbers[i], err)
+		}
+		if head.Hash() != body.Hash {
+			return nil, fmt.Errorf("block %s header mismatch", numbers[i])
+		}
+
+		blocks[i] = types.NewBlockWithHeader(head).WithBody(types.Body{
+			Transactions: body.Transactions,
+			Withdrawals:  body.Withdrawals,
+		})
+	}
+
+	return blocks, nil
+}
+
+// BlockReceiptsByNumbers fetches all receipts of every block via a batch of
+// eth_getBlockReceipts calls.
+func (c *client) BlockReceiptsByNumbers(ctx context.Context, numbers []*big.Int) ([][]*types.Receipt, error) {
+	receipts := make([][]*types.Receipt, len(numbers))
+	batchElems := make([]gethrpc.BatchElem, len(numbers))
+	for i, number := range numbers {
+		batchElems[i] = gethrpc.BatchElem{
+			Method: "eth_getBlockReceipts",
+			Args:   []interface{}{toBlockNumArg(number)},
+			Result: &receipts[i],
+		}
+	}
+
+	ctxwt, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+	if err := c.rpc.BatchCallContext(ctxwt, batchElems); err != nil {
+		return nil, err
+	}
+
+	for i, batchElem := range batchElems {
+		if batchElem.Error != nil {
+			return nil, fmt.Errorf("eth_getBlockReceipts %s: %w", numbers[i], batchElem.Error)
+		}
+	}
+
+	return receipts, nil
+}
+
+func (c *client) TxReceiptListByTxHashes(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, len(hashes))
+	batchElems := make([]gethrpc.BatchElem, len(hashes))
+	for i, hash := range hashes {
+		batchElems[i] = gethrpc.BatchElem{
+			Method: "eth_getTransactionReceipt",
+			Args:   []interface{}{hash},
+			Result: &receipts[i],
+		}
+	}
+
+	ctxwt, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+	if err := c.rpc.BatchCallContext(ctxwt, batchElems); err != nil {
+		return nil, err
+	}
+
+	for i, batchElem := range batchElems {
+		if batchElem.Error != nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", hashes[i].Hex(), batchElem.Error)
+		}
+		if receipts[i] == nil {
+			return nil, fmt.Errorf("eth_getTransactionReceipt %s: %w", hashes[i].Hex(), ethereum.NotFound)
+		}
+	}
+
+	return receipts, nil
+}
+
 func (c *client) TxByTxHash(hash common.Hash) (*types.Transaction, error) {
 	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
 	defer cancel()
@@ -346,6 +463,18 @@ func (c *client) TxCountByAddress(address common.Address) (hexutil.Uint64, error
 	return nonce, err
 }
 
+func (c *client) PendingTxCountByAddress(address common.Address) (hexutil.Uint64, error) {
+	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
+	defer cancel()
+	var nonce hexutil.Uint64
+	err := c.rpc.CallContext(ctxwt, &nonce, "eth_getTransactionCount", address, "pending")
+	if err != nil {
+		log.Error("Call eth_getTransactionCount method fail", "err", err)
+		return 0, err
+	}
+	return nonce, nil
+}
+
 func (c *client) SuggestGasPrice() (*big.Int, error) {
 	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
 	defer cancel()
@@ -366,6 +495,12 @@ func (c *client) SuggestGasTipCap() (*big.Int, error) {
 	return (*big.Int)(&hex), nil
 }
 
+func (c *client) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	ctxwt, cancel := context.WithTimeout(ctx, defaultRequestTimeout)
+	defer cancel()
+	return c.ethClient.EstimateGas(ctxwt, msg)
+}
+
 func (c *client) SendRawTransaction(rawTx string) error {
 	ctxwt, cancel := context.WithTimeout(context.Background(), defaultRequestTimeout)
 	defer cancel()
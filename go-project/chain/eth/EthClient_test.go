@@ -17,6 +17,9 @@ import (
 	globalconst "go-project/common"
 )
 
+// testOwnerPrvKey is the anvil dev account holding the test ERC-20 supply.
+const testOwnerPrvKey = "2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6"
+
 func TestEthClient_LatestFinalizedBlockHeader(t *testing.T) {
 	ctx := context.Background()
 	ethClient, err := DialEthClient(ctx, "http://127.0.0.1:8545")
@@ -204,7 +207,7 @@ func TestEthClient_SendRawTransaction(t *testing.T) {
 	}
 	defer ethClient.Close()
 
-	privateKey, err := crypto.HexToECDSA(globalconst.OWNER_PRV_KEY)
+	privateKey, err := crypto.HexToECDSA(testOwnerPrvKey)
 	if err != nil {
 		t.Fatalf("Failed to create private key: %v", err)
 	}
@@ -279,7 +282,7 @@ func TestEthClient_SendRawERC20Transaction(t *testing.T) {
 		t.Fatalf("Failed to create TestErc20Client: %v", err)
 	}
 
-	privateKey, err := crypto.HexToECDSA(globalconst.OWNER_PRV_KEY)
+	privateKey, err := crypto.HexToECDSA(testOwnerPrvKey)
 	if err != nil {
 		t.Fatalf("Failed to create private key: %v", err)
 	}
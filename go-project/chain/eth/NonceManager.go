@@ -0,0 +1,92 @@
+package eth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+
+	"go-project/main/log"
+)
+
+// defaultStaleReservation is how long a reserved but never sent nonce is
+// kept before it is treated as a gap.
+const defaultStaleReservation = 2 * time.Minute
+
+// NonceStore persists nonce reservations per signer address. It is
+// implemented by the signer_nonce table manager.
+type NonceStore interface {
+	Reserve(address string, chainNonce uint64) (uint64, error)
+	MarkSent(address string, nonce uint64, txHash string) error
+	Release(address string, nonce uint64) error
+	ListGaps(address string, fromNonce uint64, staleBefore time.Time) ([]uint64, error)
+	Claim(address string, nonce uint64, staleBefore time.Time) (bool, error)
+}
+
+// NonceManager hands out nonces for signers shared by several jobs. Every
+// nonce is reserved in the store before signing and either marked sent or
+// released, so concurrent senders never reuse or skip one.
+type NonceManager struct {
+	ethClient EthClient
+	store     NonceStore
+	log       *log.ZapLogger
+
+	mu               sync.Mutex
+	staleReservation time.Duration
+}
+
+func NewNonceManager(ethClient EthClient, store NonceStore, log *log.ZapLogger) *NonceManager {
+	return &NonceManager{
+		ethClient:        ethClient,
+		store:            store,
+		log:              log,
+		staleReservation: defaultStaleReservation,
+	}
+}
+
+func (m *NonceManager) Reserve(address common.Address) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending, err := m.ethClient.PendingTxCountByAddress(address)
+	if err != nil {
+		return 0, fmt.Errorf("获取pending nonce失败: %w", err)
+	}
+
+	nonce, err := m.store.Reserve(address.Hex(), uint64(pending))
+	if err != nil {
+		return 0, err
+	}
+	m.log.Info("NonceManager reserve", zap.String("address", address.Hex()), zap.Uint64("nonce", nonce), zap.Uint64("pending", uint64(pending)))
+	return nonce, nil
+}
+
+func (m *NonceManager) MarkSent(address common.Address, nonce uint64, txHash common.Hash) error {
+	return m.store.MarkSent(address.Hex(), nonce, txHash.Hex())
+}
+
+// Release gives a nonce back after a failed send, so the next Reserve
+// fills the hole instead of leaving later transactions stuck.
+func (m *NonceManager) Release(address common.Address, nonce uint64) {
+	if err := m.store.Release(address.Hex(), nonce); err != nil {
+		m.log.Error("NonceManager release", zap.String("address", address.Hex()), zap.Uint64("nonce", nonce), zap.Error(err))
+	}
+}
+
+// StuckNonces returns released or abandoned nonces between the mined nonce
+// and the signer's newest reservation. Transactions above them can not be
+// mined until the holes are filled.
+func (m *NonceManager) StuckNonces(address common.Address) ([]uint64, error) {
+	latest, err := m.ethClient.TxCountByAddress(address)
+	if err != nil {
+		return nil, fmt.Errorf("获取nonce失败: %w", err)
+	}
+	return m.store.ListGaps(address.Hex(), uint64(latest), time.Now().Add(-m.staleReservation))
+}
+
+// Claim reserves a nonce returned by StuckNonces for the caller.
+func (m *NonceManager) Claim(address common.Address, nonce uint64) (bool, error) {
+	return m.store.Claim(address.Hex(), nonce, time.Now().Add(-m.staleReservation))
+}
@@ -0,0 +1,125 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"go-project/main/config"
+	"go-project/main/log"
+)
+
+type memoryNonceStore struct {
+	status map[uint64]string
+}
+
+func (m *memoryNonceStore) Reserve(address string, chainNonce uint64) (uint64, error) {
+	next := chainNonce
+	for nonce, status := range m.status {
+		if nonce >= chainNonce && status == "released" {
+			m.status[nonce] = "reserved"
+			return nonce, nil
+		}
+		if nonce >= next {
+			next = nonce + 1
+		}
+	}
+	m.status[next] = "reserved"
+	return next, nil
+}
+
+func (m *memoryNonceStore) MarkSent(address string, nonce uint64, txHash string) error {
+	m.status[nonce] = "sent"
+	return nil
+}
+
+func (m *memoryNonceStore) Release(address string, nonce uint64) error {
+	m.status[nonce] = "released"
+	return nil
+}
+
+func (m *memoryNonceStore) ListGaps(address string, fromNonce uint64, staleBefore time.Time) ([]uint64, error) {
+	return nil, nil
+}
+
+func (m *memoryNonceStore) Claim(address string, nonce uint64, staleBefore time.Time) (bool, error) {
+	return false, nil
+}
+
+type sendingEthClient struct {
+	EthClient
+	pending hexutil.Uint64
+	sendErr error
+	sent    int
+}
+
+func (c *sendingEthClient) PendingTxCountByAddress(common.Address) (hexutil.Uint64, error) {
+	return c.pending, nil
+}
+
+func (c *sendingEthClient) BlockHeaderByNumber(*big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(1e9)}, nil
+}
+
+func (c *sendingEthClient) SuggestGasTipCap() (*big.Int, error) {
+	return big.NewInt(1e9), nil
+}
+
+func (c *sendingEthClient) EstimateGas(context.Context, ethereum.CallMsg) (uint64, error) {
+	return 50000, nil
+}
+
+func (c *sendingEthClient) SendRawTransaction(rawTx string) error {
+	c.sent++
+	return c.sendErr
+}
+
+func newTestLogger(t *testing.T) *log.ZapLogger {
+	logger, err := log.NewLogger(&config.Configuration{
+		Log: config.LogConfig{Level: "info", RootDir: t.TempDir(), Filename: "test.log"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	return logger
+}
+
+func TestNonceManager_ReleaseOnFailedSend(t *testing.T) {
+	logger := newTestLogger(t)
+	store := &memoryNonceStore{status: map[uint64]string{}}
+	ethClient := &sendingEthClient{pending: 7, sendErr: errors.New("connection reset")}
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	signer := &PrivateKeySigner{key: privateKey, address: crypto.PubkeyToAddress(privateKey.PublicKey)}
+	service := NewEthBusinessService(ethClient, nil, signer, NewNonceManager(ethClient, store, logger), NewFeeConfig(0, 0, 0, 0), logger)
+	from := signer.Address()
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+
+	_, err = service.attemptTransfer(context.Background(), "TransferNative", to, big.NewInt(1), nil)
+	if err == nil {
+		t.Fatal("Expected send error")
+	}
+	if store.status[7] != "released" {
+		t.Fatalf("Expected nonce 7 to be released, got %q", store.status[7])
+	}
+
+	// the released nonce is handed out again instead of skipping to 8
+	nonce, err := service.reserveNonce(from)
+	if err != nil {
+		t.Fatalf("Failed to reserve nonce: %v", err)
+	}
+	if nonce != 7 {
+		t.Fatalf("Expected released nonce 7 to be reused, got %d", nonce)
+	}
+}
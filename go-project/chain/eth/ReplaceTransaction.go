@@ -0,0 +1,132 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"go.uber.org/zap"
+
+	globalconst "go-project/common"
+)
+
+// defaultReplaceBumpPercent is above the 10% both tip and fee cap must rise
+// by for nodes to accept a replacement with the same nonce.
+const defaultReplaceBumpPercent = 15
+
+// ReplacementFeeTooHighError means outbidding the stuck transaction would
+// exceed the configured maxFeePerGas.
+var ReplacementFeeTooHighError = errors.New("ReplacementFeeTooHighError")
+
+// ReplacementRequest describes a sent transaction to re-broadcast with the
+// same nonce. Cancel replaces it with a zero-value self-transfer instead.
+type ReplacementRequest struct {
+	To                   common.Address
+	Value                *big.Int
+	Data                 []byte
+	Nonce                uint64
+	GasLimit             uint64
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	BumpPercent          uint64
+	Cancel               bool
+}
+
+// PrepareReplacement signs, without broadcasting, a replacement of a stuck
+// transaction with bumped fees. The caller persists the result and then
+// calls Broadcast; the scanner settles whichever transaction of the nonce is
+// mined. The nonce stays with the stuck transaction, so unlike a prepared
+// transfer the result must not be discarded.
+func (s *BusinessService) PrepareReplacement(ctx context.Context, req ReplacementRequest) (*TransferResult, error) {
+	from := s.signer.Address()
+
+	suggested, err := suggestDynamicFee(s.ethClient, s.feeConfig)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := replacementFee(suggested, req, s.feeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	to := req.To
+	value := req.Value
+	data := req.Data
+	gasLimit := req.GasLimit
+	if value == nil || req.Cancel {
+		value = big.NewInt(0)
+	}
+	if req.Cancel {
+		to = from
+		data = nil
+		gasLimit = params.TxGas
+	}
+
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(globalconst.ChainId),
+		Nonce:     req.Nonce,
+		GasTipCap: fee.GasTipCap,
+		GasFeeCap: fee.GasFeeCap,
+		Gas:       gasLimit,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
+	signedTx, rawTx, err := s.sign(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("替换交易已签名", zap.String("txHash", signedTx.Hash().Hex()), zap.Uint64("nonce", req.Nonce), zap.Bool("cancel", req.Cancel),
+		zap.String("maxFeePerGas", fee.GasFeeCap.String()), zap.String("maxPriorityFeePerGas", fee.GasTipCap.String()))
+	return &TransferResult{
+		TxHash:               signedTx.Hash().Hex(),
+		RawTx:                rawTx,
+		Data:                 data,
+		Nonce:                req.Nonce,
+		GasLimit:             gasLimit,
+		MaxFeePerGas:         fee.GasFeeCap,
+		MaxPriorityFeePerGas: fee.GasTipCap,
+	}, nil
+}
+
+// replacementFee raises the previous tip and fee cap by the bump percentage,
+// or to the current suggestion when that is higher. The priority fee cap is
+// not applied since a replacement has to outbid the previous tip.
+func replacementFee(suggested *DynamicFee, req ReplacementRequest, feeConfig FeeConfig) (*DynamicFee, error) {
+	bumpPercent := req.BumpPercent
+	if bumpPercent == 0 {
+		bumpPercent = defaultReplaceBumpPercent
+	}
+
+	tip := maxBig(suggested.GasTipCap, bumpBig(req.MaxPriorityFeePerGas, bumpPercent))
+	feeCap := maxBig(suggested.GasFeeCap, bumpBig(req.MaxFeePerGas, bumpPercent))
+	if tip.Cmp(feeCap) > 0 {
+		feeCap = new(big.Int).Set(tip)
+	}
+	if feeConfig.MaxFeePerGas != nil && feeCap.Cmp(feeConfig.MaxFeePerGas) > 0 {
+		return nil, fmt.Errorf("%w: 需要 %s, 上限 %s", ReplacementFeeTooHighError, feeCap, feeConfig.MaxFeePerGas)
+	}
+	return &DynamicFee{GasTipCap: tip, GasFeeCap: feeCap}, nil
+}
+
+func bumpBig(value *big.Int, percent uint64) *big.Int {
+	if value == nil {
+		return new(big.Int)
+	}
+	// round up so tiny values still increase
+	bumped := new(big.Int).Mul(value, new(big.Int).SetUint64(100+percent))
+	bumped.Add(bumped, big.NewInt(99))
+	return bumped.Div(bumped, big.NewInt(100))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
@@ -0,0 +1,60 @@
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestReplacementFee_BumpsPreviousFees(t *testing.T) {
+	suggested := &DynamicFee{GasTipCap: gwei(1), GasFeeCap: gwei(12)}
+	req := ReplacementRequest{MaxFeePerGas: gwei(20), MaxPriorityFeePerGas: gwei(2), BumpPercent: 10}
+
+	fee, err := replacementFee(suggested, req, NewFeeConfig(0, 0, 0, 0))
+	if err != nil {
+		t.Fatalf("replacementFee failed: %v", err)
+	}
+	if fee.GasFeeCap.Cmp(gwei(22)) != 0 {
+		t.Fatalf("Expected fee cap 22 gwei, got %s", fee.GasFeeCap)
+	}
+	wantTip := new(big.Int).Div(gwei(22), big.NewInt(10))
+	if fee.GasTipCap.Cmp(wantTip) != 0 {
+		t.Fatalf("Expected tip %s, got %s", wantTip, fee.GasTipCap)
+	}
+}
+
+func TestReplacementFee_UsesHigherSuggestion(t *testing.T) {
+	suggested := &DynamicFee{GasTipCap: gwei(5), GasFeeCap: gwei(50)}
+	req := ReplacementRequest{MaxFeePerGas: gwei(20), MaxPriorityFeePerGas: gwei(2)}
+
+	fee, err := replacementFee(suggested, req, NewFeeConfig(0, 0, 0, 0))
+	if err != nil {
+		t.Fatalf("replacementFee failed: %v", err)
+	}
+	if fee.GasFeeCap.Cmp(gwei(50)) != 0 || fee.GasTipCap.Cmp(gwei(5)) != 0 {
+		t.Fatalf("Expected suggested fees, got tip %s cap %s", fee.GasTipCap, fee.GasFeeCap)
+	}
+}
+
+func TestReplacementFee_RoundsUpSmallValues(t *testing.T) {
+	suggested := &DynamicFee{GasTipCap: big.NewInt(0), GasFeeCap: big.NewInt(0)}
+	req := ReplacementRequest{MaxFeePerGas: big.NewInt(3), MaxPriorityFeePerGas: big.NewInt(1)}
+
+	fee, err := replacementFee(suggested, req, NewFeeConfig(0, 0, 0, 0))
+	if err != nil {
+		t.Fatalf("replacementFee failed: %v", err)
+	}
+	if fee.GasFeeCap.Int64() != 4 || fee.GasTipCap.Int64() != 2 {
+		t.Fatalf("Expected tip 2 cap 4, got tip %s cap %s", fee.GasTipCap, fee.GasFeeCap)
+	}
+}
+
+func TestReplacementFee_RespectsFeeCap(t *testing.T) {
+	suggested := &DynamicFee{GasTipCap: gwei(1), GasFeeCap: gwei(12)}
+	req := ReplacementRequest{MaxFeePerGas: gwei(20), MaxPriorityFeePerGas: gwei(2)}
+
+	_, err := replacementFee(suggested, req, NewFeeConfig(21, 0, 0, 0))
+	if !errors.Is(err, ReplacementFeeTooHighError) {
+		t.Fatalf("Expected ReplacementFeeTooHighError, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+
+	"go-project/main/config"
+)
+
+const (
+	SignerTypeKey      = "key"
+	SignerTypeKeystore = "keystore"
+	SignerTypeRemote   = "remote"
+)
+
+// Signer signs transactions for one account. The private key may live in
+// process memory or behind a remote signing service.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// NewSigner builds the signer selected by the signer section of config.yml.
+func NewSigner(ctx context.Context, cfg config.SignerConfig) (Signer, error) {
+	switch cfg.Type {
+	case SignerTypeKey, "":
+		hexKey := cfg.PrivateKey
+		if cfg.PrivateKeyEnv != "" && os.Getenv(cfg.PrivateKeyEnv) != "" {
+			hexKey = os.Getenv(cfg.PrivateKeyEnv)
+		}
+		if hexKey == "" {
+			return nil, fmt.Errorf("signer private key is empty, set %s or signer.private_key", cfg.PrivateKeyEnv)
+		}
+		return NewPrivateKeySigner(hexKey)
+	case SignerTypeKeystore:
+		password := cfg.KeystorePassword
+		if cfg.KeystorePasswordEnv != "" && os.Getenv(cfg.KeystorePasswordEnv) != "" {
+			password = os.Getenv(cfg.KeystorePasswordEnv)
+		}
+		return NewKeystoreSigner(cfg.KeystorePath, password)
+	case SignerTypeRemote:
+		return NewRemoteSigner(ctx, cfg.RemoteUrl, common.HexToAddress(cfg.RemoteAddress))
+	default:
+		return nil, fmt.Errorf("unknown signer type %q", cfg.Type)
+	}
+}
+
+// PrivateKeySigner signs with a key held in memory.
+type PrivateKeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+func NewPrivateKeySigner(hexKey string) (*PrivateKeySigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %w", err)
+	}
+	return &PrivateKeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+// NewKeystoreSigner decrypts a go-ethereum keystore (V3) file once at
+// startup.
+func NewKeystoreSigner(path, password string) (*PrivateKeySigner, error) {
+	keyJson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore文件失败: %w", err)
+	}
+	key, err := keystore.DecryptKey(keyJson, password)
+	if err != nil {
+		return nil, fmt.Errorf("解密keystore失败: %w", err)
+	}
+	return &PrivateKeySigner{key: key.PrivateKey, address: key.Address}, nil
+}
+
+func (s *PrivateKeySigner) Address() common.Address {
+	return s.address
+}
+
+func (s *PrivateKeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// RemoteSigner asks a signing service (clef, web3signer, a geth node with
+// an unlocked account) to sign through eth_signTransaction.
+type RemoteSigner struct {
+	rpc     *gethrpc.Client
+	address common.Address
+}
+
+func NewRemoteSigner(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	if url == "" {
+		return nil, fmt.Errorf("remote signer url is empty")
+	}
+	client, err := gethrpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("连接远程签名服务失败: %w", err)
+	}
+	return &RemoteSigner{rpc: client, address: address}, nil
+}
+
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+type signTransactionArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to,omitempty"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	GasPrice             *hexutil.Big    `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas,omitempty"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Data                 hexutil.Bytes   `json:"data"`
+	ChainID              *hexutil.Big    `json:"chainId"`
+}
+
+type signTransactionResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := signTransactionArgs{
+		From:    s.address,
+		To:      tx.To(),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		Data:    tx.Data(),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	var result signTransactionResult
+	if err := s.rpc.CallContext(ctx, &result, "eth_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("远程签名失败: %w", err)
+	}
+
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(result.Raw); err != nil {
+		return nil, fmt.Errorf("解析远程签名交易失败: %w", err)
+	}
+	if err := verifySignedTx(tx, signedTx, s.address, chainID); err != nil {
+		return nil, err
+	}
+	return signedTx, nil
+}
+
+// verifySignedTx makes sure the remote signer signed exactly the requested
+// transaction with the expected account.
+func verifySignedTx(tx, signedTx *types.Transaction, address common.Address, chainID *big.Int) error {
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signedTx) != signer.Hash(tx) {
+		return fmt.Errorf("远程签名交易与请求不一致")
+	}
+	sender, err := types.Sender(signer, signedTx)
+	if err != nil {
+		return fmt.Errorf("远程签名交易无法恢复签名地址: %w", err)
+	}
+	if sender != address {
+		return fmt.Errorf("远程签名地址不一致: %s != %s", sender.Hex(), address.Hex())
+	}
+	return nil
+}
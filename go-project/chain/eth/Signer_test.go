@@ -0,0 +1,180 @@
+package eth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+
+	"go-project/main/config"
+)
+
+func newTestDynamicFeeTx() *types.Transaction {
+	to := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(31337),
+		Nonce:     3,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(3e9),
+		Gas:       60000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0xa9, 0x05, 0x9c, 0xbb},
+	})
+}
+
+func assertSignedBy(t *testing.T, signedTx *types.Transaction, address common.Address) {
+	t.Helper()
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(31337)), signedTx)
+	if err != nil {
+		t.Fatalf("Failed to recover sender: %v", err)
+	}
+	if sender != address {
+		t.Fatalf("Expected sender %s, got %s", address.Hex(), sender.Hex())
+	}
+}
+
+func TestNewSigner_KeyFromEnv(t *testing.T) {
+	t.Setenv("TEST_SIGNER_KEY", testOwnerPrvKey)
+	signer, err := NewSigner(context.Background(), config.SignerConfig{Type: SignerTypeKey, PrivateKeyEnv: "TEST_SIGNER_KEY"})
+	if err != nil {
+		t.Fatalf("NewSigner failed: %v", err)
+	}
+
+	signedTx, err := signer.SignTx(context.Background(), newTestDynamicFeeTx(), big.NewInt(31337))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signedTx, signer.Address())
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	key := &keystore.Key{Address: crypto.PubkeyToAddress(privateKey.PublicKey), PrivateKey: privateKey}
+	keyJson, err := keystore.EncryptKey(key, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "owner.json")
+	if err := os.WriteFile(path, keyJson, 0600); err != nil {
+		t.Fatalf("Failed to write keystore: %v", err)
+	}
+
+	if _, err := NewKeystoreSigner(path, "wrong"); err == nil {
+		t.Fatal("Expected error for wrong password")
+	}
+	signer, err := NewKeystoreSigner(path, "secret")
+	if err != nil {
+		t.Fatalf("NewKeystoreSigner failed: %v", err)
+	}
+	if signer.Address() != key.Address {
+		t.Fatalf("Expected address %s, got %s", key.Address.Hex(), signer.Address().Hex())
+	}
+
+	signedTx, err := signer.SignTx(context.Background(), newTestDynamicFeeTx(), big.NewInt(31337))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signedTx, key.Address)
+}
+
+// stubSignerService answers eth_signTransaction like clef or geth do.
+type stubSignerService struct {
+	key      *ecdsa.PrivateKey
+	gasDelta uint64
+}
+
+func (s *stubSignerService) SignTransaction(args signTransactionArgs) (*signTransactionResult, error) {
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   args.ChainID.ToInt(),
+		Nonce:     uint64(args.Nonce),
+		GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: args.MaxFeePerGas.ToInt(),
+		Gas:       uint64(args.Gas) + s.gasDelta,
+		To:        args.To,
+		Value:     args.Value.ToInt(),
+		Data:      args.Data,
+	})
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTransactionResult{Raw: hexutil.Bytes(raw)}, nil
+}
+
+func newStubRemoteSigner(t *testing.T, service *stubSignerService, address common.Address) *RemoteSigner {
+	t.Helper()
+	server := gethrpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatalf("Failed to register stub: %v", err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+
+	signer, err := NewRemoteSigner(context.Background(), httpServer.URL, address)
+	if err != nil {
+		t.Fatalf("NewRemoteSigner failed: %v", err)
+	}
+	return signer
+}
+
+func TestRemoteSigner(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	signer := newStubRemoteSigner(t, &stubSignerService{key: privateKey}, address)
+
+	tx := newTestDynamicFeeTx()
+	signedTx, err := signer.SignTx(context.Background(), tx, big.NewInt(31337))
+	if err != nil {
+		t.Fatalf("SignTx failed: %v", err)
+	}
+	assertSignedBy(t, signedTx, address)
+	if signedTx.Nonce() != tx.Nonce() || signedTx.Gas() != tx.Gas() {
+		t.Fatalf("Signed transaction differs from request")
+	}
+}
+
+func TestRemoteSigner_RejectsMismatch(t *testing.T) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	otherKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to create private key: %v", err)
+	}
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	wrongAccount := newStubRemoteSigner(t, &stubSignerService{key: otherKey}, address)
+	if _, err := wrongAccount.SignTx(context.Background(), newTestDynamicFeeTx(), big.NewInt(31337)); err == nil {
+		t.Fatal("Expected error for transaction signed by another account")
+	}
+
+	alteredTx := newStubRemoteSigner(t, &stubSignerService{key: privateKey, gasDelta: 1}, address)
+	if _, err := alteredTx.SignTx(context.Background(), newTestDynamicFeeTx(), big.NewInt(31337)); err == nil {
+		t.Fatal("Expected error for altered transaction")
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 type TestErc20Client interface {
 	BalanceOf(ctx context.Context, address common.Address) (*big.Int, error)
+	Allowance(ctx context.Context, owner common.Address, spender common.Address) (*big.Int, error)
 	Approve(auth *bind.TransactOpts, spender common.Address, amount *big.Int) (common.Hash, error)
 	Transfer(auth *bind.TransactOpts, to common.Address, amount *big.Int) (common.Hash, error)
 	Close() error
@@ -49,6 +50,10 @@ func (c *erc20Client) BalanceOf(ctx context.Context, address common.Address) (*b
 	return c.instance.BalanceOf(&bind.CallOpts{Context: ctx}, address)
 }
 
+func (c *erc20Client) Allowance(ctx context.Context, owner common.Address, spender common.Address) (*big.Int, error) {
+	return c.instance.Allowance(&bind.CallOpts{Context: ctx}, owner, spender)
+}
+
 func (c *erc20Client) Approve(auth *bind.TransactOpts, spender common.Address, amount *big.Int) (common.Hash, error) {
 	tx, err := c.instance.Approve(auth, spender, amount)
 	if err != nil {
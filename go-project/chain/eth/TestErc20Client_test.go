@@ -40,7 +40,7 @@ func TestTestErc20Client_ApproveAndTransfer(t *testing.T) {
 	printERC20Balance(t, ctx, erc20Client, fromAddress, "From")
 	printERC20Balance(t, ctx, erc20Client, toAddress, "To")
 
-	privateKey, err := crypto.HexToECDSA(global_const.OWNER_PRV_KEY)
+	privateKey, err := crypto.HexToECDSA(testOwnerPrvKey)
 	if err != nil {
 		t.Fatalf("Failed to create private key: %v", err)
 	}
@@ -0,0 +1,122 @@
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+
+	"go-project/abigo"
+)
+
+// TokenMetadata is what an ERC-20 contract reports about itself.
+type TokenMetadata struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+// TokenRegistry hands out one ERC-20 client per contract address, built on
+// first use over a single shared connection.
+type TokenRegistry struct {
+	backend bind.ContractBackend
+	closer  func()
+
+	mu      sync.Mutex
+	clients map[common.Address]TestErc20Client
+}
+
+func NewTokenRegistry(ctx context.Context, rpcUrl string) (*TokenRegistry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDialTimeout)
+	defer cancel()
+
+	ethClient, err := ethclient.DialContext(ctx, rpcUrl)
+	if err != nil {
+		return nil, err
+	}
+	registry := NewTokenRegistryWithBackend(ethClient)
+	registry.closer = ethClient.Close
+	return registry, nil
+}
+
+func NewTokenRegistryWithBackend(backend bind.ContractBackend) *TokenRegistry {
+	return &TokenRegistry{
+		backend: backend,
+		clients: make(map[common.Address]TestErc20Client),
+	}
+}
+
+func (r *TokenRegistry) Client(contractAddress common.Address) (TestErc20Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if client, ok := r.clients[contractAddress]; ok {
+		return client, nil
+	}
+	instance, err := abigo.NewTesterc20(contractAddress, r.backend)
+	if err != nil {
+		return nil, fmt.Errorf("创建ERC20客户端失败 %s: %w", contractAddress.Hex(), err)
+	}
+	client := &erc20Client{instance: instance}
+	r.clients[contractAddress] = client
+	return client, nil
+}
+
+// Metadata reads name, symbol and decimals from the contract. It fails for
+// addresses without code or contracts that are not ERC-20 tokens.
+func (r *TokenRegistry) Metadata(ctx context.Context, contractAddress common.Address) (*TokenMetadata, error) {
+	code, err := r.backend.CodeAt(ctx, contractAddress, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取合约代码失败: %w", err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("地址 %s 不是合约", contractAddress.Hex())
+	}
+
+	caller, err := abigo.NewTesterc20Caller(contractAddress, r.backend)
+	if err != nil {
+		return nil, err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	name, err := caller.Name(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取name失败: %w", err)
+	}
+	symbol, err := caller.Symbol(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取symbol失败: %w", err)
+	}
+	decimals, err := caller.Decimals(opts)
+	if err != nil {
+		return nil, fmt.Errorf("读取decimals失败: %w", err)
+	}
+	return &TokenMetadata{Name: name, Symbol: symbol, Decimals: decimals}, nil
+}
+
+// NativeBalance returns the account's balance of the native asset. It needs
+// a backend that can read balances, such as an ethclient connection.
+func (r *TokenRegistry) NativeBalance(ctx context.Context, account common.Address) (*big.Int, error) {
+	reader, ok := r.backend.(balanceReader)
+	if !ok {
+		return nil, fmt.Errorf("backend 不支持查询余额")
+	}
+	balance, err := reader.BalanceAt(ctx, account, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取余额失败: %w", err)
+	}
+	return balance, nil
+}
+
+type balanceReader interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+func (r *TokenRegistry) Close() {
+	if r.closer != nil {
+		r.closer()
+	}
+}
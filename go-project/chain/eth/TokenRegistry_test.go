@@ -0,0 +1,80 @@
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"go-project/abigo"
+)
+
+// fakeContractBackend answers the ERC-20 metadata calls of one contract.
+type fakeContractBackend struct {
+	bind.ContractBackend
+	token common.Address
+}
+
+func (b *fakeContractBackend) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	if contract == b.token {
+		return []byte{0x60, 0x80}, nil
+	}
+	return nil, nil
+}
+
+func (b *fakeContractBackend) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	erc20Abi, err := abigo.Testerc20MetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	method, err := erc20Abi.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "name":
+		return method.Outputs.Pack("Test USDT")
+	case "symbol":
+		return method.Outputs.Pack("TUSDT")
+	case "decimals":
+		return method.Outputs.Pack(uint8(6))
+	}
+	return nil, nil
+}
+
+func TestTokenRegistry_Metadata(t *testing.T) {
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	registry := NewTokenRegistryWithBackend(&fakeContractBackend{token: token})
+
+	metadata, err := registry.Metadata(context.Background(), token)
+	if err != nil {
+		t.Fatalf("Metadata failed: %v", err)
+	}
+	if metadata.Name != "Test USDT" || metadata.Symbol != "TUSDT" || metadata.Decimals != 6 {
+		t.Fatalf("Unexpected metadata: %+v", metadata)
+	}
+
+	if _, err := registry.Metadata(context.Background(), common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")); err == nil {
+		t.Fatal("Expected error for address without code")
+	}
+}
+
+func TestTokenRegistry_ClientIsCached(t *testing.T) {
+	registry := NewTokenRegistryWithBackend(&fakeContractBackend{})
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+
+	first, err := registry.Client(token)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	second, err := registry.Client(token)
+	if err != nil {
+		t.Fatalf("Client failed: %v", err)
+	}
+	if first != second {
+		t.Fatal("Expected the same client for the same contract")
+	}
+}
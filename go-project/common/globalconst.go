@@ -3,7 +3,6 @@ package global_const
 const (
 	SystemUser              = "0"
 	ChainId                 = 31337
-	OWNER_PRV_KEY           = "2a871d0798f97d79848a013d4936a73bf4cc922c825d33c1cf7073dff6d409c6"
 	TEMP_TO_ADDRESS         = "0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f"
 	TEMP_TEST_ERC20_ADDRESS = "0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35"
 )
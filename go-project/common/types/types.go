@@ -1,14 +1,16 @@
 package types
 
 type PageReq struct {
-	PageNum  uint64 `json:"pageNum"`
-	PageSize uint64 `json:"pageSize"`
+	PageNum  uint64 `json:"pageNum" form:"pageNum"`
+	PageSize uint64 `json:"pageSize" form:"pageSize"`
 }
 
 type PageResp struct {
-	PageNum   uint64 `json:"pageNum,string"`
-	PageSize  uint64 `json:"pageSize,string"`
-	TotalPage uint64 `json:"totalPage,string"`
+	PageNum    uint64 `json:"pageNum,string"`
+	PageSize   uint64 `json:"pageSize,string"`
+	TotalPage  uint64 `json:"totalPage,string"`
+	Total      uint64 `json:"total,string"`
+	NextCursor string `json:"nextCursor,omitempty"` // set by keyset paginated APIs while more rows may follow
 }
 
 type GenericPageReq[T any] struct {
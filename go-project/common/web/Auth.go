@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"go-project/util/jwt"
+)
+
+const callerAddressKey = "callerAddress"
+
+// AuthHandler rejects requests without a valid session token and stores the
+// wallet address it is bound to for CallerAddress.
+func AuthHandler(secret []byte) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || token == "" {
+			unauthorized(c)
+			return
+		}
+		claims, err := jwt.Parse(token, secret, time.Now())
+		if err != nil || claims.Subject == "" {
+			unauthorized(c)
+			return
+		}
+		c.Set(callerAddressKey, claims.Subject)
+		c.Next()
+	}
+}
+
+// CallerAddress returns the authenticated wallet address, empty on routes
+// not behind AuthHandler.
+func CallerAddress(c *gin.Context) string {
+	return c.GetString(callerAddressKey)
+}
+
+func unauthorized(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, Response{
+		http.StatusUnauthorized,
+		nil,
+		"unauthorized",
+	})
+}
@@ -0,0 +1,32 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCorsHandler_Preflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CorsHandler([]string{"http://localhost:8888"}))
+
+	request := httptest.NewRequest(http.MethodOptions, "/workflow/create", nil)
+	request.Header.Set("Origin", "http://localhost:8888")
+	request.Header.Set("Access-Control-Request-Headers", "authorization,content-type,idempotency-key")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Header().Get("Access-Control-Allow-Headers"), IdempotencyKeyHeader) {
+		t.Fatalf("Expected %s to be allowed, got %q", IdempotencyKeyHeader, recorder.Header().Get("Access-Control-Allow-Headers"))
+	}
+	if !strings.Contains(recorder.Header().Get("Access-Control-Expose-Headers"), IdempotentReplayedHeader) {
+		t.Fatalf("Expected %s to be exposed, got %q", IdempotentReplayedHeader, recorder.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed" // set on replayed responses
+)
+
+var (
+	IdempotencyKeyInProgressError = errors.New("IdempotencyKeyInProgressError")
+	IdempotencyKeyReusedError     = errors.New("IdempotencyKeyReusedError")
+)
+
+// StoredResponse is a response kept for an idempotency key.
+type StoredResponse struct {
+	Code int
+	Body []byte
+}
+
+// IdempotencyStore keeps idempotency keys per caller.
+type IdempotencyStore interface {
+	// Begin claims key for a request with requestHash. It returns the stored
+	// response when the key already completed, IdempotencyKeyInProgressError
+	// while another request holds it and IdempotencyKeyReusedError when the
+	// key was used for a different request.
+	Begin(callerAddr, key, requestHash string) (*StoredResponse, error)
+	Complete(callerAddr, key string, response StoredResponse) error
+	// Release forgets a key whose request did not succeed.
+	Release(callerAddr, key string) error
+}
+
+// IdempotencyHandler replays the stored response when a request is retried
+// with the same Idempotency-Key header. Only successful responses are kept,
+// so a failed request may be retried with its key. Requests without the
+// header pass through. It must run after AuthHandler.
+func IdempotencyHandler(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 128 {
+			FailV2(c, http.StatusBadRequest, "Idempotency-Key longer than 128 characters")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			FailV2(c, http.StatusBadRequest, "read body error")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		callerAddr := CallerAddress(c)
+		stored, err := store.Begin(callerAddr, key, requestHash(c.Request.Method, c.FullPath(), body))
+		switch {
+		case errors.Is(err, IdempotencyKeyInProgressError):
+			FailV2(c, http.StatusConflict, "request with this Idempotency-Key is in progress")
+			c.Abort()
+			return
+		case errors.Is(err, IdempotencyKeyReusedError):
+			FailV2(c, http.StatusUnprocessableEntity, "Idempotency-Key was used for a different request")
+			c.Abort()
+			return
+		case err != nil:
+			Fail(c, err.Error())
+			c.Abort()
+			return
+		case stored != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(stored.Code, "application/json; charset=utf-8", stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			if !completed {
+				_ = store.Release(callerAddr, key)
+			}
+		}()
+
+		c.Next()
+
+		if !succeeded(c.Writer.Status(), recorder.body.Bytes()) {
+			return
+		}
+		if err := store.Complete(callerAddr, key, StoredResponse{Code: c.Writer.Status(), Body: recorder.body.Bytes()}); err != nil {
+			return
+		}
+		completed = true
+	}
+}
+
+func requestHash(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// succeeded reports whether the handler answered with Success; failures
+// are also sent with HTTP 200 but carry another code in the body.
+func succeeded(status int, body []byte) bool {
+	if status != http.StatusOK {
+		return false
+	}
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	return response.Code == http.StatusOK
+}
+
+// responseRecorder copies what the handler writes.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
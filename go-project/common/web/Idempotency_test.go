@@ -0,0 +1,115 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryEntry struct {
+	hash     string
+	response *StoredResponse
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+func (s *memoryStore) Begin(callerAddr, key, requestHash string) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[callerAddr+key]
+	if !ok {
+		s.entries[callerAddr+key] = &memoryEntry{hash: requestHash}
+		return nil, nil
+	}
+	if entry.hash != requestHash {
+		return nil, IdempotencyKeyReusedError
+	}
+	if entry.response == nil {
+		return nil, IdempotencyKeyInProgressError
+	}
+	return entry.response, nil
+}
+
+func (s *memoryStore) Complete(callerAddr, key string, response StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[callerAddr+key].response = &response
+	return nil
+}
+
+func (s *memoryStore) Release(callerAddr, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, callerAddr+key)
+	return nil
+}
+
+func newIdempotentEngine(store IdempotencyStore, handled *int, fail *bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/workflow/create", IdempotencyHandler(store), func(c *gin.Context) {
+		*handled++
+		if *fail {
+			Fail(c, "Insufficient balance")
+			return
+		}
+		Success(c, map[string]int{"id": *handled})
+	})
+	return engine
+}
+
+func post(engine *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/workflow/create", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(IdempotencyKeyHeader, key)
+	}
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyHandler_Replays(t *testing.T) {
+	store := &memoryStore{entries: map[string]*memoryEntry{}}
+	handled, fail := 0, false
+	engine := newIdempotentEngine(store, &handled, &fail)
+
+	first := post(engine, "key-1", `{"amount":"1"}`)
+	retry := post(engine, "key-1", `{"amount":"1"}`)
+	if handled != 1 {
+		t.Fatalf("Expected the handler to run once, ran %d times", handled)
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("Expected replay of %s, got %s", first.Body.String(), retry.Body.String())
+	}
+
+	reused := post(engine, "key-1", `{"amount":"2"}`)
+	if handled != 1 || !strings.Contains(reused.Body.String(), `"code":422`) {
+		t.Fatalf("Expected a reused key to be refused, got %s", reused.Body.String())
+	}
+
+	post(engine, "", `{"amount":"1"}`)
+	post(engine, "", `{"amount":"1"}`)
+	if handled != 3 {
+		t.Fatalf("Expected requests without a key to pass through, handler ran %d times", handled)
+	}
+}
+
+func TestIdempotencyHandler_ReleasesFailures(t *testing.T) {
+	store := &memoryStore{entries: map[string]*memoryEntry{}}
+	handled, fail := 0, true
+	engine := newIdempotentEngine(store, &handled, &fail)
+
+	post(engine, "key-2", `{"amount":"1"}`)
+	fail = false
+	retry := post(engine, "key-2", `{"amount":"1"}`)
+	if handled != 2 || !strings.Contains(retry.Body.String(), `"code":200`) {
+		t.Fatalf("Expected a failed request to be retried, handler ran %d times, got %s", handled, retry.Body.String())
+	}
+}
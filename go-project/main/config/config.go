@@ -9,6 +9,13 @@ type Configuration struct {
 	Log           LogConfig           `mapstructure:"log" json:"log" yaml:"log"`
 	MysqlDatabase MysqlDatabaseConfig `mapstructure:"mysqlDatabase" json:"mysqlDatabase" yaml:"mysqlDatabase"`
 	Anvil         AnvilConfig         `mapstructure:"anvil" json:"anvil" yaml:"anvil"`
+	Scan          ScanConfig          `mapstructure:"scan" json:"scan" yaml:"scan"`
+	Fee           FeeConfig           `mapstructure:"fee" json:"fee" yaml:"fee"`
+	Replace       ReplaceConfig       `mapstructure:"replace" json:"replace" yaml:"replace"`
+	Signer        SignerConfig        `mapstructure:"signer" json:"signer" yaml:"signer"`
+	Workflow      WorkflowConfig      `mapstructure:"workflow" json:"workflow" yaml:"workflow"`
+	Auth          AuthConfig          `mapstructure:"auth" json:"auth" yaml:"auth"`
+	Dispatch      DispatchConfig      `mapstructure:"dispatch" json:"dispatch" yaml:"dispatch"`
 }
 
 type ServerConfig struct {
@@ -50,6 +57,55 @@ type AnvilConfig struct {
 	Port int    `mapstructure:"port" json:"port" yaml:"port"`
 }
 
+type ScanConfig struct {
+	HeadMode       string `mapstructure:"head_mode" json:"head_mode" yaml:"head_mode"` // finalized / confirmation
+	Workers        int    `mapstructure:"workers" json:"workers" yaml:"workers"`
+	FetchBatchSize int    `mapstructure:"fetch_batch_size" json:"fetch_batch_size" yaml:"fetch_batch_size"` // blocks per rpc batch
+}
+
+type FeeConfig struct {
+	MaxFeePerGasGwei         uint64 `mapstructure:"max_fee_per_gas_gwei" json:"max_fee_per_gas_gwei" yaml:"max_fee_per_gas_gwei"`                            // 0 = no cap
+	MaxPriorityFeePerGasGwei uint64 `mapstructure:"max_priority_fee_per_gas_gwei" json:"max_priority_fee_per_gas_gwei" yaml:"max_priority_fee_per_gas_gwei"` // 0 = no cap
+	BaseFeeMultiplier        int64  `mapstructure:"base_fee_multiplier" json:"base_fee_multiplier" yaml:"base_fee_multiplier"`
+	GasLimitMarginPercent    uint64 `mapstructure:"gas_limit_margin_percent" json:"gas_limit_margin_percent" yaml:"gas_limit_margin_percent"`
+}
+
+type ReplaceConfig struct {
+	StuckAfterSeconds int    `mapstructure:"stuck_after_seconds" json:"stuck_after_seconds" yaml:"stuck_after_seconds"` // pending this long before a speed-up
+	MaxSpeedUps       int    `mapstructure:"max_speed_ups" json:"max_speed_ups" yaml:"max_speed_ups"`                   // then cancel
+	FeeBumpPercent    uint64 `mapstructure:"fee_bump_percent" json:"fee_bump_percent" yaml:"fee_bump_percent"`
+}
+
+type SignerConfig struct {
+	Type                string `mapstructure:"type" json:"type" yaml:"type"` // key / keystore / remote
+	PrivateKey          string `mapstructure:"private_key" json:"private_key" yaml:"private_key"`
+	PrivateKeyEnv       string `mapstructure:"private_key_env" json:"private_key_env" yaml:"private_key_env"` // overrides private_key when set
+	KeystorePath        string `mapstructure:"keystore_path" json:"keystore_path" yaml:"keystore_path"`
+	KeystorePassword    string `mapstructure:"keystore_password" json:"keystore_password" yaml:"keystore_password"`
+	KeystorePasswordEnv string `mapstructure:"keystore_password_env" json:"keystore_password_env" yaml:"keystore_password_env"`
+	RemoteUrl           string `mapstructure:"remote_url" json:"remote_url" yaml:"remote_url"`
+	RemoteAddress       string `mapstructure:"remote_address" json:"remote_address" yaml:"remote_address"`
+}
+
+type WorkflowConfig struct {
+	ExpireAfterHours int `mapstructure:"expire_after_hours" json:"expire_after_hours" yaml:"expire_after_hours"` // pending workflows expire after this
+}
+
+type DispatchConfig struct {
+	LeaseSeconds    int    `mapstructure:"lease_seconds" json:"lease_seconds" yaml:"lease_seconds"`          // a claimed payout returns to pending when not renewed for this long
+	BatchSize       int    `mapstructure:"batch_size" json:"batch_size" yaml:"batch_size"`                   // payouts claimed per tick
+	DisperseAddress string `mapstructure:"disperse_address" json:"disperse_address" yaml:"disperse_address"` // batch mode: ERC-20 payouts of a token are paid in one call of this contract, empty sends them one by one
+}
+
+type AuthConfig struct {
+	Domain            string   `mapstructure:"domain" json:"domain" yaml:"domain"` // host[:port] expected in SIWE messages
+	JwtSecret         string   `mapstructure:"jwt_secret" json:"jwt_secret" yaml:"jwt_secret"`
+	JwtSecretEnv      string   `mapstructure:"jwt_secret_env" json:"jwt_secret_env" yaml:"jwt_secret_env"` // overrides jwt_secret when set
+	NonceTtlMinutes   int      `mapstructure:"nonce_ttl_minutes" json:"nonce_ttl_minutes" yaml:"nonce_ttl_minutes"`
+	SessionTtlMinutes int      `mapstructure:"session_ttl_minutes" json:"session_ttl_minutes" yaml:"session_ttl_minutes"`
+	AllowedOrigins    []string `mapstructure:"allowed_origins" json:"allowed_origins" yaml:"allowed_origins"`
+}
+
 func LoadConfig() (*Configuration, error) {
 	viper.SetConfigFile("config.yml")
 	err := viper.ReadInConfig()
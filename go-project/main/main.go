@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"go.uber.org/zap"
 
+	"go-project/business/token/do"
 	"go-project/chain/eth"
-	globalconst "go-project/common"
 	"go-project/main/anvil"
 	"go-project/main/config"
 	"go-project/main/db"
@@ -49,26 +50,56 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to create Ethereum client", zap.Any("anvilUrl", anvilUrl), zap.Error(err))
 	}
-	erc20Client, err := eth.NewTestErc20Client(ctx, anvilUrl, globalconst.TEMP_TEST_ERC20_ADDRESS)
+	tokens, err := eth.NewTokenRegistry(ctx, anvilUrl)
 	if err != nil {
-		logger.Fatal("Failed to create NewTestErc20Client", zap.Any("anvilUrl", anvilUrl), zap.Error(err))
+		logger.Fatal("Failed to create TokenRegistry", zap.Any("anvilUrl", anvilUrl), zap.Error(err))
 	}
+	defer tokens.Close()
 
-	scanBlock, err := scheduled.NewScanBlock(ctx, ethClient, dbb, logger)
+	ethSlot, err := scheduled.NewEthSlot(ctx, ethClient, dbb, logger, cfg.Scan.HeadMode)
+	if err != nil {
+		logger.Fatal("Failed to create EthSlot", zap.Error(err))
+	}
+	blockFetcher := scheduled.NewBlockFetcher(ethClient, cfg.Scan.Workers, cfg.Scan.FetchBatchSize, logger)
+	scanBlock, err := scheduled.NewScanBlock(ctx, ethClient, ethSlot, blockFetcher, dbb, logger)
 	if err != nil {
 		logger.Fatal("Failed to create ScanBlock", zap.Error(err))
 	}
-	processingFLow, err := scheduled.NewProcessingFLow(ctx, ethClient, erc20Client, dbb, logger)
+	signer, err := eth.NewSigner(ctx, cfg.Signer)
+	if err != nil {
+		logger.Fatal("Failed to create signer", zap.String("type", cfg.Signer.Type), zap.Error(err))
+	}
+	nonceManager := eth.NewNonceManager(ethClient, do.NewSignerNonceManager(dbb), logger)
+	feeConfig := eth.NewFeeConfig(cfg.Fee.MaxFeePerGasGwei, cfg.Fee.MaxPriorityFeePerGasGwei, cfg.Fee.BaseFeeMultiplier, cfg.Fee.GasLimitMarginPercent)
+	businessService := eth.NewEthBusinessService(ethClient, tokens, signer, nonceManager, feeConfig, logger)
+	processingFLow, err := scheduled.NewProcessingFLow(ctx, ethClient, tokens, businessService, dbb, logger,
+		time.Duration(cfg.Dispatch.LeaseSeconds)*time.Second, cfg.Dispatch.BatchSize, cfg.Dispatch.DisperseAddress)
 	if err != nil {
 		logger.Fatal("Failed to create processingFLow", zap.Error(err))
 	}
-	incrementBlock, err := scheduled.NewTestIncrementBlock(ctx, ethClient, erc20Client, dbb, logger)
+	incrementBlock, err := scheduled.NewTestIncrementBlock(ctx, ethClient, tokens, businessService, dbb, logger)
 	if err != nil {
 		logger.Fatal("Failed to create incrementBlock", zap.Error(err))
 	}
+	stuckTxReplacer, err := scheduled.NewStuckTxReplacer(ctx, ethClient, businessService, dbb, logger,
+		time.Duration(cfg.Replace.StuckAfterSeconds)*time.Second, cfg.Replace.MaxSpeedUps, cfg.Replace.FeeBumpPercent)
+	if err != nil {
+		logger.Fatal("Failed to create stuckTxReplacer", zap.Error(err))
+	}
+	workflowExpirer, err := scheduled.NewWorkflowExpirer(ctx, dbb, logger, time.Duration(cfg.Workflow.ExpireAfterHours)*time.Hour)
+	if err != nil {
+		logger.Fatal("Failed to create workflowExpirer", zap.Error(err))
+	}
+	workflowScheduler, err := scheduled.NewWorkflowScheduler(ctx, dbb, logger)
+	if err != nil {
+		logger.Fatal("Failed to create workflowScheduler", zap.Error(err))
+	}
 	go scanBlock.Start()
 	go processingFLow.Start()
 	go incrementBlock.Start()
+	go stuckTxReplacer.Start()
+	go workflowExpirer.Start()
+	go workflowScheduler.Start()
 
-	server.RunServer(cfg, logger, dbb, erc20Client)
+	server.RunServer(cfg, logger, dbb, tokens, signer)
 }
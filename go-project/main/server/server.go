@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,22 +15,31 @@ import (
 	"gorm.io/gorm"
 
 	"go-project/business"
+	authservice "go-project/business/auth/service"
 	"go-project/chain/eth"
+	globalconst "go-project/common"
 	"go-project/common/web"
 	"go-project/main/config"
 	"go-project/main/log"
 )
 
-func RunServer(cfg *config.Configuration, log *log.ZapLogger, db *gorm.DB, ERC20Client eth.TestErc20Client) {
+func RunServer(cfg *config.Configuration, log *log.ZapLogger, db *gorm.DB, tokens *eth.TokenRegistry, signer eth.Signer) {
 	ginRouter := gin.Default()
 
-	ginRouter.Use(web.CorsHandler())
+	authSettings, err := newAuthSettings(cfg.Auth, cfg.Server.Env)
+	if err != nil {
+		log.Fatal("Failed to load auth config", zap.Error(err))
+	}
+
+	ginRouter.Use(web.CorsHandler(cfg.Auth.AllowedOrigins))
 	ginRouter.Use(web.ErrorHandler(log))
 
 	router := &business.Route{
-		DB:          db,
-		Log:         log,
-		ERC20Client: ERC20Client,
+		DB:     db,
+		Log:    log,
+		Tokens: tokens,
+		Signer: signer,
+		Auth:   authSettings,
 	}
 	router.Register(ginRouter)
 
@@ -58,3 +68,32 @@ func RunServer(cfg *config.Configuration, log *log.ZapLogger, db *gorm.DB, ERC20
 
 	log.Info("ServerConfig exiting")
 }
+
+// newAuthSettings loads the auth config. Outside the local env the JWT
+// secret has to come from the environment, the one in config.yml is public.
+func newAuthSettings(cfg config.AuthConfig, env string) (authservice.Settings, error) {
+	secret := cfg.JwtSecret
+	fromEnv := cfg.JwtSecretEnv != "" && os.Getenv(cfg.JwtSecretEnv) != ""
+	if fromEnv {
+		secret = os.Getenv(cfg.JwtSecretEnv)
+	}
+	if !fromEnv && env != "local" {
+		return authservice.Settings{}, fmt.Errorf("jwt secret must be set with %s outside the local env", cfg.JwtSecretEnv)
+	}
+	if len(secret) < 16 {
+		return authservice.Settings{}, fmt.Errorf("jwt secret must be at least 16 bytes, set %s or auth.jwt_secret", cfg.JwtSecretEnv)
+	}
+	if cfg.NonceTtlMinutes <= 0 || cfg.SessionTtlMinutes <= 0 {
+		return authservice.Settings{}, errors.New("auth.nonce_ttl_minutes and auth.session_ttl_minutes must be positive")
+	}
+	if cfg.Domain == "" {
+		return authservice.Settings{}, errors.New("auth.domain is empty")
+	}
+	return authservice.Settings{
+		Domain:     cfg.Domain,
+		ChainID:    globalconst.ChainId,
+		JwtSecret:  []byte(secret),
+		NonceTtl:   time.Duration(cfg.NonceTtlMinutes) * time.Minute,
+		SessionTtl: time.Duration(cfg.SessionTtlMinutes) * time.Minute,
+	}, nil
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"testing"
+
+	"go-project/main/config"
+)
+
+func TestNewAuthSettings(t *testing.T) {
+	t.Setenv("TEST_JWT_SECRET", "")
+	base := config.AuthConfig{
+		Domain:            "localhost:8888",
+		JwtSecret:         "local-dev-jwt-secret-change-me",
+		JwtSecretEnv:      "TEST_JWT_SECRET",
+		NonceTtlMinutes:   5,
+		SessionTtlMinutes: 15,
+	}
+
+	if _, err := newAuthSettings(base, "local"); err != nil {
+		t.Fatalf("Expected the file secret to be accepted locally, got %v", err)
+	}
+	if _, err := newAuthSettings(base, "prod"); err == nil {
+		t.Fatal("Expected the file secret to be refused outside local")
+	}
+
+	noSession := base
+	noSession.SessionTtlMinutes = 0
+	if _, err := newAuthSettings(noSession, "local"); err == nil {
+		t.Fatal("Expected session_ttl_minutes 0 to be refused")
+	}
+
+	t.Setenv("TEST_JWT_SECRET", "a-secret-from-the-environment")
+	settings, err := newAuthSettings(base, "prod")
+	if err != nil {
+		t.Fatalf("Expected the env secret to be accepted, got %v", err)
+	}
+	if string(settings.JwtSecret) != "a-secret-from-the-environment" {
+		t.Fatalf("Expected the env secret, got %q", settings.JwtSecret)
+	}
+}
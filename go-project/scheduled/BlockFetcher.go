@@ -0,0 +1,214 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"go.uber.org/zap"
+
+	"go-project/chain/eth"
+	"go-project/main/log"
+)
+
+const (
+	defaultFetchWorkers   = 4
+	defaultFetchBatchSize = 20
+
+	// methodNotFoundCode is the JSON-RPC error code for unknown methods
+	methodNotFoundCode = -32601
+)
+
+type fetchedBlock struct {
+	block    *types.Block
+	receipts map[common.Hash]*types.Receipt
+}
+
+type fetchedChunk struct {
+	blocks []*fetchedBlock
+	err    error
+	done   chan struct{}
+}
+
+// BlockFetcher downloads blocks and receipts with a bounded pool of workers,
+// each worker pulling one chunk of consecutive heights per JSON-RPC batch.
+// Results are handed to the caller strictly in block number order.
+type BlockFetcher struct {
+	ethClient eth.EthClient
+	workers   int
+	batchSize int
+	log       *log.ZapLogger
+
+	blockReceiptsUnsupported atomic.Bool
+}
+
+func NewBlockFetcher(client eth.EthClient, workers, batchSize int, log *log.ZapLogger) *BlockFetcher {
+	if workers <= 0 {
+		workers = defaultFetchWorkers
+	}
+	if batchSize <= 0 {
+		batchSize = defaultFetchBatchSize
+	}
+	return &BlockFetcher{
+		ethClient: client,
+		workers:   workers,
+		batchSize: batchSize,
+		log:       log,
+	}
+}
+
+// Fetch downloads every header's block and calls commit for each of them in
+// ascending order. It stops at the first fetch or commit error.
+func (f *BlockFetcher) Fetch(ctx context.Context, headers []*types.Header, commit func(*fetchedBlock) error) error {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	var chunks []*fetchedChunk
+	var chunkHeaders [][]*types.Header
+	for start := 0; start < len(headers); start += f.batchSize {
+		end := start + f.batchSize
+		if end > len(headers) {
+			end = len(headers)
+		}
+		chunks = append(chunks, &fetchedChunk{done: make(chan struct{})})
+		chunkHeaders = append(chunkHeaders, headers[start:end])
+	}
+
+	jobs := make(chan int)
+	for i := 0; i < f.workers && i < len(chunks); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				chunks[index].blocks, chunks[index].err = f.fetchChunk(ctx, chunkHeaders[index])
+				close(chunks[index].done)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for index := range chunks {
+			select {
+			case jobs <- index:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, chunk := range chunks {
+		select {
+		case <-chunk.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if chunk.err != nil {
+			return chunk.err
+		}
+		for _, block := range chunk.blocks {
+			if err := commit(block); err != nil {
+				return err
+			}
+		}
+		// release the chunk as soon as it is committed
+		chunk.blocks = nil
+	}
+
+	return nil
+}
+
+func (f *BlockFetcher) fetchChunk(ctx context.Context, headers []*types.Header) ([]*fetchedBlock, error) {
+	numbers := make([]*big.Int, len(headers))
+	for i, header := range headers {
+		numbers[i] = header.Number
+	}
+
+	blocks, err := f.ethClient.BlockListByNumbers(ctx, numbers)
+	if err != nil {
+		return nil, fmt.Errorf("批量获取区块失败 (%s-%s): %w", numbers[0], numbers[len(numbers)-1], err)
+	}
+
+	result := make([]*fetchedBlock, len(blocks))
+	for i, block := range blocks {
+		// the header list was validated for reorgs, the block must match it
+		if block.Hash() != headers[i].Hash() {
+			return nil, fmt.Errorf("区块 %d 哈希与区块头不一致", block.NumberU64())
+		}
+		result[i] = &fetchedBlock{
+			block:    block,
+			receipts: make(map[common.Hash]*types.Receipt, len(block.Transactions())),
+		}
+	}
+
+	receipts, err := f.fetchReceipts(ctx, numbers, blocks)
+	if err != nil {
+		return nil, err
+	}
+	for _, receipt := range receipts {
+		for _, fetched := range result {
+			if fetched.block.Hash() == receipt.BlockHash {
+				fetched.receipts[receipt.TxHash] = receipt
+				break
+			}
+		}
+	}
+
+	for _, fetched := range result {
+		if len(fetched.receipts) != len(fetched.block.Transactions()) {
+			return nil, fmt.Errorf("区块 %d 收据数量不一致: %d/%d", fetched.block.NumberU64(), len(fetched.receipts), len(fetched.block.Transactions()))
+		}
+	}
+
+	return result, nil
+}
+
+// fetchReceipts prefers eth_getBlockReceipts and falls back to batched
+// eth_getTransactionReceipt, permanently once the node reports the method
+// as unsupported.
+func (f *BlockFetcher) fetchReceipts(ctx context.Context, numbers []*big.Int, blocks []*types.Block) ([]*types.Receipt, error) {
+	if !f.blockReceiptsUnsupported.Load() {
+		blockReceipts, err := f.ethClient.BlockReceiptsByNumbers(ctx, numbers)
+		if err == nil {
+			var receipts []*types.Receipt
+			for _, list := range blockReceipts {
+				receipts = append(receipts, list...)
+			}
+			return receipts, nil
+		}
+		f.log.Error("eth_getBlockReceipts 失败，改用 eth_getTransactionReceipt", zap.Error(err))
+		var rpcErr gethrpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+			f.blockReceiptsUnsupported.Store(true)
+		}
+	}
+
+	var hashes []common.Hash
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			hashes = append(hashes, tx.Hash())
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	receipts, err := f.ethClient.TxReceiptListByTxHashes(ctx, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("批量获取交易收据失败: %w", err)
+	}
+	return receipts, nil
+}
@@ -0,0 +1,71 @@
+package scheduled
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-project/chain/eth"
+)
+
+// fakeEthClient serves blocks built from a fixed header chain. Only the
+// methods used by BlockFetcher are implemented.
+type fakeEthClient struct {
+	eth.EthClient
+	blocks map[uint64]*types.Block
+}
+
+func newFakeEthClient(count int) (*fakeEthClient, []*types.Header) {
+	client := &fakeEthClient{blocks: make(map[uint64]*types.Block)}
+	headers := make([]*types.Header, count)
+	parent := common.Hash{}
+	for i := 0; i < count; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Difficulty: big.NewInt(0)}
+		headers[i] = header
+		client.blocks[uint64(i)] = types.NewBlockWithHeader(header)
+		parent = header.Hash()
+	}
+	return client, headers
+}
+
+func (c *fakeEthClient) BlockListByNumbers(ctx context.Context, numbers []*big.Int) ([]*types.Block, error) {
+	// random latency so chunks complete out of order
+	time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+	blocks := make([]*types.Block, len(numbers))
+	for i, number := range numbers {
+		blocks[i] = c.blocks[number.Uint64()]
+	}
+	return blocks, nil
+}
+
+func (c *fakeEthClient) BlockReceiptsByNumbers(ctx context.Context, numbers []*big.Int) ([][]*types.Receipt, error) {
+	return make([][]*types.Receipt, len(numbers)), nil
+}
+
+func TestBlockFetcher_FetchCommitsInOrder(t *testing.T) {
+	client, headers := newFakeEthClient(57)
+	fetcher := NewBlockFetcher(client, 4, 5, nil)
+
+	var committed []uint64
+	err := fetcher.Fetch(context.Background(), headers, func(fetched *fetchedBlock) error {
+		committed = append(committed, fetched.block.NumberU64())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if len(committed) != len(headers) {
+		t.Fatalf("Expected %d committed blocks, got %d", len(headers), len(committed))
+	}
+	for i, number := range committed {
+		if number != uint64(i) {
+			t.Fatalf("Block committed out of order at %d: %v", i, committed)
+		}
+	}
+}
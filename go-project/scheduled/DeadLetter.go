@@ -0,0 +1,54 @@
+package scheduled
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	do2 "go-project/business/workflow/do"
+	workflowservice "go-project/business/workflow/service"
+)
+
+// deadLetterExhausted moves unsent payouts that used up their retries, e.g.
+// through expired leases or dropped transactions, to the dead letter status
+// and fails their workflow, so an operator can retry or cancel them.
+func (s *ProcessingFLow) deadLetterExhausted() {
+	exhausted, err := do.NewTokenTransferLogManager(s.db).ListExhausted(maxTransferRetry, s.batchSize)
+	if err != nil {
+		s.log.Error("processingFLow ListExhausted", zap.Error(err))
+		return
+	}
+	for i := range exhausted {
+		if err := s.deadLetter(&exhausted[i]); err != nil {
+			s.log.Error("转账移入死信队列失败", zap.Error(err), zap.Int("LogID", exhausted[i].ID))
+		}
+	}
+}
+
+func (s *ProcessingFLow) deadLetter(transferLog *do.TokenTransferLog) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		moved, err := do.NewTokenTransferLogManager(tx).MoveStatus(transferLog.ID, []string{do.StatusPending}, do.StatusDeadLetter, map[string]interface{}{
+			"updated_by":   processingFlowOperator,
+			"updated_addr": "system",
+		})
+		if err != nil || !moved {
+			return err
+		}
+		transferLog.Status = do.StatusDeadLetter
+		if err := do.NewTokenTransferAttemptManager(tx).Create(do.NewTokenTransferAttempt(transferLog, do.AttemptOutcomeDeadLetter, processingFlowOperator)); err != nil {
+			return err
+		}
+		s.log.Error("转账重试次数用尽，已进入死信队列", zap.Int("LogID", transferLog.ID), zap.Int("RetryCount", transferLog.RetryCount),
+			zap.String("FailureReason", transferLog.FailureReason))
+		err = workflowservice.TransitionByID(tx, transferLog.WorkflowID, do2.WorkFlowStatusFailed, processingFlowOperator, transferLog.FailureReason)
+		var illegal *workflowservice.IllegalTransitionError
+		if errors.Is(err, workflowservice.WorkflowNotFoundError) || errors.As(err, &illegal) {
+			// the payout is dead-lettered all the same
+			s.log.Error("工作流状态无法更新", zap.Int("WorkflowID", transferLog.WorkflowID), zap.Error(err))
+			return nil
+		}
+		return err
+	})
+}
@@ -0,0 +1,278 @@
+package scheduled
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	do2 "go-project/business/workflow/do"
+	"go-project/chain/eth"
+)
+
+// groupForDisperse groups claimed payouts by token for batch mode. Native
+// payouts, payouts with operator overrides and tokens with a single payout
+// are returned to be sent one by one.
+func groupForDisperse(pendingLogs []do.TokenTransferLog) ([][]do.TokenTransferLog, []do.TokenTransferLog) {
+	var groups [][]do.TokenTransferLog
+	var singles []do.TokenTransferLog
+	index := make(map[string]int)
+	for _, pendingLog := range pendingLogs {
+		if pendingLog.IsNative() || hasTransferOverrides(&pendingLog) {
+			singles = append(singles, pendingLog)
+			continue
+		}
+		token := strings.ToLower(pendingLog.ContractAddress)
+		i, ok := index[token]
+		if !ok {
+			i = len(groups)
+			index[token] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], pendingLog)
+	}
+
+	batches := groups[:0]
+	for _, group := range groups {
+		if len(group) < 2 {
+			singles = append(singles, group...)
+			continue
+		}
+		batches = append(batches, group)
+	}
+	return batches, singles
+}
+
+func hasTransferOverrides(pendingLog *do.TokenTransferLog) bool {
+	return pendingLog.GasLimitOverride != 0 || pendingLog.MaxFeePerGasOverride != "" || pendingLog.PriorityFeeOverride != ""
+}
+
+// dispatchBatch pays claimed ERC-20 payouts of one token in a single call
+// of the disperse contract. It returns the payouts to send one by one
+// instead, e.g. all of them when the batch can not be signed because the
+// contract lacks allowance.
+func (s *ProcessingFLow) dispatchBatch(group []do.TokenTransferLog) []do.TokenTransferLog {
+	tokenTransferLogManager := do.NewTokenTransferLogManager(s.db)
+	workflowManager := do2.NewWorkFlowInfoManager(s.db)
+
+	var batchLogs, singles []do.TokenTransferLog
+	var payments []eth.DispersePayment
+	for _, pendingLog := range group {
+		owned, err := tokenTransferLogManager.RenewLease(pendingLog.ID, s.owner, time.Now().Add(s.lease))
+		if err != nil || !owned {
+			s.log.Error("转账租约已丢失", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+			continue
+		}
+		stopRenewing := s.keepLease(pendingLog.ID)
+		defer stopRenewing()
+
+		// 异常的转账单独发送，由单笔流程记录失败原因
+		workflow, err := workflowManager.GetByID(pendingLog.WorkflowID)
+		amount, ok := new(big.Int).SetString(pendingLog.Amount, 10)
+		if err != nil || workflow == nil || !ok || amount.Sign() <= 0 {
+			singles = append(singles, pendingLog)
+			continue
+		}
+		pendingLog.ToAddress = workflow.ToAddr
+		batchLogs = append(batchLogs, pendingLog)
+		payments = append(payments, eth.DispersePayment{To: common.HexToAddress(workflow.ToAddr), Amount: amount})
+	}
+	if len(batchLogs) < 2 {
+		return append(singles, batchLogs...)
+	}
+
+	contractAddress := batchLogs[0].ContractAddress
+	result, err := s.business.PrepareDisperseERC20(s.ctx, s.disperse, contractAddress, payments, eth.TransferOptions{})
+	if err != nil {
+		if errors.Is(err, eth.DisperseAllowanceError) {
+			s.log.Error("批量转账合约授权不足，改为逐笔转账", zap.Error(err), zap.String("token", contractAddress))
+		} else {
+			s.log.Error("签名批量转账失败，改为逐笔转账", zap.Error(err), zap.String("token", contractAddress))
+		}
+		return append(singles, batchLogs...)
+	}
+
+	fromAddress := s.business.SignerAddress()
+	transferBatch := &do.TokenTransferBatch{
+		TokenInfoID:          batchLogs[0].TokenInfoID,
+		ContractAddress:      contractAddress,
+		DisperseAddress:      s.disperse.Hex(),
+		FromAddress:          fromAddress.Hex(),
+		TxHash:               result.TxHash,
+		PayoutCount:          len(batchLogs),
+		TotalAmount:          eth.DisperseTotal(payments).String(),
+		Nonce:                result.Nonce,
+		GasLimit:             result.GasLimit,
+		MaxFeePerGas:         bigString(result.MaxFeePerGas),
+		MaxPriorityFeePerGas: bigString(result.MaxPriorityFeePerGas),
+		RawTx:                result.RawTx,
+		Status:               do.BatchStatusSigned,
+		CreatedTime:          time.Now(),
+		UpdatedTime:          time.Now(),
+	}
+	for i := range batchLogs {
+		batchLogs[i].FromAddress = fromAddress.Hex()
+		batchLogs[i].TransactionHash = result.TxHash
+		batchLogs[i].TransferData = "" // the batch call data is kept on the transaction
+		batchLogs[i].GasLimit = result.GasLimit
+		batchLogs[i].MaxFeePerGas = transferBatch.MaxFeePerGas
+		batchLogs[i].MaxPriorityFeePerGas = transferBatch.MaxPriorityFeePerGas
+	}
+
+	if err := s.saveSignedBatch(transferBatch, batchLogs, fromAddress); err != nil {
+		s.log.Error("保存已签名批量交易失败", zap.Error(err), zap.String("TxHash", result.TxHash))
+		s.business.Discard(result)
+		for i := range batchLogs {
+			batchLogs[i].TransactionHash = ""
+			batchLogs[i].BatchID = 0
+			s.unclaim(&batchLogs[i])
+		}
+		return singles
+	}
+
+	// 交易已保存，广播失败或进程崩溃都由恢复任务重新广播
+	outcome := do.AttemptOutcomeBroadcast
+	failureReason := ""
+	if err := s.business.Broadcast(s.ctx, result); err != nil {
+		s.log.Error("广播批量交易失败，等待重新广播", zap.Error(err), zap.String("TxHash", result.TxHash))
+		outcome = do.AttemptOutcomeBroadcastFailed
+		failureReason = err.Error()
+	} else {
+		s.log.Info("批量转账已广播", zap.String("TxHash", result.TxHash), zap.Int("count", len(batchLogs)))
+		if err := do.NewTokenTransferBatchManager(s.db).MarkBroadcast(result.TxHash); err != nil {
+			s.log.Error("更新批量交易状态失败", zap.Error(err), zap.String("TxHash", result.TxHash))
+		}
+	}
+
+	// 等待扫块按Transfer事件逐笔结算
+	for i := range batchLogs {
+		batchLogs[i].Status = do.StatusPending
+		batchLogs[i].FailureReason = failureReason
+		if err := s.finish(&batchLogs[i], fromAddress, outcome); err != nil {
+			s.log.Error("更新转账日志状态失败", zap.Error(err), zap.Int("LogID", batchLogs[i].ID))
+		}
+	}
+	return singles
+}
+
+// saveSignedBatch persists a signed batch together with its payouts before
+// it is broadcast.
+func (s *ProcessingFLow) saveSignedBatch(transferBatch *do.TokenTransferBatch, batchLogs []do.TokenTransferLog, fromAddress common.Address) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := do.NewTokenTransferBatchManager(tx).Create(transferBatch); err != nil {
+			return err
+		}
+		tokenTransferLogManager := do.NewTokenTransferLogManager(tx)
+		for i := range batchLogs {
+			batchLogs[i].BatchID = transferBatch.ID
+			batchLogs[i].UpdatedBy = fromAddress.Hex()
+			batchLogs[i].UpdatedAddr = fromAddress.Hex()
+			batchLogs[i].UpdatedTime = time.Now()
+			owned, err := tokenTransferLogManager.SaveSigned(&batchLogs[i], s.owner)
+			if err != nil {
+				return err
+			}
+			if !owned {
+				return fmt.Errorf("lease of transfer log %d lost", batchLogs[i].ID)
+			}
+		}
+		return nil
+	})
+}
+
+// recoverSignedBatches rebroadcasts unsettled batches that are in one of
+// statuses, like recoverSignedTxs does for single payouts.
+func (s *ProcessingFLow) recoverSignedBatches(statuses ...string) {
+	batches, err := do.NewTokenTransferBatchManager(s.db).ListUnconfirmed(statuses, recoveryBatchSize)
+	if err != nil {
+		s.log.Error("查询未确认批量交易失败", zap.Error(err))
+		return
+	}
+	for i := range batches {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err := s.rebroadcastBatch(&batches[i]); err != nil {
+			s.log.Error("重新广播批量交易失败", zap.Error(err), zap.String("TxHash", batches[i].TxHash))
+		}
+	}
+}
+
+func (s *ProcessingFLow) rebroadcastBatch(transferBatch *do.TokenTransferBatch) error {
+	batchManager := do.NewTokenTransferBatchManager(s.db)
+	mined, err := s.txMined(transferBatch.TxHash)
+	if err != nil {
+		return err
+	}
+	if mined {
+		// 已上链，由扫块结算
+		return batchManager.MarkBroadcast(transferBatch.TxHash)
+	}
+
+	err = s.business.Broadcast(s.ctx, &eth.TransferResult{
+		TxHash: transferBatch.TxHash,
+		RawTx:  transferBatch.RawTx,
+		Nonce:  transferBatch.Nonce,
+	})
+	if errors.Is(err, eth.NonceTooLowError) {
+		return s.dropBatch(transferBatch)
+	}
+	if err != nil {
+		return err
+	}
+	s.log.Info("已重新广播批量交易", zap.String("TxHash", transferBatch.TxHash))
+	return batchManager.MarkBroadcast(transferBatch.TxHash)
+}
+
+// dropBatch re-queues the payouts of a batch whose nonce went to another
+// transaction, each to be sent again. Batches are never replaced, so
+// without a receipt of their own none of the payouts was paid. The receipt
+// is checked again, the batch itself may have taken the nonce since it was
+// last checked. Payouts still held by a dispatcher are re-queued once their
+// lease expires, the batch is only marked dropped when none is left.
+func (s *ProcessingFLow) dropBatch(transferBatch *do.TokenTransferBatch) error {
+	mined, err := s.txMined(transferBatch.TxHash)
+	if err != nil {
+		return err
+	}
+	if mined {
+		// 批量交易已上链，由扫块结算
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		transferLogManager := do.NewTokenTransferLogManager(tx)
+		pendingLogs, err := transferLogManager.ListUnsettledByBatch(transferBatch.ID, transferBatch.TxHash)
+		if err != nil {
+			return err
+		}
+		remaining := len(pendingLogs)
+		for i := range pendingLogs {
+			requeued, err := transferLogManager.Requeue(pendingLogs[i].ID, transferBatch.TxHash, droppedReason)
+			if err != nil {
+				return err
+			}
+			if !requeued {
+				continue
+			}
+			remaining--
+			pendingLogs[i].RetryCount++
+			pendingLogs[i].FailureReason = droppedReason
+			attempt := do.NewTokenTransferAttempt(&pendingLogs[i], do.AttemptOutcomeDropped, processingFlowOperator)
+			if err := do.NewTokenTransferAttemptManager(tx).Create(attempt); err != nil {
+				return err
+			}
+		}
+		if remaining > 0 {
+			return nil
+		}
+		s.log.Error("批量交易已被丢弃，转账重新排队", zap.String("TxHash", transferBatch.TxHash), zap.Int("count", len(pendingLogs)))
+		return do.NewTokenTransferBatchManager(tx).MarkDropped(transferBatch.TxHash)
+	})
+}
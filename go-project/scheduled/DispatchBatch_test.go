@@ -0,0 +1,78 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-project/business/token/do"
+	"go-project/chain/eth"
+)
+
+func TestGroupForDisperse(t *testing.T) {
+	tokenA := "0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35"
+	tokenB := "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+	pendingLogs := []do.TokenTransferLog{
+		{ID: 1, ContractAddress: tokenA},
+		{ID: 2, ContractAddress: do.NativeTokenAddress},
+		{ID: 3, ContractAddress: tokenB},
+		{ID: 4, ContractAddress: tokenA},
+		{ID: 5, ContractAddress: tokenA, GasLimitOverride: 100000},
+		{ID: 6, ContractAddress: tokenA},
+	}
+
+	groups, singles := groupForDisperse(pendingLogs)
+	if len(groups) != 1 || len(groups[0]) != 3 {
+		t.Fatalf("Expected one batch of 3 payouts, got %v", groups)
+	}
+	for i, id := range []int{1, 4, 6} {
+		if groups[0][i].ID != id {
+			t.Fatalf("Expected payout %d at %d of the batch, got %d", id, i, groups[0][i].ID)
+		}
+	}
+	// native, overridden and lone payouts are sent one by one
+	if len(singles) != 3 {
+		t.Fatalf("Expected 3 single payouts, got %v", singles)
+	}
+}
+
+// minedAfterBroadcastClient reports no receipt until a broadcast was
+// rejected with nonce too low, as when the batch is mined in between.
+type minedAfterBroadcastClient struct {
+	eth.EthClient
+	broadcasts int
+}
+
+func (c *minedAfterBroadcastClient) TxReceiptByTxHash(hash common.Hash) (*types.Receipt, error) {
+	if c.broadcasts == 0 {
+		return nil, ethereum.NotFound
+	}
+	return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful}, nil
+}
+
+func (c *minedAfterBroadcastClient) SendRawTransaction(rawTx string) error {
+	c.broadcasts++
+	return errors.New("nonce too low: next nonce 8, tx nonce 7")
+}
+
+func TestRebroadcastBatch_MinedAfterNonceTooLow(t *testing.T) {
+	client := &minedAfterBroadcastClient{}
+	flow := &ProcessingFLow{
+		ctx:       context.Background(),
+		ethClient: client,
+		business:  eth.NewEthBusinessService(client, nil, nil, nil, eth.FeeConfig{}, nil),
+		// no database: a mined batch must not touch its payouts
+	}
+
+	transferBatch := &do.TokenTransferBatch{ID: 1, TxHash: common.HexToHash("0x01").Hex(), RawTx: "0x02", Nonce: 7}
+	if err := flow.rebroadcastBatch(transferBatch); err != nil {
+		t.Fatalf("rebroadcastBatch failed: %v", err)
+	}
+	if client.broadcasts != 1 {
+		t.Fatalf("Expected one broadcast, got %d", client.broadcasts)
+	}
+}
@@ -2,28 +2,78 @@ package scheduled
 
 import (
 	"context"
+	"fmt"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 
+	"go-project/business/workflow/do"
 	"go-project/chain/eth"
 	"go-project/main/log"
 )
 
+const (
+	HeadModeFinalized    = "finalized"
+	HeadModeConfirmation = "confirmation"
+
+	defaultEthFinalizeNum = 64
+)
+
+// EthSlot decides which block the scanner may safely index up to. In
+// confirmation mode that is latest minus eth_finalize_num confirmations,
+// which also works on anvil where the finalized tag is not meaningful.
 type EthSlot struct {
 	ctx       context.Context
 	ethClient eth.EthClient
 	db        *gorm.DB
 	log       *log.ZapLogger
+	headMode  string
 }
 
-func NewEthSlot(ctx context.Context, client eth.EthClient, db *gorm.DB, log *log.ZapLogger) (*EthSlot, error) {
+func NewEthSlot(ctx context.Context, client eth.EthClient, db *gorm.DB, log *log.ZapLogger, headMode string) (*EthSlot, error) {
+	switch headMode {
+	case "":
+		headMode = HeadModeConfirmation
+	case HeadModeFinalized, HeadModeConfirmation:
+	default:
+		return nil, fmt.Errorf("unknown scan head mode: %s", headMode)
+	}
+
 	return &EthSlot{
 		ctx:       ctx,
 		ethClient: client,
 		db:        db,
 		log:       log,
+		headMode:  headMode,
 	}, nil
 }
 
-// slot 32 64
-// change scan all block to LatestFinalizedBlockHeader
+// SafeBlockHeader returns the newest header the scanner is allowed to index,
+// or nil when the chain is not yet deeper than the confirmation depth.
+func (s *EthSlot) SafeBlockHeader() (*types.Header, error) {
+	if s.headMode == HeadModeFinalized {
+		return s.ethClient.LatestFinalizedBlockHeader()
+	}
+
+	confirmations, err := do.NewWorkflowConfigurationManager(s.db).GetUint64(do.ConfigCodeEthFinalizeNum, defaultEthFinalizeNum)
+	if err != nil {
+		return nil, fmt.Errorf("读取确认数配置失败: %w", err)
+	}
+
+	latest, err := s.ethClient.BlockHeaderByNumber(nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新区块头失败: %w", err)
+	}
+	if latest.Number.Uint64() < confirmations {
+		s.log.Info("链高度不足确认数", zap.Uint64("latest", latest.Number.Uint64()), zap.Uint64("confirmations", confirmations))
+		return nil, nil
+	}
+	if confirmations == 0 {
+		return latest, nil
+	}
+
+	safeNumber := new(big.Int).Sub(latest.Number, new(big.Int).SetUint64(confirmations))
+	return s.ethClient.BlockHeaderByNumber(safeNumber)
+}
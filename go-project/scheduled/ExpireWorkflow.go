@@ -0,0 +1,72 @@
+package scheduled
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	idempotencyservice "go-project/business/idempotency/service"
+	"go-project/business/workflow/service"
+	"go-project/main/log"
+)
+
+const (
+	defaultExpireAfter        = 72 * time.Hour
+	expireWorkflowLimit       = 100
+	deleteIdempotencyKeyLimit = 1000
+)
+
+// WorkflowExpirer moves workflows nobody decided on within expireAfter to
+// expired. It also deletes idempotency keys past their replay window.
+type WorkflowExpirer struct {
+	ctx         context.Context
+	db          *gorm.DB
+	log         *log.ZapLogger
+	expireAfter time.Duration
+}
+
+func NewWorkflowExpirer(ctx context.Context, db *gorm.DB, log *log.ZapLogger, expireAfter time.Duration) (*WorkflowExpirer, error) {
+	if expireAfter <= 0 {
+		expireAfter = defaultExpireAfter
+	}
+	return &WorkflowExpirer{
+		ctx:         ctx,
+		db:          db,
+		log:         log,
+		expireAfter: expireAfter,
+	}, nil
+}
+
+func (s *WorkflowExpirer) Start() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			fmt.Println("WorkflowExpirer done")
+			return
+		case <-ticker.C:
+			expired, err := service.NewService(s.log, s.db).ExpirePendingWorkflows(time.Now().Add(-s.expireAfter), expireWorkflowLimit)
+			if err != nil {
+				s.log.Error("工作流过期处理失败", zap.Error(err))
+				continue
+			}
+			if expired > 0 {
+				s.log.Info("工作流已过期", zap.Int("count", expired))
+			}
+
+			deleted, err := idempotencyservice.NewStore(s.log, s.db).DeleteExpired(time.Now(), deleteIdempotencyKeyLimit)
+			if err != nil {
+				s.log.Error("清理过期幂等键失败", zap.Error(err))
+				continue
+			}
+			if deleted > 0 {
+				s.log.Info("已清理过期幂等键", zap.Int64("count", deleted))
+			}
+		}
+	}
+}
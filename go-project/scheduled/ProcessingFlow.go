@@ -2,44 +2,108 @@ package scheduled
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/crypto"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 
 	"go-project/business/token/do"
 	do2 "go-project/business/workflow/do"
+	workflowservice "go-project/business/workflow/service"
 	"go-project/chain/eth"
-	globalconst "go-project/common"
 	"go-project/main/log"
 )
 
+// maxTransferRetry is how many failed sends a payout gets before it is
+// dead-lettered for an operator.
+const maxTransferRetry = 3
+
+const (
+	defaultDispatchLease     = 2 * time.Minute
+	defaultDispatchBatchSize = 10
+)
+
+// processingFlowOperator is recorded as operator of the dispatcher's
+// changes.
+const processingFlowOperator = "ProcessingFLow"
+
+// ProcessingFLow dispatches approved payouts. Payouts are claimed under a
+// lease so several replicas can run it against the same database. With a
+// disperse contract configured, ERC-20 payouts of the same token are paid
+// in one batch transaction.
 type ProcessingFLow struct {
-	ctx         context.Context
-	ethClient   eth.EthClient
-	erc20Client eth.TestErc20Client
-	db          *gorm.DB
-	log         *log.ZapLogger
+	ctx       context.Context
+	ethClient eth.EthClient
+	tokens    *eth.TokenRegistry
+	business  *eth.BusinessService
+	db        *gorm.DB
+	log       *log.ZapLogger
+	owner     string
+	lease     time.Duration
+	batchSize int
+	disperse  common.Address // zero when batch mode is off
 }
 
-func NewProcessingFLow(ctx context.Context, client eth.EthClient, erc20Client eth.TestErc20Client, db *gorm.DB, log *log.ZapLogger) (*ProcessingFLow, error) {
+func NewProcessingFLow(ctx context.Context, client eth.EthClient, tokens *eth.TokenRegistry, business *eth.BusinessService, db *gorm.DB, log *log.ZapLogger,
+	lease time.Duration, batchSize int, disperseAddress string) (*ProcessingFLow, error) {
+	if lease <= 0 {
+		lease = defaultDispatchLease
+	}
+	if batchSize <= 0 {
+		batchSize = defaultDispatchBatchSize
+	}
+	owner, err := dispatcherID()
+	if err != nil {
+		return nil, err
+	}
+	var disperse common.Address
+	if disperseAddress != "" {
+		if !common.IsHexAddress(disperseAddress) {
+			return nil, fmt.Errorf("invalid disperse address %q", disperseAddress)
+		}
+		disperse = common.HexToAddress(disperseAddress)
+	}
 	return &ProcessingFLow{
-		ctx:         ctx,
-		ethClient:   client,
-		erc20Client: erc20Client,
-		db:          db,
-		log:         log,
+		ctx:       ctx,
+		ethClient: client,
+		tokens:    tokens,
+		business:  business,
+		db:        db,
+		log:       log,
+		owner:     owner,
+		lease:     lease,
+		batchSize: batchSize,
+		disperse:  disperse,
 	}, nil
 }
 
+// dispatcherID names this process as lease owner.
+func dispatcherID() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	random := make([]byte, 4)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), hex.EncodeToString(random)), nil
+}
+
 func (s *ProcessingFLow) Start() {
+	// 启动时先重新广播上次运行保存但可能未发出的交易
+	s.recoverSignedTxs(do.TxStatusSigned, do.TxStatusBroadcast)
+	s.recoverSignedBatches(do.BatchStatusSigned, do.BatchStatusBroadcast)
+
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -59,10 +123,17 @@ func (s *ProcessingFLow) Start() {
 }
 
 func (s *ProcessingFLow) processingFLow() error {
+	s.recoverExpiredLeases()
+	s.recoverSignedTxs(do.TxStatusSigned)
+	// broadcast batches too: batches are never replaced, one whose block was
+	// orphaned is only recovered here
+	s.recoverSignedBatches(do.BatchStatusSigned, do.BatchStatusBroadcast)
+	s.deadLetterExhausted()
+
 	tokenTransferLogManager := do.NewTokenTransferLogManager(s.db)
-	pendingLogList, err := tokenTransferLogManager.GetPendingTokenTransferLogs()
+	pendingLogList, err := tokenTransferLogManager.ClaimPending(s.owner, time.Now().Add(s.lease), maxTransferRetry, s.batchSize)
 	if err != nil {
-		s.log.Error("processingFLow GetPendingTokenTransferLogs", zap.Error(err))
+		s.log.Error("processingFLow ClaimPending", zap.Error(err))
 		return err
 	}
 	if len(pendingLogList) <= 0 {
@@ -70,81 +141,290 @@ func (s *ProcessingFLow) processingFLow() error {
 		return nil
 	}
 	pendingLogListJson, _ := json.Marshal(pendingLogList)
-	s.log.Info("current deal pendingLogList", zap.Any("pendingLogList", string(pendingLogListJson)))
+	s.log.Info("current deal pendingLogList", zap.String("owner", s.owner), zap.Any("pendingLogList", string(pendingLogListJson)))
 
-	tokenInfoManager := do.NewTokenInfoManager(s.db)
-	tokenInfo, err := tokenInfoManager.GetByID(1)
-	if err != nil {
-		s.log.Error("processingFLow GetByID", zap.Error(err))
-		return err
+	if err := s.business.FillNonceGaps(s.ctx); err != nil {
+		s.log.Error("processingFLow FillNonceGaps", zap.Error(err))
 	}
 
-	businessService := eth.NewEthBusinessService(s.ethClient, s.erc20Client, s.log)
-
-	for _, pendingLog := range pendingLogList {
-		workflowManager := do2.NewWorkFlowInfoManager(s.db)
-		workflow, err := workflowManager.GetByID(pendingLog.WorkflowID)
-		if err != nil {
-			s.log.Error("获取工作流信息失败", zap.Error(err), zap.Int("WorkflowID", pendingLog.WorkflowID))
-			continue
+	singles := pendingLogList
+	if s.disperse != (common.Address{}) {
+		var groups [][]do.TokenTransferLog
+		groups, singles = groupForDisperse(pendingLogList)
+		for _, group := range groups {
+			singles = append(singles, s.dispatchBatch(group)...)
 		}
+	}
+	for _, pendingLog := range singles {
+		s.dispatch(pendingLog)
+	}
 
-		privateKey, err := crypto.HexToECDSA(globalconst.OWNER_PRV_KEY)
+	return nil
+}
+
+// recoverExpiredLeases hands payouts of dispatchers that stopped renewing
+// their lease back to pending.
+func (s *ProcessingFLow) recoverExpiredLeases() {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		recovered, err := do.NewTokenTransferLogManager(tx).RecoverExpiredLeases(time.Now())
 		if err != nil {
-			s.log.Error("解析私钥失败", zap.Error(err))
-			continue
+			return err
+		}
+		attemptManager := do.NewTokenTransferAttemptManager(tx)
+		for i := range recovered {
+			if err := attemptManager.Create(do.NewTokenTransferAttempt(&recovered[i], do.AttemptOutcomeLeaseExpired, processingFlowOperator)); err != nil {
+				return err
+			}
+		}
+		if len(recovered) > 0 {
+			s.log.Info("回收过期租约的转账", zap.Int("count", len(recovered)))
 		}
-		fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+		return nil
+	})
+	if err != nil {
+		s.log.Error("processingFLow RecoverExpiredLeases", zap.Error(err))
+	}
+}
 
-		printERC20Balance(s.ctx, s.erc20Client, fromAddress, "From (after)")
-		printERC20Balance(s.ctx, s.erc20Client, common.HexToAddress(workflow.ToAddr), "To (after)")
+// dispatch sends one claimed payout and records the outcome, as long as
+// this dispatcher still holds its lease.
+func (s *ProcessingFLow) dispatch(pendingLog do.TokenTransferLog) {
+	tokenTransferLogManager := do.NewTokenTransferLogManager(s.db)
+	owned, err := tokenTransferLogManager.RenewLease(pendingLog.ID, s.owner, time.Now().Add(s.lease))
+	if err != nil || !owned {
+		s.log.Error("转账租约已丢失", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+		return
+	}
+	stopRenewing := s.keepLease(pendingLog.ID)
+	defer stopRenewing()
 
-		txHash, transferData, err := businessService.TransferERC20(
+	workflowManager := do2.NewWorkFlowInfoManager(s.db)
+	workflow, err := workflowManager.GetByID(pendingLog.WorkflowID)
+	if err != nil || workflow == nil {
+		s.log.Error("获取工作流信息失败", zap.Error(err), zap.Int("WorkflowID", pendingLog.WorkflowID))
+		s.unclaim(&pendingLog)
+		return
+	}
+	tokenInfo, err := do.NewTokenInfoManager(s.db).GetByID(pendingLog.TokenInfoID)
+	if err != nil || tokenInfo == nil {
+		s.log.Error("获取代币信息失败", zap.Error(err), zap.Int("TokenInfoID", pendingLog.TokenInfoID))
+		s.unclaim(&pendingLog)
+		return
+	}
+	balanceOf, err := s.balanceFunc(tokenInfo)
+	if err != nil {
+		s.log.Error("获取代币客户端失败", zap.Error(err), zap.Int("TokenInfoID", pendingLog.TokenInfoID))
+		s.unclaim(&pendingLog)
+		return
+	}
+
+	fromAddress := s.business.SignerAddress()
+
+	printBalance(s.ctx, balanceOf, fromAddress, "From (before)")
+	printBalance(s.ctx, balanceOf, common.HexToAddress(workflow.ToAddr), "To (before)")
+
+	amount, ok := new(big.Int).SetString(pendingLog.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		s.log.Error("转账金额无效", zap.Int("LogID", pendingLog.ID), zap.String("Amount", pendingLog.Amount))
+		pendingLog.Status = do.StatusFailed
+		pendingLog.FailureReason = fmt.Sprintf("invalid amount %q", pendingLog.Amount)
+		if err := s.finish(&pendingLog, fromAddress, do.AttemptOutcomeFailed); err != nil {
+			s.log.Error("更新转账日志状态失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+		}
+		return
+	}
+
+	var result *eth.TransferResult
+	if tokenInfo.IsNative() {
+		result, err = s.business.PrepareNativeTransfer(s.ctx, workflow.ToAddr, amount, transferOptions(&pendingLog))
+	} else {
+		result, err = s.business.PrepareERC20Transfer(
 			s.ctx,
-			privateKey,
-			fromAddress.Hex(),
 			workflow.ToAddr,
 			tokenInfo.ContractAddress,
-			new(big.Int).SetUint64(123456),
+			amount,
+			transferOptions(&pendingLog),
 		)
+	}
 
-		if err != nil {
-			s.log.Error("ERC20转账失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
-			if errors.Is(err, eth.InsufficientBalanceError) {
-				s.log.Error("余额不足", zap.Int("LogID", pendingLog.ID))
-				pendingLog.Status = do.StatusFailed
-			} else {
-				pendingLog.RetryCount++
-				pendingLog.Status = do.StatusPending
-			}
+	pendingLog.FromAddress = fromAddress.Hex()
+	pendingLog.ToAddress = workflow.ToAddr
+	pendingLog.ContractAddress = tokenInfo.ContractAddress
+
+	if err != nil {
+		s.log.Error("签名转账失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+		if errors.Is(err, eth.InsufficientBalanceError) {
+			s.log.Error("余额不足", zap.Int("LogID", pendingLog.ID))
+			pendingLog.Status = do.StatusFailed
+			pendingLog.FailureReason = "insufficient balance"
 		} else {
-			s.log.Info("ERC20转账成功", zap.Int("LogID", pendingLog.ID), zap.String("TxHash", txHash))
+			pendingLog.RetryCount++
 			pendingLog.Status = do.StatusPending
-			pendingLog.TransactionHash = txHash
+			pendingLog.FailureReason = err.Error()
+			if pendingLog.RetryCount > maxTransferRetry {
+				s.log.Error("转账重试次数用尽，已进入死信队列", zap.Int("LogID", pendingLog.ID), zap.Int("RetryCount", pendingLog.RetryCount))
+				pendingLog.Status = do.StatusDeadLetter
+			}
+		}
+		if err := s.finish(&pendingLog, fromAddress, do.AttemptOutcomeFailed); err != nil {
+			s.log.Error("更新转账日志状态失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+		}
+		return
+	}
+
+	pendingLog.TransactionHash = result.TxHash
+	pendingLog.TransferData = hexutil.Encode(result.Data)
+	pendingLog.GasLimit = result.GasLimit
+	pendingLog.MaxFeePerGas = bigString(result.MaxFeePerGas)
+	pendingLog.MaxPriorityFeePerGas = bigString(result.MaxPriorityFeePerGas)
+
+	if err := s.saveSigned(&pendingLog, result, fromAddress); err != nil {
+		s.log.Error("保存已签名交易失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+		s.business.Discard(result)
+		pendingLog.TransactionHash = ""
+		s.unclaim(&pendingLog)
+		return
+	}
+
+	// 交易已保存，广播失败或进程崩溃都由恢复任务重新广播，不会重复转账
+	outcome := do.AttemptOutcomeBroadcast
+	pendingLog.FailureReason = ""
+	if err := s.business.Broadcast(s.ctx, result); err != nil {
+		s.log.Error("广播交易失败，等待重新广播", zap.Error(err), zap.Int("LogID", pendingLog.ID), zap.String("TxHash", result.TxHash))
+		outcome = do.AttemptOutcomeBroadcastFailed
+		pendingLog.FailureReason = err.Error()
+	} else {
+		s.log.Info("转账已广播", zap.Int("LogID", pendingLog.ID), zap.String("TxHash", result.TxHash))
+		if err := do.NewTokenTransferTxManager(s.db).MarkBroadcast(result.TxHash); err != nil {
+			s.log.Error("更新交易状态失败", zap.Error(err), zap.String("TxHash", result.TxHash))
 		}
+	}
+
+	// 等待扫块结算
+	pendingLog.Status = do.StatusPending
+	if err := s.finish(&pendingLog, fromAddress, outcome); err != nil {
+		s.log.Error("更新转账日志状态失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+	}
+
+	printBalance(s.ctx, balanceOf, fromAddress, "From (after)")
+	printBalance(s.ctx, balanceOf, common.HexToAddress(workflow.ToAddr), "To (after)")
+}
 
-		pendingLog.TransferData = hexutil.Encode(transferData)
-		pendingLog.FromAddress = fromAddress.Hex()
-		pendingLog.ToAddress = workflow.ToAddr
-		pendingLog.ContractAddress = tokenInfo.ContractAddress
-		pendingLog.UpdatedBy = fromAddress.Hex()
-		pendingLog.UpdatedAddr = fromAddress.Hex()
-		pendingLog.UpdatedTime = time.Now()
+// saveSigned persists the signed transaction of a payout before it is
+// broadcast, so it is never signed again for a new nonce after a crash.
+func (s *ProcessingFLow) saveSigned(pendingLog *do.TokenTransferLog, result *eth.TransferResult, fromAddress common.Address) error {
+	pendingLog.UpdatedBy = fromAddress.Hex()
+	pendingLog.UpdatedAddr = fromAddress.Hex()
+	pendingLog.UpdatedTime = time.Now()
 
-		err = tokenTransferLogManager.Update(&pendingLog)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		owned, err := do.NewTokenTransferLogManager(tx).SaveSigned(pendingLog, s.owner)
 		if err != nil {
-			s.log.Error("更新转账日志状态失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
+			return err
+		}
+		if !owned {
+			return fmt.Errorf("lease of transfer log %d lost", pendingLog.ID)
 		}
+		return do.NewTokenTransferTxManager(tx).Create(newTokenTransferTx(pendingLog.ID, fromAddress.Hex(), do.TxTypeOriginal, do.TxStatusSigned, result))
+	})
+}
 
-		printERC20Balance(s.ctx, s.erc20Client, fromAddress, "From (after)")
-		printERC20Balance(s.ctx, s.erc20Client, common.HexToAddress(workflow.ToAddr), "To (after)")
+// finish records the send outcome with its attempt and, for a failed or
+// dead-lettered payout, the workflow transition in one transaction,
+// releasing the lease.
+func (s *ProcessingFLow) finish(pendingLog *do.TokenTransferLog, fromAddress common.Address, outcome string) error {
+	pendingLog.UpdatedBy = fromAddress.Hex()
+	pendingLog.UpdatedAddr = fromAddress.Hex()
+	pendingLog.UpdatedTime = time.Now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		owned, err := do.NewTokenTransferLogManager(tx).FinishSending(pendingLog, s.owner)
+		if err != nil {
+			return err
+		}
+		if !owned {
+			return fmt.Errorf("lease of transfer log %d lost", pendingLog.ID)
+		}
+		attempt := do.NewTokenTransferAttempt(pendingLog, outcome, processingFlowOperator)
+		if err := do.NewTokenTransferAttemptManager(tx).Create(attempt); err != nil {
+			return err
+		}
+		if pendingLog.Status == do.StatusFailed || pendingLog.Status == do.StatusDeadLetter {
+			return workflowservice.TransitionByID(tx, pendingLog.WorkflowID, do2.WorkFlowStatusFailed, processingFlowOperator, pendingLog.FailureReason)
+		}
+		return nil
+	})
+}
+
+// unclaim hands a payout that could not be sent back to pending.
+func (s *ProcessingFLow) unclaim(pendingLog *do.TokenTransferLog) {
+	pendingLog.Status = do.StatusPending
+	if _, err := do.NewTokenTransferLogManager(s.db).FinishSending(pendingLog, s.owner); err != nil {
+		s.log.Error("释放转账租约失败", zap.Error(err), zap.Int("LogID", pendingLog.ID))
 	}
+}
 
-	return nil
+// keepLease renews the lease of a payout while it is being signed and
+// broadcast, a slow node can take longer than the lease. The returned func stops
+// renewing.
+func (s *ProcessingFLow) keepLease(id int) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				owned, err := do.NewTokenTransferLogManager(s.db).RenewLease(id, s.owner, time.Now().Add(s.lease))
+				if err != nil || !owned {
+					s.log.Error("续租转账失败", zap.Error(err), zap.Int("LogID", id))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// transferOptions reads the overrides an operator set when retrying the
+// payout. They are validated when set.
+func transferOptions(pendingLog *do.TokenTransferLog) eth.TransferOptions {
+	opts := eth.TransferOptions{GasLimit: pendingLog.GasLimitOverride}
+	if value, ok := new(big.Int).SetString(pendingLog.MaxFeePerGasOverride, 10); ok {
+		opts.MaxFeePerGas = value
+	}
+	if value, ok := new(big.Int).SetString(pendingLog.PriorityFeeOverride, 10); ok {
+		opts.MaxPriorityFeePerGas = value
+	}
+	return opts
+}
+
+func bigString(value *big.Int) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+type balanceFunc func(ctx context.Context, address common.Address) (*big.Int, error)
+
+// balanceFunc reads balances of the payout asset, ERC-20 or native.
+func (s *ProcessingFLow) balanceFunc(tokenInfo *do.TokenInfo) (balanceFunc, error) {
+	if tokenInfo.IsNative() {
+		return func(ctx context.Context, address common.Address) (*big.Int, error) {
+			return s.ethClient.BalanceAt(ctx, address, nil)
+		}, nil
+	}
+	erc20Client, err := s.tokens.Client(common.HexToAddress(tokenInfo.ContractAddress))
+	if err != nil {
+		return nil, err
+	}
+	return erc20Client.BalanceOf, nil
 }
 
-func printERC20Balance(ctx context.Context, client eth.TestErc20Client, address common.Address, label string) {
-	balance, err := client.BalanceOf(ctx, address)
+func printBalance(ctx context.Context, balanceOf balanceFunc, address common.Address, label string) {
+	balance, err := balanceOf(ctx, address)
 	if err != nil {
 		fmt.Printf("Failed to get balance for %s address: %v", label, err)
 		fmt.Println()
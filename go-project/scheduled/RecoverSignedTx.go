@@ -0,0 +1,121 @@
+package scheduled
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	"go-project/chain/eth"
+)
+
+// recoveryBatchSize bounds how many saved transactions one pass handles.
+const recoveryBatchSize = 100
+
+// droppedReason is recorded on a payout re-queued because its transaction
+// lost its nonce to another one.
+const droppedReason = "transaction dropped, nonce used by another transaction"
+
+// recoverSignedTxs rebroadcasts the current transactions of unsettled
+// payouts that are in one of statuses, e.g. saved right before a crash. A
+// mined transaction is left to the scanner, one that can no longer be
+// mined is dropped and its payout re-queued.
+func (s *ProcessingFLow) recoverSignedTxs(statuses ...string) {
+	txs, err := do.NewTokenTransferTxManager(s.db).ListUnconfirmed(statuses, recoveryBatchSize)
+	if err != nil {
+		s.log.Error("查询未确认交易失败", zap.Error(err))
+		return
+	}
+	for i := range txs {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err := s.rebroadcast(&txs[i]); err != nil {
+			s.log.Error("重新广播交易失败", zap.Error(err), zap.Int("LogID", txs[i].TokenTransferLogID), zap.String("TxHash", txs[i].TxHash))
+		}
+	}
+}
+
+func (s *ProcessingFLow) rebroadcast(transferTx *do.TokenTransferTx) error {
+	txManager := do.NewTokenTransferTxManager(s.db)
+	mined, err := s.txMined(transferTx.TxHash)
+	if err != nil {
+		return err
+	}
+	if mined {
+		// 已上链，由扫块结算
+		return txManager.MarkBroadcast(transferTx.TxHash)
+	}
+
+	err = s.business.Broadcast(s.ctx, &eth.TransferResult{
+		TxHash: transferTx.TxHash,
+		RawTx:  transferTx.RawTx,
+		Nonce:  transferTx.Nonce,
+	})
+	if errors.Is(err, eth.NonceTooLowError) {
+		return s.drop(transferTx)
+	}
+	if err != nil {
+		return err
+	}
+	s.log.Info("已重新广播交易", zap.Int("LogID", transferTx.TokenTransferLogID), zap.String("TxHash", transferTx.TxHash))
+	return txManager.MarkBroadcast(transferTx.TxHash)
+}
+
+// drop gives up a transaction whose nonce went to another transaction. The
+// payout is only re-queued when none of its transactions was mined, so it
+// can not be paid twice.
+func (s *ProcessingFLow) drop(transferTx *do.TokenTransferTx) error {
+	sentTxs, err := do.NewTokenTransferTxManager(s.db).ListByTransferLogID(transferTx.TokenTransferLogID)
+	if err != nil {
+		return err
+	}
+	for _, sentTx := range sentTxs {
+		mined, err := s.txMined(sentTx.TxHash)
+		if err != nil {
+			return err
+		}
+		if mined {
+			// 同一nonce的其他交易已上链，由扫块结算
+			return nil
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		transferLogManager := do.NewTokenTransferLogManager(tx)
+		// a payout still held by a dispatcher is re-queued once its lease expires
+		requeued, err := transferLogManager.Requeue(transferTx.TokenTransferLogID, transferTx.TxHash, droppedReason)
+		if err != nil || !requeued {
+			return err
+		}
+		if err := do.NewTokenTransferTxManager(tx).MarkDropped(transferTx.TxHash); err != nil {
+			return err
+		}
+		transferLog, err := transferLogManager.GetByID(transferTx.TokenTransferLogID)
+		if err != nil {
+			return err
+		}
+		attempt := do.NewTokenTransferAttempt(transferLog, do.AttemptOutcomeDropped, processingFlowOperator)
+		attempt.TxHash = transferTx.TxHash
+		if err := do.NewTokenTransferAttemptManager(tx).Create(attempt); err != nil {
+			return err
+		}
+		s.log.Error("交易已被丢弃，转账重新排队", zap.Int("LogID", transferTx.TokenTransferLogID), zap.String("TxHash", transferTx.TxHash))
+		return nil
+	})
+}
+
+func (s *ProcessingFLow) txMined(txHash string) (bool, error) {
+	receipt, err := s.ethClient.TxReceiptByTxHash(common.HexToHash(txHash))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("获取交易收据失败: %w", err)
+	}
+	return receipt != nil, nil
+}
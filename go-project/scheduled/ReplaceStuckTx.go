@@ -0,0 +1,253 @@
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/token/do"
+	"go-project/chain/eth"
+	"go-project/main/log"
+)
+
+const (
+	defaultStuckAfter   = 2 * time.Minute
+	defaultMaxSpeedUps  = 3
+	stuckTxReplaceLimit = 10
+	stuckTxReplacedBy   = "StuckTxReplacer"
+)
+
+// StuckTxReplacer re-broadcasts payouts that stay unmined. Each round bumps
+// the fees of the latest transaction with the same nonce; after maxSpeedUps
+// attempts the payout is cancelled with a zero-value self-transfer.
+type StuckTxReplacer struct {
+	ctx         context.Context
+	ethClient   eth.EthClient
+	business    *eth.BusinessService
+	db          *gorm.DB
+	log         *log.ZapLogger
+	stuckAfter  time.Duration
+	maxSpeedUps int
+	bumpPercent uint64
+}
+
+func NewStuckTxReplacer(ctx context.Context, client eth.EthClient, business *eth.BusinessService, db *gorm.DB, log *log.ZapLogger,
+	stuckAfter time.Duration, maxSpeedUps int, bumpPercent uint64) (*StuckTxReplacer, error) {
+	if stuckAfter <= 0 {
+		stuckAfter = defaultStuckAfter
+	}
+	if maxSpeedUps <= 0 {
+		maxSpeedUps = defaultMaxSpeedUps
+	}
+	return &StuckTxReplacer{
+		ctx:         ctx,
+		ethClient:   client,
+		business:    business,
+		db:          db,
+		log:         log,
+		stuckAfter:  stuckAfter,
+		maxSpeedUps: maxSpeedUps,
+		bumpPercent: bumpPercent,
+	}, nil
+}
+
+func (s *StuckTxReplacer) Start() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			fmt.Println("StuckTxReplacer done")
+			return
+		case <-ticker.C:
+			err := s.replaceStuckTxs()
+			if err != nil {
+				fmt.Printf("StuckTxReplacer error: %v\n", err)
+			}
+		}
+	}
+}
+
+func (s *StuckTxReplacer) replaceStuckTxs() error {
+	stuckLogs, err := do.NewTokenTransferLogManager(s.db).ListStuckPending(time.Now().Add(-s.stuckAfter), stuckTxReplaceLimit)
+	if err != nil {
+		s.log.Error("replaceStuckTxs ListStuckPending", zap.Error(err))
+		return err
+	}
+	if len(stuckLogs) == 0 {
+		return nil
+	}
+
+	for i := range stuckLogs {
+		if err := s.replaceStuckTx(&stuckLogs[i]); err != nil {
+			s.log.Error("替换卡住的交易失败", zap.Error(err), zap.Int("LogID", stuckLogs[i].ID), zap.String("TxHash", stuckLogs[i].TransactionHash))
+		}
+	}
+	return nil
+}
+
+func (s *StuckTxReplacer) replaceStuckTx(transferLog *do.TokenTransferLog) error {
+	from := s.business.SignerAddress()
+	if common.HexToAddress(transferLog.FromAddress) != from {
+		return fmt.Errorf("转账地址 %s 不是当前签名地址", transferLog.FromAddress)
+	}
+
+	txManager := do.NewTokenTransferTxManager(s.db)
+	sentTxs, err := txManager.ListByTransferLogID(transferLog.ID)
+	if err != nil {
+		return err
+	}
+	if len(sentTxs) == 0 {
+		// sent before replacements were recorded, rebuild it from the node
+		original, err := s.originalFromChain(transferLog)
+		if err != nil {
+			return err
+		}
+		sentTxs = append(sentTxs, *original)
+	}
+	latest := sentTxs[len(sentTxs)-1]
+
+	mined, err := s.nonceMined(from, latest.Nonce)
+	if err != nil {
+		return err
+	}
+	if mined {
+		// one of the transactions landed, the scanner settles it
+		return nil
+	}
+
+	speedUps := 0
+	for _, sentTx := range sentTxs {
+		if sentTx.TxType == do.TxTypeSpeedUp {
+			speedUps++
+		}
+	}
+	cancel := latest.TxType == do.TxTypeCancel || speedUps >= s.maxSpeedUps
+
+	data, err := hexutil.Decode(transferLog.TransferData)
+	if err != nil {
+		return fmt.Errorf("解析transfer data失败: %w", err)
+	}
+	maxFeePerGas, _ := new(big.Int).SetString(latest.MaxFeePerGas, 10)
+	maxPriorityFeePerGas, _ := new(big.Int).SetString(latest.MaxPriorityFeePerGas, 10)
+
+	to := common.HexToAddress(transferLog.ContractAddress)
+	value := big.NewInt(0)
+	if transferLog.IsNative() {
+		to = common.HexToAddress(transferLog.ToAddress)
+		value, _ = new(big.Int).SetString(transferLog.Amount, 10)
+	}
+
+	result, err := s.business.PrepareReplacement(s.ctx, eth.ReplacementRequest{
+		To:                   to,
+		Value:                value,
+		Data:                 data,
+		Nonce:                latest.Nonce,
+		GasLimit:             latest.GasLimit,
+		MaxFeePerGas:         maxFeePerGas,
+		MaxPriorityFeePerGas: maxPriorityFeePerGas,
+		BumpPercent:          s.bumpPercent,
+		Cancel:               cancel,
+	})
+	if err != nil {
+		if errors.Is(err, eth.ReplacementFeeTooHighError) {
+			s.log.Error("替换交易费用超过上限，等待gas下降", zap.Int("LogID", transferLog.ID), zap.Error(err))
+			return nil
+		}
+		return err
+	}
+
+	txType := do.TxTypeSpeedUp
+	if cancel {
+		txType = do.TxTypeCancel
+	}
+	transferLog.TransactionHash = result.TxHash
+	transferLog.GasLimit = result.GasLimit
+	transferLog.MaxFeePerGas = bigString(result.MaxFeePerGas)
+	transferLog.MaxPriorityFeePerGas = bigString(result.MaxPriorityFeePerGas)
+	transferLog.UpdatedBy = stuckTxReplacedBy
+	transferLog.UpdatedAddr = "system"
+
+	// saved before it is broadcast, a replacement mined with an unknown hash
+	// would look dropped and the payout be sent again
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txManager := do.NewTokenTransferTxManager(tx)
+		if len(sentTxs) == 1 && sentTxs[0].ID == 0 {
+			if err := txManager.Create(&sentTxs[0]); err != nil {
+				return err
+			}
+		}
+		if err := txManager.Create(newTokenTransferTx(transferLog.ID, from.Hex(), txType, do.TxStatusSigned, result)); err != nil {
+			return err
+		}
+		return do.NewTokenTransferLogManager(tx).Update(transferLog)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.business.Broadcast(s.ctx, result)
+	if errors.Is(err, eth.NonceTooLowError) {
+		// 同一nonce的交易已上链，由扫块结算
+		return nil
+	}
+	if err != nil {
+		// still signed, the dispatcher rebroadcasts it
+		return err
+	}
+	return txManager.MarkBroadcast(result.TxHash)
+}
+
+// nonceMined reports whether any transaction with nonce has been mined.
+func (s *StuckTxReplacer) nonceMined(from common.Address, nonce uint64) (bool, error) {
+	latestNonce, err := s.ethClient.TxCountByAddress(from)
+	if err != nil {
+		return false, fmt.Errorf("获取nonce失败: %w", err)
+	}
+	return uint64(latestNonce) > nonce, nil
+}
+
+func (s *StuckTxReplacer) originalFromChain(transferLog *do.TokenTransferLog) (*do.TokenTransferTx, error) {
+	tx, err := s.ethClient.TxByTxHash(common.HexToHash(transferLog.TransactionHash))
+	if err != nil {
+		if errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("节点上找不到交易 %s", transferLog.TransactionHash)
+		}
+		return nil, fmt.Errorf("获取交易失败: %w", err)
+	}
+	return &do.TokenTransferTx{
+		TokenTransferLogID:   transferLog.ID,
+		TxHash:               tx.Hash().Hex(),
+		TxType:               do.TxTypeOriginal,
+		Status:               do.TxStatusBroadcast,
+		FromAddress:          transferLog.FromAddress,
+		Nonce:                tx.Nonce(),
+		GasLimit:             tx.Gas(),
+		MaxFeePerGas:         tx.GasFeeCap().String(),
+		MaxPriorityFeePerGas: tx.GasTipCap().String(),
+	}, nil
+}
+
+func newTokenTransferTx(transferLogID int, from string, txType, status string, result *eth.TransferResult) *do.TokenTransferTx {
+	return &do.TokenTransferTx{
+		TokenTransferLogID:   transferLogID,
+		TxHash:               result.TxHash,
+		TxType:               txType,
+		RawTx:                result.RawTx,
+		Status:               status,
+		FromAddress:          from,
+		Nonce:                result.Nonce,
+		GasLimit:             result.GasLimit,
+		MaxFeePerGas:         bigString(result.MaxFeePerGas),
+		MaxPriorityFeePerGas: bigString(result.MaxPriorityFeePerGas),
+	}
+}
@@ -2,8 +2,10 @@ package scheduled
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -13,23 +15,38 @@ import (
 
 	do2 "go-project/business/scan/do"
 	"go-project/business/token/do"
+	do3 "go-project/business/workflow/do"
+	workflowservice "go-project/business/workflow/service"
 	"go-project/chain/eth"
 	"go-project/main/log"
 )
 
+const defaultScanSingleQuantity = 100
+
 type ScanBlock struct {
-	ctx       context.Context
-	ethClient eth.EthClient
-	db        *gorm.DB
-	log       *log.ZapLogger
+	ctx          context.Context
+	ethClient    eth.EthClient
+	ethSlot      *EthSlot
+	fetcher      *BlockFetcher
+	eventDecoder *eth.Erc20EventDecoder
+	db           *gorm.DB
+	log          *log.ZapLogger
 }
 
-func NewScanBlock(ctx context.Context, client eth.EthClient, db *gorm.DB, log *log.ZapLogger) (*ScanBlock, error) {
+func NewScanBlock(ctx context.Context, client eth.EthClient, ethSlot *EthSlot, fetcher *BlockFetcher, db *gorm.DB, log *log.ZapLogger) (*ScanBlock, error) {
+	eventDecoder, err := eth.NewErc20EventDecoder()
+	if err != nil {
+		return nil, err
+	}
+
 	return &ScanBlock{
-		ctx:       ctx,
-		ethClient: client,
-		db:        db,
-		log:       log,
+		ctx:          ctx,
+		ethClient:    client,
+		ethSlot:      ethSlot,
+		fetcher:      fetcher,
+		eventDecoder: eventDecoder,
+		db:           db,
+		log:          log,
 	}, nil
 }
 
@@ -52,33 +69,47 @@ func (s *ScanBlock) Start() {
 }
 
 func (s *ScanBlock) scanBlocks() error {
+	configurationManager := do3.NewWorkflowConfigurationManager(s.db)
+	scanStartBlockNum, err := configurationManager.GetUint64(do3.ConfigCodeScanStartBlockNum, 0)
+	if err != nil {
+		return fmt.Errorf("读取起始区块配置失败: %w", err)
+	}
+	scanSingleQuantity, err := configurationManager.GetUint64(do3.ConfigCodeScanSingleQuantity, defaultScanSingleQuantity)
+	if err != nil {
+		return fmt.Errorf("读取单次扫描数量配置失败: %w", err)
+	}
+	if scanSingleQuantity == 0 {
+		scanSingleQuantity = defaultScanSingleQuantity
+	}
+
 	blockInfoManager := do2.NewBlockInfoManager(s.db)
-	dbLatestBlockNumber, err := blockInfoManager.GetLatestBlockNumber()
+	dbLatestBlock, err := blockInfoManager.GetLatestBlock()
 	if err != nil {
 		return fmt.Errorf("获取最新扫描的区块号失败: %w", err)
 	}
-	s.log.Info("扫描区块 dbLatestBlockNumber ", zap.Uint64("dbLatestBlockNumber", dbLatestBlockNumber))
-	fmt.Printf("扫描区块 dbLatestBlockNumber %d", dbLatestBlockNumber)
-	fmt.Println()
 
-	remoteLatestBlock, err := s.ethClient.LatestFinalizedBlockHeader()
+	startBlock := new(big.Int).SetUint64(scanStartBlockNum)
+	if dbLatestBlock != nil && dbLatestBlock.BlockNumber >= scanStartBlockNum {
+		startBlock.SetUint64(dbLatestBlock.BlockNumber + 1)
+	}
+	s.log.Info("扫描区块 startBlock ", zap.Uint64("startBlock", startBlock.Uint64()))
+
+	remoteLatestBlock, err := s.ethSlot.SafeBlockHeader()
 	if err != nil {
 		return fmt.Errorf("获取最新区块失败: %w", err)
 	}
-	s.log.Info("远程最新区块 remoteLatestBlock", zap.Uint64("remoteLatestBlock", remoteLatestBlock.Number.Uint64()))
-	fmt.Printf("扫描区块 remoteLatestBlock %d", remoteLatestBlock.Number)
-	fmt.Println()
-
-	startBlock := new(big.Int).SetUint64(dbLatestBlockNumber)
-	if dbLatestBlockNumber > 0 {
-		startBlock.Add(startBlock, big.NewInt(1))
+	if remoteLatestBlock == nil {
+		s.log.Info("没有达到确认数的区块")
+		return nil
 	}
-	endBlock := new(big.Int).Add(startBlock, big.NewInt(100))
-	s.log.Info("扫描区块范围", zap.Uint64("startBlock", startBlock.Uint64()), zap.Uint64("endBlock", endBlock.Uint64()))
+	s.log.Info("远程最新区块 remoteLatestBlock", zap.Uint64("remoteLatestBlock", remoteLatestBlock.Number.Uint64()))
 
+	endBlock := new(big.Int).Add(startBlock, new(big.Int).SetUint64(scanSingleQuantity-1))
 	if endBlock.Cmp(remoteLatestBlock.Number) > 0 {
 		endBlock = remoteLatestBlock.Number
 	}
+	s.log.Info("扫描区块范围", zap.Uint64("startBlock", startBlock.Uint64()), zap.Uint64("endBlock", endBlock.Uint64()))
+
 	if startBlock.Cmp(endBlock) > 0 {
 		s.log.Info("没有新区块需要扫描")
 		return nil
@@ -88,30 +119,72 @@ func (s *ScanBlock) scanBlocks() error {
 	if err != nil {
 		return fmt.Errorf("获取区块头列表失败: %w", err)
 	}
+	if len(headers) == 0 {
+		return nil
+	}
 
-	return s.processBlocksInTransaction(headers)
+	reorged, err := s.detectReorg(headers[0])
+	if err != nil {
+		return fmt.Errorf("链重组检测失败: %w", err)
+	}
+	if reorged {
+		return nil
+	}
+
+	return s.processBlocks(trimDiscontinuousHeaders(headers))
 }
 
-func (s *ScanBlock) processBlocksInTransaction(headers []*types.Header) error {
+// scanBatch carries the transaction-bound managers and the token registry
+// snapshot used while indexing one block.
+type scanBatch struct {
+	tx                        *gorm.DB
+	blockInfoManager          *do2.BlockInfoManager
+	transactionManager        *do2.TransactionInfoManager
+	tokenTransferLogManager   *do.TokenTransferLogManager
+	tokenTransferEventManager *do.TokenTransferEventManager
+	tokenTransferTxManager    *do.TokenTransferTxManager
+	tokenTransferBatchManager *do.TokenTransferBatchManager
+	tokens                    map[common.Address]do.TokenInfo
+}
+
+func (s *ScanBlock) processBlocks(headers []*types.Header) error {
 	if len(headers) == 0 {
 		return nil
 	}
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		blockInfoManager := do2.NewBlockInfoManager(tx)
-		transactionManager := do2.NewTransactionInfoManager(tx)
-		tokenTransferLogManager := do.NewTokenTransferLogManager(tx)
 
-		for _, header := range headers {
-			if err := s.processBlockHeader(header, blockInfoManager); err != nil {
-				return err
+	tokenInfos, err := do.NewTokenInfoManager(s.db).List()
+	if err != nil {
+		return fmt.Errorf("获取代币列表失败: %w", err)
+	}
+	tokens := make(map[common.Address]do.TokenInfo, len(tokenInfos))
+	for _, tokenInfo := range tokenInfos {
+		if tokenInfo.IsNative() {
+			continue
+		}
+		tokens[common.HexToAddress(tokenInfo.ContractAddress)] = tokenInfo
+	}
+
+	// blocks are fetched concurrently but each one is committed in its own
+	// transaction in ascending order, so an error leaves a consistent prefix
+	return s.fetcher.Fetch(s.ctx, headers, func(fetched *fetchedBlock) error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			batch := &scanBatch{
+				tx:                        tx,
+				blockInfoManager:          do2.NewBlockInfoManager(tx),
+				transactionManager:        do2.NewTransactionInfoManager(tx),
+				tokenTransferLogManager:   do.NewTokenTransferLogManager(tx),
+				tokenTransferEventManager: do.NewTokenTransferEventManager(tx),
+				tokenTransferTxManager:    do.NewTokenTransferTxManager(tx),
+				tokenTransferBatchManager: do.NewTokenTransferBatchManager(tx),
+				tokens:                    tokens,
 			}
 
-			if err := s.processBlockTransactions(header, transactionManager, tokenTransferLogManager); err != nil {
+			if err := s.processBlockHeader(fetched.block.Header(), batch.blockInfoManager); err != nil {
 				return err
 			}
-		}
 
-		return nil
+			return s.processBlockTransactions(fetched, batch)
+		})
 	})
 }
 
@@ -131,20 +204,16 @@ func (s *ScanBlock) processBlockHeader(header *types.Header, blockInfoManager *d
 	return nil
 }
 
-func (s *ScanBlock) processBlockTransactions(header *types.Header, transactionManager *do2.TransactionInfoManager, tokenTransferLogManager *do.TokenTransferLogManager) error {
-	block, err := s.ethClient.BlockByNumberV3(s.ctx, header.Number)
-	if err != nil {
-		return fmt.Errorf("获取区块失败: %w", err)
-	}
-
+func (s *ScanBlock) processBlockTransactions(fetched *fetchedBlock, batch *scanBatch) error {
+	block := fetched.block
 	s.log.Info("处理区块交易", zap.Uint64("blockNumber", block.NumberU64()), zap.Int("txCount", len(block.Transactions())))
 
-	if len(block.Transactions()) == 0 {
-		return nil
-	}
-
 	for _, tx := range block.Transactions() {
-		if err := s.processSingleTransaction(block, tx, transactionManager, tokenTransferLogManager); err != nil {
+		receipt, ok := fetched.receipts[tx.Hash()]
+		if !ok {
+			return fmt.Errorf("缺少交易收据: %s", tx.Hash().Hex())
+		}
+		if err := s.processSingleTransaction(block, tx, receipt, batch); err != nil {
 			return err
 		}
 	}
@@ -152,7 +221,7 @@ func (s *ScanBlock) processBlockTransactions(header *types.Header, transactionMa
 	return nil
 }
 
-func (s *ScanBlock) processSingleTransaction(block *types.Block, tx *types.Transaction, transactionManager *do2.TransactionInfoManager, tokenTransferLogManager *do.TokenTransferLogManager) error {
+func (s *ScanBlock) processSingleTransaction(block *types.Block, tx *types.Transaction, receipt *types.Receipt, batch *scanBatch) error {
 	txHash := tx.Hash().Hex()
 	// 尝试使用不同的方法获取发送者
 	var from common.Address
@@ -165,31 +234,23 @@ func (s *ScanBlock) processSingleTransaction(block *types.Block, tx *types.Trans
 		from = common.Address{}
 	}
 
-	to := tx.To()
+	// 合约创建交易没有 to 地址；代币的实际收款方以 Transfer 事件为准
 	toAddress := ""
-	if to != nil {
+	tokenAddress := ""
+	if to := tx.To(); to != nil {
 		toAddress = to.Hex()
-	}
-
-	data := tx.Data()
-	if len(data) >= 4 && toAddress == "" {
-		if len(data) >= 36 {
-			toAddress = common.BytesToAddress(data[4:36]).Hex()
+		if _, ok := batch.tokens[*to]; ok {
+			tokenAddress = to.Hex()
 		}
 	}
 
-	receipt, err := s.ethClient.TxReceiptByTxHash(tx.Hash())
-	if err != nil {
-		return fmt.Errorf("获取交易收据失败: %w", err)
-	}
-
 	txInfo := &do2.TransactionInfo{
 		BlockNumber:      block.NumberU64(),
 		BlockHash:        block.Hash().Hex(),
 		TxHash:           txHash,
 		FromAddress:      from.Hex(),
 		ToAddress:        toAddress,
-		TokenAddress:     toAddress,
+		TokenAddress:     tokenAddress,
 		Value:            tx.Value().String(),
 		GasPrice:         tx.GasPrice().String(),
 		GasLimit:         tx.Gas(),
@@ -202,35 +263,260 @@ func (s *ScanBlock) processSingleTransaction(block *types.Block, tx *types.Trans
 		CreatedTime:      time.Unix(int64(block.Time()), 0),
 	}
 
-	if err := transactionManager.Create(txInfo); err != nil {
+	if err := batch.transactionManager.Create(txInfo); err != nil {
 		return fmt.Errorf("保存交易信息失败: %w", err)
 	}
 
-	if err := s.updateTokenTransferLog(txHash, from.Hex(), toAddress, tokenTransferLogManager); err != nil {
+	events, err := s.processReceiptLogs(receipt, batch)
+	if err != nil {
+		return fmt.Errorf("解析代币事件失败: %w", err)
+	}
+
+	if err := s.updateTokenTransferLog(tx, receipt, from.Hex(), events, batch); err != nil {
 		return fmt.Errorf("更新TokenTransferLog失败: %w", err)
 	}
 
 	return nil
 }
 
-func (s *ScanBlock) updateTokenTransferLog(txHash, fromAddress, toAddress string, tokenTransferLogManager *do.TokenTransferLogManager) error {
-	pendingLog, err := tokenTransferLogManager.GetByTxHashAndAddresses(txHash, fromAddress, toAddress)
+func (s *ScanBlock) processReceiptLogs(receipt *types.Receipt, batch *scanBatch) ([]*eth.Erc20Event, error) {
+	var events []*eth.Erc20Event
+	for _, receiptLog := range receipt.Logs {
+		tokenInfo, ok := batch.tokens[receiptLog.Address]
+		if !ok || receiptLog.Removed {
+			continue
+		}
+
+		event, err := s.eventDecoder.Decode(*receiptLog)
+		if err != nil {
+			s.log.Error("代币事件解码失败", zap.Error(err), zap.String("txHash", receiptLog.TxHash.Hex()))
+			continue
+		}
+		if event == nil {
+			continue
+		}
+
+		err = batch.tokenTransferEventManager.Create(&do.TokenTransferEvent{
+			TokenInfoID:  tokenInfo.ID,
+			TokenAddress: event.Token.Hex(),
+			EventType:    event.EventType,
+			FromAddress:  event.From.Hex(),
+			ToAddress:    event.To.Hex(),
+			Value:        event.Value.String(),
+			LogIndex:     event.LogIndex,
+			BlockNumber:  event.BlockNumber,
+			BlockHash:    event.BlockHash.Hex(),
+			TxHash:       event.TxHash.Hex(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("保存代币事件失败: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *ScanBlock) updateTokenTransferLog(tx *types.Transaction, receipt *types.Receipt, fromAddress string, events []*eth.Erc20Event, batch *scanBatch) error {
+	txHash := receipt.TxHash.Hex()
+	transferBatch, err := batch.tokenTransferBatchManager.GetByTxHash(txHash)
+	if err != nil {
+		return fmt.Errorf("查询TokenTransferBatch失败: %w", err)
+	}
+	if transferBatch != nil {
+		return s.settleTransferBatch(transferBatch, receipt, fromAddress, events, batch)
+	}
+
+	pendingLog, transferTx, err := s.findPendingTransferLog(txHash, fromAddress, batch)
 	if err != nil {
 		return fmt.Errorf("查询TokenTransferLog失败: %w", err)
 	}
+	if pendingLog == nil || (pendingLog.Status != do.StatusPending && pendingLog.Status != do.StatusSending) {
+		return nil
+	}
 
-	if pendingLog != nil && pendingLog.Status == do.StatusPending {
+	// any replacement of the payout may be the one that got mined
+	pendingLog.TransactionHash = txHash
+	if transferTx != nil && transferTx.TxType == do.TxTypeCancel {
+		pendingLog.Status = do.StatusFailed
+		pendingLog.FailureReason = fmt.Sprintf("cancelled by replacement transaction in block %d", receipt.BlockNumber)
+		s.log.Error("TokenTransferLog已被取消交易替换", zap.String("txHash", txHash))
+	} else if reason := settlementFailureReason(pendingLog, tx, receipt, events); reason != "" {
+		pendingLog.Status = do.StatusFailed
+		pendingLog.FailureReason = reason
+		s.log.Error("TokenTransferLog结算失败", zap.String("txHash", txHash), zap.String("reason", reason))
+	} else {
 		pendingLog.Status = do.StatusSuccess
+		pendingLog.FailureReason = ""
+		s.log.Info("TokenTransferLog状态更新为成功", zap.String("txHash", txHash))
+	}
+	pendingLog.UpdatedTime = time.Now()
+	pendingLog.UpdatedBy = "ScanBlock"
+	pendingLog.UpdatedAddr = "system"
+
+	if err := batch.tokenTransferLogManager.Update(pendingLog); err != nil {
+		return fmt.Errorf("更新TokenTransferLog状态失败: %w", err)
+	}
+
+	workflowStatus := do3.WorkFlowStatusPaid
+	if pendingLog.Status == do.StatusFailed {
+		workflowStatus = do3.WorkFlowStatusFailed
+	}
+	if err := s.transitionWorkflow(batch.tx, pendingLog.WorkflowID, workflowStatus, pendingLog.FailureReason); err != nil {
+		return fmt.Errorf("更新工作流状态失败: %w", err)
+	}
+
+	return nil
+}
+
+// settleTransferBatch settles each payout of a mined disperse batch from
+// the Transfer events of its receipt.
+func (s *ScanBlock) settleTransferBatch(transferBatch *do.TokenTransferBatch, receipt *types.Receipt, fromAddress string, events []*eth.Erc20Event, batch *scanBatch) error {
+	if !strings.EqualFold(transferBatch.FromAddress, fromAddress) {
+		return nil
+	}
+	txHash := transferBatch.TxHash
+	pendingLogs, err := batch.tokenTransferLogManager.ListUnsettledByBatch(transferBatch.ID, txHash)
+	if err != nil {
+		return fmt.Errorf("查询批量转账的TokenTransferLog失败: %w", err)
+	}
+
+	reasons := batchSettlementFailureReasons(pendingLogs, receipt, events)
+	for i := range pendingLogs {
+		pendingLog := &pendingLogs[i]
+		workflowStatus := do3.WorkFlowStatusPaid
+		if reasons[i] != "" {
+			pendingLog.Status = do.StatusFailed
+			pendingLog.FailureReason = reasons[i]
+			workflowStatus = do3.WorkFlowStatusFailed
+			s.log.Error("批量转账中的TokenTransferLog结算失败", zap.String("txHash", txHash), zap.Int("LogID", pendingLog.ID), zap.String("reason", reasons[i]))
+		} else {
+			pendingLog.Status = do.StatusSuccess
+			pendingLog.FailureReason = ""
+		}
 		pendingLog.UpdatedTime = time.Now()
 		pendingLog.UpdatedBy = "ScanBlock"
 		pendingLog.UpdatedAddr = "system"
 
-		if err := tokenTransferLogManager.Update(pendingLog); err != nil {
+		if err := batch.tokenTransferLogManager.Update(pendingLog); err != nil {
 			return fmt.Errorf("更新TokenTransferLog状态失败: %w", err)
 		}
-
-		s.log.Info("TokenTransferLog状态更新为成功", zap.String("txHash", txHash))
+		if err := s.transitionWorkflow(batch.tx, pendingLog.WorkflowID, workflowStatus, pendingLog.FailureReason); err != nil {
+			return fmt.Errorf("更新工作流状态失败: %w", err)
+		}
 	}
 
+	status := do.BatchStatusSettled
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		status = do.BatchStatusReverted
+	}
+	if err := batch.tokenTransferBatchManager.MarkMined(txHash, status); err != nil {
+		return fmt.Errorf("更新TokenTransferBatch状态失败: %w", err)
+	}
+	s.log.Info("批量转账已结算", zap.String("txHash", txHash), zap.Int("count", len(pendingLogs)), zap.String("status", status))
 	return nil
 }
+
+// transitionWorkflow follows a payout's settlement on its workflow. A
+// workflow that is missing or in an unexpected status is logged and skipped
+// so it cannot block indexing.
+func (s *ScanBlock) transitionWorkflow(tx *gorm.DB, workflowID int, to, reason string) error {
+	err := workflowservice.TransitionByID(tx, workflowID, to, "ScanBlock", reason)
+	var illegal *workflowservice.IllegalTransitionError
+	if errors.Is(err, workflowservice.WorkflowNotFoundError) || errors.As(err, &illegal) {
+		s.log.Error("工作流状态无法更新", zap.Int("WorkflowID", workflowID), zap.String("to", to), zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+// findPendingTransferLog resolves the payout of a mined transaction through
+// token_transfer_tx, falling back to the log's own hash for payouts sent
+// before replacements were recorded.
+func (s *ScanBlock) findPendingTransferLog(txHash, fromAddress string, batch *scanBatch) (*do.TokenTransferLog, *do.TokenTransferTx, error) {
+	transferTx, err := batch.tokenTransferTxManager.GetByTxHash(txHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if transferTx == nil {
+		pendingLog, err := batch.tokenTransferLogManager.GetPendingByTxHashAndFrom(txHash, fromAddress)
+		return pendingLog, nil, err
+	}
+
+	pendingLog, err := batch.tokenTransferLogManager.GetByID(transferTx.TokenTransferLogID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pendingLog == nil || !strings.EqualFold(pendingLog.FromAddress, fromAddress) {
+		return nil, nil, nil
+	}
+	return pendingLog, transferTx, nil
+}
+
+// settlementFailureReason checks that the receipt succeeded and contains a
+// Transfer event moving exactly the expected amount of the expected token
+// to the workflow recipient. Native payouts are checked against the value
+// and recipient of the transaction itself. It returns "" when the payout is
+// settled.
+func settlementFailureReason(transferLog *do.TokenTransferLog, tx *types.Transaction, receipt *types.Receipt, events []*eth.Erc20Event) string {
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Sprintf("transaction reverted in block %d", receipt.BlockNumber)
+	}
+
+	if transferLog.IsNative() {
+		to := common.HexToAddress(transferLog.ToAddress)
+		amount, ok := new(big.Int).SetString(transferLog.Amount, 10)
+		if !ok {
+			return fmt.Sprintf("invalid amount %q", transferLog.Amount)
+		}
+		if tx.To() != nil && *tx.To() == to && tx.Value().Cmp(amount) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("transaction does not send %s wei to %s", amount.String(), to.Hex())
+	}
+
+	return transferEventFailureReason(transferLog, events, nil)
+}
+
+// batchSettlementFailureReasons settles the payouts of a disperse batch,
+// returning the failure reason of each, "" when settled. Every Transfer
+// event pays at most one payout, so two equal payouts need two events.
+func batchSettlementFailureReasons(transferLogs []do.TokenTransferLog, receipt *types.Receipt, events []*eth.Erc20Event) []string {
+	reasons := make([]string, len(transferLogs))
+	used := make([]bool, len(events))
+	for i := range transferLogs {
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			reasons[i] = fmt.Sprintf("transaction reverted in block %d", receipt.BlockNumber)
+			continue
+		}
+		reasons[i] = transferEventFailureReason(&transferLogs[i], events, used)
+	}
+	return reasons
+}
+
+// transferEventFailureReason looks for a Transfer event moving exactly the
+// payout's amount of its token to its recipient and returns "" when there
+// is one. With used set, events already marked are skipped and the matching
+// one is marked.
+func transferEventFailureReason(transferLog *do.TokenTransferLog, events []*eth.Erc20Event, used []bool) string {
+	token := common.HexToAddress(transferLog.ContractAddress)
+	from := common.HexToAddress(transferLog.FromAddress)
+	to := common.HexToAddress(transferLog.ToAddress)
+	amount, ok := new(big.Int).SetString(transferLog.Amount, 10)
+	if !ok {
+		return fmt.Sprintf("invalid amount %q", transferLog.Amount)
+	}
+
+	for i, event := range events {
+		if event.EventType != eth.Erc20EventTransfer || (used != nil && used[i]) {
+			continue
+		}
+		if event.Token == token && event.From == from && event.To == to && event.Value.Cmp(amount) == 0 {
+			if used != nil {
+				used[i] = true
+			}
+			return ""
+		}
+	}
+
+	return fmt.Sprintf("no Transfer event of %s from %s to %s on token %s", amount.String(), from.Hex(), to.Hex(), token.Hex())
+}
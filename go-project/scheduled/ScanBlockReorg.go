@@ -0,0 +1,152 @@
+package scheduled
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	do2 "go-project/business/scan/do"
+	"go-project/business/token/do"
+	do3 "go-project/business/workflow/do"
+)
+
+// maxReorgDepth bounds how far back the scanner walks looking for the
+// common ancestor before giving up and asking for manual intervention.
+const maxReorgDepth = 256
+
+// detectReorg compares the parent hash of the first header in a batch with
+// the hash stored for the previous height. On mismatch it rolls the database
+// back to the common ancestor and reports true, so the caller can rescan.
+func (s *ScanBlock) detectReorg(header *types.Header) (bool, error) {
+	if header.Number.Sign() == 0 {
+		return false, nil
+	}
+
+	blockInfoManager := do2.NewBlockInfoManager(s.db)
+	parent, err := blockInfoManager.GetByNumber(header.Number.Uint64() - 1)
+	if err != nil {
+		return false, fmt.Errorf("查询父区块失败: %w", err)
+	}
+	if parent == nil || parent.BlockHash == header.ParentHash.Hex() {
+		return false, nil
+	}
+
+	s.log.Error("检测到链重组",
+		zap.Uint64("blockNumber", header.Number.Uint64()),
+		zap.String("remoteParentHash", header.ParentHash.Hex()),
+		zap.String("storedParentHash", parent.BlockHash))
+
+	ancestor, err := s.findCommonAncestor(header.ParentHash, parent.BlockNumber)
+	if err != nil {
+		return false, err
+	}
+
+	if err := s.rollbackToBlock(ancestor); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// findCommonAncestor walks the remote chain backwards from hash (at height
+// number) until it reaches a block whose hash matches the stored one.
+func (s *ScanBlock) findCommonAncestor(hash common.Hash, number uint64) (uint64, error) {
+	blockInfoManager := do2.NewBlockInfoManager(s.db)
+
+	for depth := 0; depth < maxReorgDepth; depth++ {
+		stored, err := blockInfoManager.GetByNumber(number)
+		if err != nil {
+			return 0, fmt.Errorf("查询区块 %d 失败: %w", number, err)
+		}
+		if stored == nil || stored.BlockHash == hash.Hex() {
+			s.log.Info("找到共同祖先区块", zap.Uint64("blockNumber", number), zap.Int("depth", depth))
+			return number, nil
+		}
+		if number == 0 {
+			return 0, fmt.Errorf("创世区块哈希不一致: stored %s remote %s", stored.BlockHash, hash.Hex())
+		}
+
+		remote, err := s.ethClient.BlockHeaderByBlockHash(hash)
+		if err != nil {
+			return 0, fmt.Errorf("获取远程区块头失败 (%s): %w", hash.Hex(), err)
+		}
+		hash = remote.ParentHash
+		number--
+	}
+
+	return 0, fmt.Errorf("链重组深度超过 %d 个区块", maxReorgDepth)
+}
+
+// rollbackToBlock removes every block and transaction above ancestor and
+// reverts payouts that were settled by one of the orphaned transactions.
+// Batches mined by them go back to broadcast, the dispatcher then waits for
+// them to be mined again or re-queues their payouts.
+func (s *ScanBlock) rollbackToBlock(ancestor uint64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		blockInfoManager := do2.NewBlockInfoManager(tx)
+		transactionManager := do2.NewTransactionInfoManager(tx)
+		tokenTransferLogManager := do.NewTokenTransferLogManager(tx)
+		tokenTransferEventManager := do.NewTokenTransferEventManager(tx)
+
+		txHashes, err := transactionManager.ListTxHashesAfterBlockNumber(ancestor)
+		if err != nil {
+			return fmt.Errorf("查询孤块交易失败: %w", err)
+		}
+
+		workflowIDs, err := tokenTransferLogManager.ListWorkflowIDsSettledByTxHashes(txHashes)
+		if err != nil {
+			return fmt.Errorf("查询孤块结算的工作流失败: %w", err)
+		}
+		reverted, err := tokenTransferLogManager.RevertSettledByTxHashes(txHashes, "ScanBlock")
+		if err != nil {
+			return fmt.Errorf("回滚TokenTransferLog失败: %w", err)
+		}
+		resetBatches, err := do.NewTokenTransferBatchManager(tx).ResetMinedByTxHashes(txHashes)
+		if err != nil {
+			return fmt.Errorf("回滚批量交易状态失败: %w", err)
+		}
+		for _, workflowID := range workflowIDs {
+			err := s.transitionWorkflow(tx, workflowID, do3.WorkFlowStatusApproved, fmt.Sprintf("payout reverted by reorg above block %d", ancestor))
+			if err != nil {
+				return fmt.Errorf("回滚工作流状态失败: %w", err)
+			}
+		}
+
+		deletedEvents, err := tokenTransferEventManager.DeleteAfterBlockNumber(ancestor)
+		if err != nil {
+			return fmt.Errorf("删除孤块代币事件失败: %w", err)
+		}
+
+		deletedTxs, err := transactionManager.DeleteAfterBlockNumber(ancestor)
+		if err != nil {
+			return fmt.Errorf("删除孤块交易失败: %w", err)
+		}
+
+		deletedBlocks, err := blockInfoManager.DeleteAfterNumber(ancestor)
+		if err != nil {
+			return fmt.Errorf("删除孤块失败: %w", err)
+		}
+
+		s.log.Info("链重组回滚完成",
+			zap.Uint64("ancestor", ancestor),
+			zap.Int64("deletedBlocks", deletedBlocks),
+			zap.Int64("deletedTxs", deletedTxs),
+			zap.Int64("deletedEvents", deletedEvents),
+			zap.Int64("revertedTransferLogs", reverted),
+			zap.Int64("resetBatches", resetBatches))
+		return nil
+	})
+}
+
+// trimDiscontinuousHeaders cuts the batch at the first header that does not
+// build on its predecessor, which happens when the chain reorgs mid-fetch.
+func trimDiscontinuousHeaders(headers []*types.Header) []*types.Header {
+	for i := 1; i < len(headers); i++ {
+		if headers[i].ParentHash != headers[i-1].Hash() {
+			return headers[:i]
+		}
+	}
+	return headers
+}
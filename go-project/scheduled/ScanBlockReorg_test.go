@@ -0,0 +1,147 @@
+package scheduled
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"go-project/main/config"
+	"go-project/main/log"
+)
+
+// recordingDriver is a database/sql driver that records every statement.
+// Queries are answered by rows, keyed by a substring of the SQL; anything
+// else returns no rows.
+type recordingDriver struct {
+	mu         sync.Mutex
+	statements []string
+	rows       map[string][]string
+}
+
+func (d *recordingDriver) record(statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, statement)
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d}, nil }
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepared statements are not supported")
+}
+func (c *recordingConn) Close() error { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.d.record("BEGIN")
+	return c, nil
+}
+func (c *recordingConn) Commit() error {
+	c.d.record("COMMIT")
+	return nil
+}
+func (c *recordingConn) Rollback() error {
+	c.d.record("ROLLBACK")
+	return nil
+}
+
+func (c *recordingConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(withArgs(query, args))
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(withArgs(query, args))
+	for match, values := range c.d.rows {
+		if strings.Contains(query, match) {
+			return &recordingRows{values: values}, nil
+		}
+	}
+	return &recordingRows{}, nil
+}
+
+func withArgs(query string, args []driver.NamedValue) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = fmt.Sprint(arg.Value)
+	}
+	return query + " " + strings.Join(values, ",")
+}
+
+// recordingRows is a single column result.
+type recordingRows struct {
+	values []string
+}
+
+func (r *recordingRows) Columns() []string { return []string{"value"} }
+func (r *recordingRows) Close() error      { return nil }
+func (r *recordingRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.values[0]
+	r.values = r.values[1:]
+	return nil
+}
+
+func openRecordingDB(t *testing.T, name string, d *recordingDriver) *gorm.DB {
+	t.Helper()
+	sql.Register(name, d)
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open gorm: %v", err)
+	}
+	return db
+}
+
+func TestRollbackToBlock_ResetsOrphanedBatches(t *testing.T) {
+	batchTxHash := "0x5d1c0a6c0b3ad6d8f9d3a8c0d2b1e9c6a7f5e4d3c2b1a09f8e7d6c5b4a392817"
+	d := &recordingDriver{rows: map[string][]string{"FROM `transaction_info`": {batchTxHash}}}
+	db := openRecordingDB(t, "recording-rollback", d)
+	logger, err := log.NewLogger(&config.Configuration{Log: config.LogConfig{Level: "info", RootDir: t.TempDir(), Filename: "test.log", Format: "console"}})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	scanBlock := &ScanBlock{db: db, log: logger}
+	if err := scanBlock.rollbackToBlock(100); err != nil {
+		t.Fatalf("rollbackToBlock failed: %v", err)
+	}
+
+	inTx := false
+	reset := false
+	for _, statement := range d.statements {
+		switch {
+		case statement == "BEGIN":
+			inTx = true
+		case statement == "COMMIT":
+			inTx = false
+		case strings.HasPrefix(statement, "UPDATE `token_transfer_batch`"):
+			if !inTx {
+				t.Fatalf("Batch reset outside the rollback transaction: %s", statement)
+			}
+			for _, want := range []string{"broadcast", batchTxHash, "settled", "reverted"} {
+				if !strings.Contains(statement, want) {
+					t.Fatalf("Expected %q in batch reset, got %s", want, statement)
+				}
+			}
+			reset = true
+		}
+	}
+	if !reset {
+		t.Fatalf("Expected the orphaned batch to be reset, statements: %v", d.statements)
+	}
+}
@@ -0,0 +1,89 @@
+package scheduled
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"go-project/business/token/do"
+	"go-project/chain/eth"
+)
+
+func TestSettlementFailureReason_Native(t *testing.T) {
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+	other := common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC")
+	transferLog := &do.TokenTransferLog{
+		FromAddress:     "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		ToAddress:       to.Hex(),
+		ContractAddress: do.NativeTokenAddress,
+		Amount:          "1000",
+	}
+	success := &types.Receipt{Status: types.ReceiptStatusSuccessful}
+
+	cases := []struct {
+		name    string
+		tx      *types.Transaction
+		receipt *types.Receipt
+		settled bool
+	}{
+		{"matching value and recipient", types.NewTx(&types.DynamicFeeTx{To: &to, Value: big.NewInt(1000)}), success, true},
+		{"wrong value", types.NewTx(&types.DynamicFeeTx{To: &to, Value: big.NewInt(999)}), success, false},
+		{"wrong recipient", types.NewTx(&types.DynamicFeeTx{To: &other, Value: big.NewInt(1000)}), success, false},
+		{"reverted", types.NewTx(&types.DynamicFeeTx{To: &to, Value: big.NewInt(1000)}), &types.Receipt{Status: types.ReceiptStatusFailed}, false},
+	}
+	for _, c := range cases {
+		reason := settlementFailureReason(transferLog, c.tx, c.receipt, nil)
+		if (reason == "") != c.settled {
+			t.Errorf("%s: expected settled=%v, got reason %q", c.name, c.settled, reason)
+		}
+	}
+}
+
+func TestSettlementFailureReason_ERC20IgnoresTxValue(t *testing.T) {
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	from := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+	transferLog := &do.TokenTransferLog{
+		FromAddress:     from.Hex(),
+		ToAddress:       to.Hex(),
+		ContractAddress: token.Hex(),
+		Amount:          "1000",
+	}
+	// an ERC-20 payout sends no value, the Transfer event carries the amount
+	tx := types.NewTx(&types.DynamicFeeTx{To: &token, Value: big.NewInt(0)})
+	events := []*eth.Erc20Event{{EventType: eth.Erc20EventTransfer, Token: token, From: from, To: to, Value: big.NewInt(1000)}}
+
+	if reason := settlementFailureReason(transferLog, tx, &types.Receipt{Status: types.ReceiptStatusSuccessful}, events); reason != "" {
+		t.Fatalf("Expected settled, got %q", reason)
+	}
+}
+
+func TestBatchSettlementFailureReasons(t *testing.T) {
+	token := common.HexToAddress("0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35")
+	from := common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+	to := common.HexToAddress("0x23618e81E3f5cdF7f54C3d65f7FBc0aBf5B21E8f")
+	other := common.HexToAddress("0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC")
+	payout := func(to common.Address, amount string) do.TokenTransferLog {
+		return do.TokenTransferLog{FromAddress: from.Hex(), ToAddress: to.Hex(), ContractAddress: token.Hex(), Amount: amount}
+	}
+	transfer := func(to common.Address, value int64) *eth.Erc20Event {
+		return &eth.Erc20Event{EventType: eth.Erc20EventTransfer, Token: token, From: from, To: to, Value: big.NewInt(value)}
+	}
+	// two equal payouts to the same recipient, only one of them was paid
+	transferLogs := []do.TokenTransferLog{payout(to, "1000"), payout(to, "1000"), payout(other, "500")}
+	events := []*eth.Erc20Event{transfer(to, 1000), transfer(other, 500)}
+
+	reasons := batchSettlementFailureReasons(transferLogs, &types.Receipt{Status: types.ReceiptStatusSuccessful}, events)
+	if reasons[0] != "" || reasons[1] == "" || reasons[2] != "" {
+		t.Fatalf("Expected only the second payout unsettled, got %q", reasons)
+	}
+
+	reasons = batchSettlementFailureReasons(transferLogs, &types.Receipt{Status: types.ReceiptStatusFailed}, events)
+	for i, reason := range reasons {
+		if reason == "" {
+			t.Fatalf("Expected payout %d of a reverted batch to fail", i)
+		}
+	}
+}
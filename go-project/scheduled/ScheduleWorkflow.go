@@ -0,0 +1,52 @@
+package scheduled
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"go-project/business/workflow/service"
+	"go-project/main/log"
+)
+
+const scheduleWorkflowLimit = 100
+
+// WorkflowScheduler creates the payouts of scheduled workflows when a run
+// is due. ProcessingFLow then sends them like any other payout.
+type WorkflowScheduler struct {
+	ctx context.Context
+	db  *gorm.DB
+	log *log.ZapLogger
+}
+
+func NewWorkflowScheduler(ctx context.Context, db *gorm.DB, log *log.ZapLogger) (*WorkflowScheduler, error) {
+	return &WorkflowScheduler{
+		ctx: ctx,
+		db:  db,
+		log: log,
+	}, nil
+}
+
+func (s *WorkflowScheduler) Start() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			fmt.Println("WorkflowScheduler done")
+			return
+		case <-ticker.C:
+			created, err := service.NewService(s.log, s.db).RunDueSchedules(time.Now(), scheduleWorkflowLimit)
+			if err != nil {
+				s.log.Error("定时工作流处理失败", zap.Error(err))
+			}
+			if created > 0 {
+				s.log.Info("定时工作流已生成转账", zap.Int("count", created))
+			}
+		}
+	}
+}
@@ -20,20 +20,22 @@ import (
 )
 
 type TestIncrementBlock struct {
-	ctx         context.Context
-	ethClient   eth.EthClient
-	erc20Client eth.TestErc20Client
-	db          *gorm.DB
-	log         *log.ZapLogger
+	ctx       context.Context
+	ethClient eth.EthClient
+	tokens    *eth.TokenRegistry
+	business  *eth.BusinessService
+	db        *gorm.DB
+	log       *log.ZapLogger
 }
 
-func NewTestIncrementBlock(ctx context.Context, client eth.EthClient, erc20Client eth.TestErc20Client, db *gorm.DB, log *log.ZapLogger) (*TestIncrementBlock, error) {
+func NewTestIncrementBlock(ctx context.Context, client eth.EthClient, tokens *eth.TokenRegistry, business *eth.BusinessService, db *gorm.DB, log *log.ZapLogger) (*TestIncrementBlock, error) {
 	return &TestIncrementBlock{
-		ctx:         ctx,
-		ethClient:   client,
-		erc20Client: erc20Client,
-		db:          db,
-		log:         log,
+		ctx:       ctx,
+		ethClient: client,
+		tokens:    tokens,
+		business:  business,
+		db:        db,
+		log:       log,
 	}, nil
 }
 
@@ -129,18 +131,13 @@ func (s *TestIncrementBlock) printBalances(fromAddress, toAddress common.Address
 }
 
 func (s *TestIncrementBlock) transferERC20() error {
-	privateKey, err := crypto.HexToECDSA(global_const.OWNER_PRV_KEY)
-	if err != nil {
-		return fmt.Errorf("解析私钥失败: %w", err)
-	}
-	fromAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	fromAddress := s.business.SignerAddress()
 	toAddress := common.HexToAddress("0x14dC79964da2C08b23698B3D3cc7Ca32193d9955")
 	tokenAddress := common.HexToAddress(global_const.TEMP_TEST_ERC20_ADDRESS)
 
 	amount := big.NewInt(1 * 1e6)
 
-	ethBusiness := eth.NewEthBusinessService(s.ethClient, s.erc20Client, s.log)
-	txHash, transferData, err := ethBusiness.TransferERC20(context.Background(), privateKey, fromAddress.Hex(), toAddress.Hex(), tokenAddress.Hex(), amount)
+	result, err := s.business.TransferERC20(context.Background(), toAddress.Hex(), tokenAddress.Hex(), amount)
 	if err != nil {
 		if errors.Is(err, eth.InsufficientBalanceError) {
 			s.log.Error("ERC20转账失败: 余额不足", zap.Error(err))
@@ -149,11 +146,15 @@ func (s *TestIncrementBlock) transferERC20() error {
 		return fmt.Errorf("ERC20转账失败: %w", err)
 	}
 
-	fmt.Printf("scanBlocks BlockByNumberV3 transactionsJson %s \n", txHash)
-	fmt.Printf("scanBlocks BlockByNumberV3 transactionsJson %s \n", hexutil.Encode(transferData))
+	fmt.Printf("scanBlocks BlockByNumberV3 transactionsJson %s \n", result.TxHash)
+	fmt.Printf("scanBlocks BlockByNumberV3 transactionsJson %s \n", hexutil.Encode(result.Data))
 
-	printERC20Balance(s.ctx, s.erc20Client, fromAddress, "From")
-	printERC20Balance(s.ctx, s.erc20Client, toAddress, "To")
+	erc20Client, err := s.tokens.Client(tokenAddress)
+	if err != nil {
+		return err
+	}
+	printBalance(s.ctx, erc20Client.BalanceOf, fromAddress, "From")
+	printBalance(s.ctx, erc20Client.BalanceOf, toAddress, "To")
 
 	return nil
 }
@@ -0,0 +1,70 @@
+package amount
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ToBaseUnits converts a human readable amount such as "12.5" into token
+// base units using decimals. It rejects negative amounts and more
+// fractional digits than the token supports.
+func ToBaseUnits(value string, decimals int) (*big.Int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("amount is empty")
+	}
+	if decimals < 0 {
+		return nil, fmt.Errorf("invalid decimals %d", decimals)
+	}
+
+	integer, fraction, _ := strings.Cut(value, ".")
+	if integer == "" && fraction == "" {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	if !isDigits(integer) || !isDigits(fraction) {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimal places", value, decimals)
+	}
+
+	digits := integer + fraction + strings.Repeat("0", decimals-len(fraction))
+	result, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", value)
+	}
+	return result, nil
+}
+
+// FromBaseUnits formats base units as a human readable amount, without
+// trailing zeros.
+func FromBaseUnits(value *big.Int, decimals int) string {
+	if decimals <= 0 {
+		return value.String()
+	}
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+	digits := new(big.Int).Abs(value).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	integer := digits[:len(digits)-decimals]
+	fraction := strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + integer
+	}
+	return sign + integer + "." + fraction
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
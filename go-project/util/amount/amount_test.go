@@ -0,0 +1,58 @@
+package amount
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestToBaseUnits(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"1", 6, "1000000"},
+		{"0.123456", 6, "123456"},
+		{"12.50", 6, "12500000"},
+		{".5", 18, "500000000000000000"},
+		{"7.", 0, "7"},
+		{"115792089237316195423570985008687907853269984665640564039457", 18, "115792089237316195423570985008687907853269984665640564039457000000000000000000"},
+	}
+	for _, tt := range tests {
+		got, err := ToBaseUnits(tt.value, tt.decimals)
+		if err != nil {
+			t.Fatalf("ToBaseUnits(%q, %d) failed: %v", tt.value, tt.decimals, err)
+		}
+		if got.String() != tt.want {
+			t.Fatalf("ToBaseUnits(%q, %d) = %s, want %s", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestToBaseUnits_Invalid(t *testing.T) {
+	for _, value := range []string{"", ".", "-1", "1e6", "1.2.3", "0x10", "0.1234567"} {
+		if _, err := ToBaseUnits(value, 6); err == nil {
+			t.Fatalf("ToBaseUnits(%q) expected error", value)
+		}
+	}
+}
+
+func TestFromBaseUnits(t *testing.T) {
+	tests := []struct {
+		value    int64
+		decimals int
+		want     string
+	}{
+		{1000000, 6, "1"},
+		{123456, 6, "0.123456"},
+		{12500000, 6, "12.5"},
+		{5, 6, "0.000005"},
+		{0, 6, "0"},
+		{42, 0, "42"},
+	}
+	for _, tt := range tests {
+		if got := FromBaseUnits(big.NewInt(tt.value), tt.decimals); got != tt.want {
+			t.Fatalf("FromBaseUnits(%d, %d) = %s, want %s", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+}
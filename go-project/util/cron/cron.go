@@ -0,0 +1,131 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed five field cron expression: minute, hour, day of
+// month, month and day of week. A field is *, a number, a range a-b, a
+// step */n, a-b/n or a/n, or a comma separated list of those. Day of week
+// 0 and 7 are Sunday. As in Vixie cron, when both day fields are restricted
+// a day matching either of them fires.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set when value i fires
+	domStar, dowStar              bool
+}
+
+type bounds struct {
+	min, max int
+}
+
+var fieldBounds = [5]bounds{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// maxSearch bounds Next, an expression like "0 0 30 2 *" never fires.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Parse parses expr, e.g. "0 9 1 * *" for 09:00 on the first of every month.
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	var bits [5]uint64
+	for i, part := range parts {
+		value, err := parseField(part, fieldBounds[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+		bits[i] = value
+	}
+	// 7 is Sunday as well
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(parts[2], "*"),
+		dowStar: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", item)
+			}
+		}
+
+		var lo, hi int
+		var err error
+		if rangePart == "*" {
+			lo, hi = b.min, b.max
+		} else if from, to, ok := strings.Cut(rangePart, "-"); ok {
+			if lo, err = strconv.Atoi(from); err == nil {
+				hi, err = strconv.Atoi(to)
+			}
+		} else {
+			lo, err = strconv.Atoi(rangePart)
+			hi = lo
+			if hasStep {
+				hi = b.max
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q", item)
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("value %q out of range %d-%d", item, b.min, b.max)
+		}
+
+		for value := lo; value <= hi; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t the schedule fires, in t's location,
+// or the zero time when it does not fire within five years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,51 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, 10, 2, 21, 35, 10, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 10, 2, 21, 36, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 10, 2, 21, 45, 0, 0, time.UTC)},
+		{"0 9 1 * *", time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * 1-5", time.Date(2024, 10, 3, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 10, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 2 *", time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"0,30 22 * 10,12 *", time.Date(2024, 10, 2, 22, 0, 0, 0, time.UTC)},
+		// both day fields restricted: the 15th or any Monday
+		{"0 0 15 * 1", time.Date(2024, 10, 7, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Fatalf("Next(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestNext_NeverFires(t *testing.T) {
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := schedule.Next(time.Now()); !got.IsZero() {
+		t.Fatalf("Expected no next run, got %s", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Fatalf("Expected Parse(%q) to fail", expr)
+		}
+	}
+}
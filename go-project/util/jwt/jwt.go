@@ -0,0 +1,92 @@
+// Package jwt issues and verifies HS256 JSON Web Tokens for API sessions.
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	InvalidTokenError = errors.New("InvalidTokenError")
+	ExpiredTokenError = errors.New("ExpiredTokenError")
+)
+
+// Claims are the registered claims used by the API; Subject holds the
+// wallet address of the session.
+type Claims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti,omitempty"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Sign returns the compact serialization of claims signed with secret.
+func Sign(claims Claims, secret []byte) (string, error) {
+	headerJson, err := json.Marshal(header{Alg: "HS256", Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claimsJson, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encoding.EncodeToString(headerJson) + "." + encoding.EncodeToString(claimsJson)
+	return signingInput + "." + encoding.EncodeToString(sign(signingInput, secret)), nil
+}
+
+// Parse verifies the signature and expiry of token and returns its claims.
+func Parse(token string, secret []byte, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", InvalidTokenError)
+	}
+
+	headerJson, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", InvalidTokenError, err)
+	}
+	var h header
+	if err := json.Unmarshal(headerJson, &h); err != nil || h.Alg != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported alg", InvalidTokenError)
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", InvalidTokenError, err)
+	}
+	if !hmac.Equal(signature, sign(parts[0]+"."+parts[1], secret)) {
+		return nil, fmt.Errorf("%w: bad signature", InvalidTokenError)
+	}
+
+	claimsJson, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", InvalidTokenError, err)
+	}
+	var claims Claims
+	if err := json.Unmarshal(claimsJson, &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", InvalidTokenError, err)
+	}
+	if claims.ExpiresAt == 0 || now.Unix() >= claims.ExpiresAt {
+		return nil, ExpiredTokenError
+	}
+	return &claims, nil
+}
+
+func sign(signingInput string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,50 @@
+package jwt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignParse(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	claims := Claims{Subject: "0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix(), ID: "n1"}
+
+	token, err := Sign(claims, secret)
+	if err != nil {
+		t.Fatalf("Sign failed: %v", err)
+	}
+	parsed, err := Parse(token, secret, now)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if *parsed != claims {
+		t.Fatalf("Expected %+v, got %+v", claims, *parsed)
+	}
+
+	if _, err := Parse(token, secret, now.Add(time.Minute)); !errors.Is(err, ExpiredTokenError) {
+		t.Fatalf("Expected ExpiredTokenError, got %v", err)
+	}
+	if _, err := Parse(token, []byte("other"), now); !errors.Is(err, InvalidTokenError) {
+		t.Fatalf("Expected InvalidTokenError for wrong secret, got %v", err)
+	}
+}
+
+func TestParse_Tampered(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	token, _ := Sign(Claims{Subject: "0x1", ExpiresAt: now.Add(time.Minute).Unix()}, secret)
+	forged, _ := Sign(Claims{Subject: "0x2", ExpiresAt: now.Add(time.Minute).Unix()}, []byte("attacker"))
+
+	parts := strings.Split(token, ".")
+	forgedParts := strings.Split(forged, ".")
+	swapped := parts[0] + "." + forgedParts[1] + "." + parts[2]
+
+	for _, bad := range []string{"", "a.b", swapped, parts[0] + "." + parts[1] + ".x"} {
+		if _, err := Parse(bad, secret, now); !errors.Is(err, InvalidTokenError) {
+			t.Errorf("%q: expected InvalidTokenError, got %v", bad, err)
+		}
+	}
+}
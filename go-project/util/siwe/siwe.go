@@ -0,0 +1,151 @@
+// Package siwe parses Sign-In with Ethereum (EIP-4361) messages.
+package siwe
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const headerSuffix = " wants you to sign in with your Ethereum account:"
+
+var InvalidMessageError = errors.New("InvalidMessageError")
+
+type Message struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// ParseMessage parses the plain text message a wallet signed.
+func ParseMessage(text string) (*Message, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], headerSuffix) {
+		return nil, fmt.Errorf("%w: missing header", InvalidMessageError)
+	}
+
+	message := &Message{Domain: strings.TrimSuffix(lines[0], headerSuffix)}
+	if i := strings.Index(message.Domain, "://"); i >= 0 {
+		message.Domain = message.Domain[i+3:]
+	}
+	if !common.IsHexAddress(lines[1]) {
+		return nil, fmt.Errorf("%w: invalid address %q", InvalidMessageError, lines[1])
+	}
+	message.Address = common.HexToAddress(lines[1])
+
+	inResources := false
+	for _, line := range lines[2:] {
+		if line == "" {
+			continue
+		}
+		if inResources {
+			if !strings.HasPrefix(line, "- ") {
+				return nil, fmt.Errorf("%w: unexpected line %q", InvalidMessageError, line)
+			}
+			message.Resources = append(message.Resources, strings.TrimPrefix(line, "- "))
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ": ")
+		if !found && line == "Resources:" {
+			inResources = true
+			continue
+		}
+		var err error
+		switch key {
+		case "URI":
+			message.URI = value
+		case "Version":
+			message.Version = value
+		case "Chain ID":
+			message.ChainID, err = strconv.ParseInt(value, 10, 64)
+		case "Nonce":
+			message.Nonce = value
+		case "Issued At":
+			message.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			message.ExpirationTime, err = parseTimePtr(value)
+		case "Not Before":
+			message.NotBefore, err = parseTimePtr(value)
+		case "Request ID":
+			message.RequestID = value
+		default:
+			if message.URI != "" || message.Statement != "" {
+				return nil, fmt.Errorf("%w: unexpected line %q", InvalidMessageError, line)
+			}
+			message.Statement = line
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", InvalidMessageError, key, err)
+		}
+	}
+
+	if message.URI == "" || message.Version != "1" || message.ChainID == 0 || message.Nonce == "" || message.IssuedAt.IsZero() {
+		return nil, fmt.Errorf("%w: missing required field", InvalidMessageError)
+	}
+	return message, nil
+}
+
+// ValidAt reports whether now lies inside the message's validity window.
+func (m *Message) ValidAt(now time.Time) bool {
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return false
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return false
+	}
+	return true
+}
+
+// String renders the message in the EIP-4361 format.
+func (m *Message) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + headerSuffix + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("URI: " + m.URI + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString("Chain ID: " + strconv.FormatInt(m.ChainID, 10) + "\n")
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestID != "" {
+		b.WriteString("\nRequest ID: " + m.RequestID)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, resource := range m.Resources {
+			b.WriteString("\n- " + resource)
+		}
+	}
+	return b.String()
+}
+
+func parseTimePtr(value string) (*time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
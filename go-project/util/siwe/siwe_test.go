@@ -0,0 +1,74 @@
+package siwe
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const exampleMessage = `localhost:8888 wants you to sign in with your Ethereum account:
+0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266
+
+Sign in to the workflow console.
+
+URI: http://localhost:8888
+Version: 1
+Chain ID: 31337
+Nonce: 32891756
+Issued At: 2024-10-02T18:24:00Z
+Expiration Time: 2024-10-02T18:39:00Z
+Resources:
+- http://localhost:8888/workflow`
+
+func TestParseMessage(t *testing.T) {
+	message, err := ParseMessage(exampleMessage)
+	if err != nil {
+		t.Fatalf("ParseMessage failed: %v", err)
+	}
+	if message.Domain != "localhost:8888" ||
+		message.Address != common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266") ||
+		message.Statement != "Sign in to the workflow console." ||
+		message.URI != "http://localhost:8888" ||
+		message.ChainID != 31337 ||
+		message.Nonce != "32891756" ||
+		message.ExpirationTime == nil ||
+		len(message.Resources) != 1 {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+	if message.String() != exampleMessage {
+		t.Fatalf("String does not round trip:\n%s", message.String())
+	}
+
+	issuedAt := message.IssuedAt
+	if !message.ValidAt(issuedAt) || message.ValidAt(message.ExpirationTime.Add(time.Second)) {
+		t.Fatal("Unexpected validity window")
+	}
+}
+
+func TestParseMessage_WithoutStatement(t *testing.T) {
+	text := "example.com wants you to sign in with your Ethereum account:\n" +
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266\n\n\n" +
+		"URI: https://example.com\nVersion: 1\nChain ID: 1\nNonce: abcdefgh\nIssued At: 2024-10-02T18:24:00Z"
+	message, err := ParseMessage(text)
+	if err != nil {
+		t.Fatalf("ParseMessage failed: %v", err)
+	}
+	if message.Statement != "" || message.String() != text {
+		t.Fatalf("Unexpected message %+v", message)
+	}
+}
+
+func TestParseMessage_Invalid(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"hello\n0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"example.com wants you to sign in with your Ethereum account:\nnot-an-address\n\nURI: a\nVersion: 1\nChain ID: 1\nNonce: n\nIssued At: 2024-10-02T18:24:00Z",
+		"example.com wants you to sign in with your Ethereum account:\n0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266\n\nURI: a\nVersion: 2\nChain ID: 1\nNonce: n\nIssued At: 2024-10-02T18:24:00Z",
+	} {
+		if _, err := ParseMessage(text); !errors.Is(err, InvalidMessageError) {
+			t.Errorf("%q: expected InvalidMessageError, got %v", text, err)
+		}
+	}
+}
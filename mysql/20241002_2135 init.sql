@@ -7,8 +7,16 @@ CREATE TABLE workflow_info
     workflow_name VARCHAR(128)                             NOT NULL,
     to_addr       varchar(64)                              not null,
     token_info_id INT                                      NOT NULL COMMENT 'tokeninfo id',
+    amount        DECIMAL(65, 0)                           NOT NULL DEFAULT 0 COMMENT 'payout amount in token base units',
     description   varchar(1024)                            NOT NULL COMMENT 'workflow description',
-    status        ENUM ('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending' COMMENT 'workflow status,default pending',
+    status        ENUM ('pending', 'approved', 'rejected', 'cancelled', 'expired', 'paid', 'failed') NOT NULL DEFAULT 'pending' COMMENT 'workflow status,default pending',
+    schedule_type ENUM ('none', 'once', 'cron')            NOT NULL DEFAULT 'none' COMMENT 'none: paid on approval, once: at next_run_time, cron: on every fire of schedule_cron',
+    schedule_cron varchar(64)                              NOT NULL DEFAULT '' COMMENT 'five field cron expression, UTC',
+    schedule_status ENUM ('none', 'active', 'paused', 'cancelled', 'completed') NOT NULL DEFAULT 'none',
+    next_run_time datetime                                 null COMMENT 'when the next payout is created',
+    schedule_end_time datetime                             null COMMENT 'no cron run after this',
+    max_runs      INT                                      NOT NULL DEFAULT 0 COMMENT '0 = until cancelled or schedule_end_time',
+    run_count     INT                                      NOT NULL DEFAULT 0 COMMENT 'payouts created so far',
     create_by     varchar(64)                              not null comment 'create_by user_id',
     create_addr   varchar(64)                              not null comment 'create_addr',
     created_time  datetime                                          DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
@@ -18,8 +26,11 @@ CREATE TABLE workflow_info
 ) COMMENT 'workflow_info';
 # CREATE INDEX idx_workflow_name ON workflow_info (workflow_name);
 CREATE INDEX idx_to_addr ON workflow_info (to_addr);
-# CREATE INDEX idx_token_info_id ON workflow_info (token_info_id);
-# CREATE INDEX idx_status ON workflow_info (status);
+CREATE INDEX idx_token_info_id ON workflow_info (token_info_id);
+CREATE INDEX idx_status ON workflow_info (status);
+CREATE INDEX idx_create_addr ON workflow_info (create_addr);
+CREATE INDEX idx_created_time ON workflow_info (created_time);
+CREATE INDEX idx_schedule_due ON workflow_info (schedule_status, next_run_time);
 
 
 CREATE TABLE workflow_approve
@@ -28,6 +39,8 @@ CREATE TABLE workflow_approve
     workflow_id  INT COMMENT 'workflow_info_id',
     approve_addr varchar(64) not null,
     status       ENUM ('approved', 'rejected') DEFAULT 'rejected' COMMENT 'approve status：approved/rejected, default rejected',
+    deadline     BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'unix seconds the signed vote is valid until',
+    signature    varchar(132)    NOT NULL DEFAULT '' COMMENT 'EIP-712 signature of the vote, hex',
     approve_time datetime,
     create_by    varchar(64) not null comment 'create_by user_id',
     create_addr  varchar(64) not null comment 'create_addr',
@@ -37,6 +50,7 @@ CREATE TABLE workflow_approve
     updated_time TIMESTAMP                     DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated_time'
 ) COMMENT 'workflow_approve';
 CREATE INDEX idx_workflow_id ON workflow_approve (workflow_id);
+CREATE UNIQUE INDEX uk_workflow_approve_addr ON workflow_approve (workflow_id, approve_addr);
 CREATE INDEX idx_approve_addr ON workflow_approve (approve_addr);
 # CREATE INDEX idx_status ON workflow_approve (status);
 
@@ -46,8 +60,10 @@ CREATE TABLE management
     id               INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
     name             VARCHAR(100) NOT NULL COMMENT 'name',
     permission_level ENUM ('none', 'partial', 'full') DEFAULT 'none' COMMENT 'permission_level: none/partial/full, default none',
+    weight           INT          NOT NULL DEFAULT 1 COMMENT 'approval weight',
     addr             varchar(64)  not null comment 'wallet addr',
     anvil_info       varchar(64)  NOT NULL COMMENT 'anvil info',
+    status           ENUM ('active', 'inactive') NOT NULL DEFAULT 'active' COMMENT 'inactive members keep past votes but cannot act',
     create_by        varchar(64)  not null comment 'create_by user_id',
     create_addr      varchar(64)  not null comment 'create_addr',
     created_time     TIMESTAMP                        DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
@@ -57,7 +73,7 @@ CREATE TABLE management
 ) COMMENT 'management';
 CREATE INDEX idx_name ON management (name);
 # CREATE INDEX idx_permission_level ON management (permission_level);
-CREATE INDEX idx_addr ON management (addr);
+CREATE UNIQUE INDEX uk_addr ON management (addr);
 
 insert into management(name, permission_level, addr, anvil_info, create_by, create_addr)
     value ('anthn', 'full', '0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266', 'anvil 0', 0, '0x0');
@@ -102,6 +118,7 @@ CREATE TABLE token_info
     token_symbol     VARCHAR(64)  NOT NULL,
     contract_address VARCHAR(64)  NOT NULL,
     decimals         int          not null default 18,
+    token_type       ENUM ('erc20', 'native') NOT NULL DEFAULT 'erc20' COMMENT 'erc20 token or the chain native asset',
     create_by        varchar(64)  not null comment 'create_by user_id',
     create_addr      varchar(64)  not null comment 'create_addr',
     created_time     TIMESTAMP             DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
@@ -112,7 +129,7 @@ CREATE TABLE token_info
 
 CREATE INDEX idx_token_name ON token_info (token_name);
 CREATE INDEX idx_token_symbol ON token_info (token_symbol);
-CREATE INDEX idx_contract_address ON token_info (contract_address);
+CREATE UNIQUE INDEX uk_contract_address ON token_info (contract_address);
 
 insert into token_info(id, token_name, token_symbol, contract_address, decimals, create_by, create_addr) VALUE
     (null, 'Test_USDT', 'Test_USDT', '0x700b6A60ce7EaaEA56F065753d8dcB9653dbAD35', '6', '0', '0x0');
@@ -122,14 +139,25 @@ CREATE TABLE token_transfer_log
     id               INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
     token_info_id    INT                                   not null,
     workflow_id      INT                                   not null,
+    schedule_run     INT                                   not null DEFAULT 0 COMMENT 'run of a scheduled workflow, 0 = paid on approval',
     from_address     VARCHAR(64)                           NOT NULL,
     to_address       VARCHAR(64)                           NOT NULL,
     contract_address VARCHAR(64)                           NOT NULL,
-    amount           BIGINT UNSIGNED                       NOT NULL,
+    amount           DECIMAL(65, 0)                        NOT NULL COMMENT 'token base units',
     transfer_data    VARCHAR(512)                          NOT NULL COMMENT 'erc20 transfer data',
-    status           ENUM ('pending', 'success', 'failed') not null DEFAULT 'pending',
+    status           ENUM ('pending', 'sending', 'success', 'failed', 'dead_letter', 'cancelled') not null DEFAULT 'pending' COMMENT 'sending: claimed by a dispatcher, dead_letter: out of retries',
     retry_count      INT                                   not null DEFAULT 0 COMMENT 'retry_count, default 0',
     transaction_hash VARCHAR(66)                           not null COMMENT 'tx hash',
+    batch_id         INT                                   not null DEFAULT 0 COMMENT 'token_transfer_batch the transfer was sent in, 0 = sent alone',
+    gas_limit        BIGINT UNSIGNED                       not null DEFAULT 0 COMMENT 'gas limit of the sent tx',
+    max_fee_per_gas  VARCHAR(78)                           not null DEFAULT '' COMMENT 'EIP-1559 maxFeePerGas, wei',
+    max_priority_fee_per_gas VARCHAR(78)                   not null DEFAULT '' COMMENT 'EIP-1559 maxPriorityFeePerGas, wei',
+    failure_reason   VARCHAR(512)                          null COMMENT 'last error of the transfer',
+    gas_limit_override       BIGINT UNSIGNED               not null DEFAULT 0 COMMENT 'set when an operator retries, 0 = estimate',
+    max_fee_per_gas_override VARCHAR(78)                   not null DEFAULT '' COMMENT 'set when an operator retries, wei',
+    priority_fee_override    VARCHAR(78)                   not null DEFAULT '' COMMENT 'set when an operator retries, wei',
+    lease_owner      VARCHAR(128)                          not null DEFAULT '' COMMENT 'dispatcher holding the sending row',
+    lease_expires_time TIMESTAMP                           null COMMENT 'sending row returns to pending after this unless renewed',
     create_by        varchar(64)                           not null comment 'create_by user_id',
     create_addr      varchar(64)                           not null comment 'create_addr',
     created_time     TIMESTAMP                                      DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
@@ -139,11 +167,25 @@ CREATE TABLE token_transfer_log
 )
     COMMENT 'token_transfer_log';
 # CREATE INDEX idx_token_info_id ON token_transfer_log (token_info_id);
-CREATE INDEX idx_workflow_id ON token_transfer_log (workflow_id);
+CREATE UNIQUE INDEX uk_workflow_run ON token_transfer_log (workflow_id, schedule_run);
 # CREATE INDEX idx_from_address ON token_transfer_log (from_address);
 # CREATE INDEX idx_to_address ON token_transfer_log (to_address);
-# CREATE INDEX idx_status ON token_transfer_log (status);
+CREATE INDEX idx_status_lease ON token_transfer_log (status, lease_expires_time);
 CREATE INDEX idx_transaction_hash ON token_transfer_log (transaction_hash);
+CREATE INDEX idx_batch_id ON token_transfer_log (batch_id);
+
+CREATE TABLE token_transfer_attempt
+(
+    id                    INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    token_transfer_log_id INT          not null,
+    outcome               VARCHAR(32)  not null COMMENT 'broadcast, broadcast_failed, failed, lease_expired, dropped, dead_letter, retried, cancelled',
+    retry_count           INT          not null DEFAULT 0 COMMENT 'retry_count of the transfer after this attempt',
+    tx_hash               VARCHAR(66)  not null DEFAULT '',
+    error                 VARCHAR(512) null,
+    operator_addr         VARCHAR(64)  not null COMMENT 'dispatcher job or operator address',
+    created_time          TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    KEY idx_token_transfer_log_id (token_transfer_log_id)
+) COMMENT 'attempt history of a token_transfer_log';
 
 CREATE TABLE block_info
 (
@@ -191,4 +233,178 @@ CREATE TABLE `transaction_info`
 #     KEY `idx_created_time` (`created_time`)
 ) ENGINE = InnoDB
   DEFAULT CHARSET = utf8mb4
-  COLLATE = utf8mb4_unicode_ci;
\ No newline at end of file
+  COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE `token_transfer_event`
+(
+    `id`            BIGINT                        NOT NULL AUTO_INCREMENT,
+    `token_info_id` INT                           NOT NULL,
+    `token_address` VARCHAR(64)                   NOT NULL,
+    `event_type`    ENUM ('transfer', 'approval') NOT NULL,
+    `from_address`  VARCHAR(64)                   NOT NULL comment 'transfer from / approval owner',
+    `to_address`    VARCHAR(64)                   NOT NULL comment 'transfer to / approval spender',
+    `value`         VARCHAR(78)                   NOT NULL comment 'uint256 decimal string',
+    `log_index`     INT UNSIGNED                  NOT NULL,
+    `block_number`  BIGINT UNSIGNED               NOT NULL,
+    `block_hash`    VARCHAR(128)                  NOT NULL,
+    `tx_hash`       VARCHAR(128)                  NOT NULL,
+    `created_time`  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_tx_hash_log_index` (`tx_hash`, `log_index`),
+    KEY `idx_token_address` (`token_address`),
+    KEY `idx_from_address` (`from_address`),
+    KEY `idx_to_address` (`to_address`),
+    KEY `idx_block_number` (`block_number`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_unicode_ci;
+
+CREATE TABLE signer_nonce
+(
+    id           bigint AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    address      varchar(64)                                         not null comment 'signer address',
+    nonce        BIGINT UNSIGNED                                     not null,
+    status       ENUM ('reserved', 'sent', 'released', 'confirmed') not null DEFAULT 'reserved',
+    tx_hash      varchar(66)                                         not null DEFAULT '' COMMENT 'tx hash once sent',
+    created_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    updated_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated_time',
+    UNIQUE KEY uk_address_nonce (address, nonce)
+) COMMENT 'signer_nonce';
+
+CREATE TABLE token_transfer_tx
+(
+    id                       INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    token_transfer_log_id    INT                                      not null,
+    tx_hash                  VARCHAR(66)                              not null,
+    tx_type                  ENUM ('original', 'speed_up', 'cancel') not null DEFAULT 'original',
+    from_address             VARCHAR(64)                              not null,
+    nonce                    BIGINT UNSIGNED                          not null,
+    gas_limit                BIGINT UNSIGNED                          not null,
+    max_fee_per_gas          VARCHAR(78)                              not null COMMENT 'wei',
+    max_priority_fee_per_gas VARCHAR(78)                              not null COMMENT 'wei',
+    raw_tx                   TEXT COMMENT 'signed tx, saved before broadcast so it can be rebroadcast',
+    status                   ENUM ('signed', 'broadcast', 'dropped') not null DEFAULT 'broadcast',
+    created_time             TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'signing time',
+    UNIQUE KEY uk_tx_hash (tx_hash),
+    KEY idx_token_transfer_log_id (token_transfer_log_id),
+    KEY idx_status (status)
+) COMMENT 'every tx broadcast for a token_transfer_log, including replacements';
+
+CREATE TABLE token_transfer_batch
+(
+    id                       INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    token_info_id            INT                                      not null,
+    contract_address         VARCHAR(64)                              not null COMMENT 'token paid',
+    disperse_address         VARCHAR(64)                              not null COMMENT 'disperse contract called',
+    from_address             VARCHAR(64)                              not null,
+    tx_hash                  VARCHAR(66)                              not null,
+    payout_count             INT                                      not null COMMENT 'token_transfer_log rows paid',
+    total_amount             DECIMAL(65, 0)                           not null COMMENT 'token base units',
+    nonce                    BIGINT UNSIGNED                          not null,
+    gas_limit                BIGINT UNSIGNED                          not null,
+    max_fee_per_gas          VARCHAR(78)                              not null COMMENT 'wei',
+    max_priority_fee_per_gas VARCHAR(78)                              not null COMMENT 'wei',
+    raw_tx                   TEXT COMMENT 'signed tx, saved before broadcast so it can be rebroadcast',
+    status                   ENUM ('signed', 'broadcast', 'settled', 'reverted', 'dropped') not null DEFAULT 'signed',
+    created_time             TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'signing time',
+    updated_time             TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated_time',
+    UNIQUE KEY uk_tx_hash (tx_hash),
+    KEY idx_status (status)
+) COMMENT 'one disperse contract call paying several token_transfer_log rows of a token';
+
+CREATE TABLE approval_policy
+(
+    id              INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    name            VARCHAR(100)                     NOT NULL,
+    token_info_id   INT                              NOT NULL DEFAULT 0 COMMENT 'token_info id, 0 applies to every token',
+    min_amount      DECIMAL(65, 0)                   NOT NULL DEFAULT 0 COMMENT 'inclusive lower bound of the amount tier, token base units',
+    required_weight INT                              NOT NULL DEFAULT 2 COMMENT 'sum of approver weights needed',
+    approver_levels SET ('none', 'partial', 'full') NOT NULL DEFAULT 'partial,full' COMMENT 'management permission levels allowed to approve',
+    reject_weight   INT                              NOT NULL DEFAULT 2 COMMENT 'sum of rejecting weights that rejects the workflow',
+    veto_levels     SET ('none', 'partial', 'full') NOT NULL DEFAULT 'full' COMMENT 'permission levels whose single rejection rejects the workflow',
+    create_by       varchar(64)                      not null comment 'create_by user_id',
+    create_addr     varchar(64)                      not null comment 'create_addr',
+    created_time    TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    updated_by      varchar(64)                      null comment 'updated_by user_id',
+    updated_addr    varchar(64)                      null comment 'updated_addr',
+    updated_time    TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated_time',
+    KEY idx_token_info_id (token_info_id)
+) COMMENT 'approval_policy';
+
+insert into approval_policy(name, token_info_id, min_amount, required_weight, approver_levels, reject_weight, veto_levels, create_by, create_addr)
+    value ('default', 0, 0, 2, 'partial,full', 2, 'full', 0, '0x0');
+
+CREATE TABLE workflow_status_history
+(
+    id            INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    workflow_id   INT          NOT NULL,
+    from_status   VARCHAR(16)  NOT NULL COMMENT 'empty for the initial status',
+    to_status     VARCHAR(16)  NOT NULL,
+    reason        VARCHAR(512) NOT NULL DEFAULT '',
+    operator_addr VARCHAR(64)  NOT NULL COMMENT 'address or system job that made the transition',
+    created_time  TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    KEY idx_workflow_id (workflow_id)
+) COMMENT 'every status transition of a workflow';
+
+CREATE TABLE auth_nonce
+(
+    id           INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    nonce        VARCHAR(32)              NOT NULL COMMENT 'Sign-In with Ethereum nonce, single use',
+    status       ENUM ('issued', 'used') NOT NULL DEFAULT 'issued',
+    address      VARCHAR(64)              NOT NULL DEFAULT '' COMMENT 'address that signed in with the nonce',
+    expires_time TIMESTAMP                NOT NULL COMMENT 'nonce is rejected after this',
+    used_time    TIMESTAMP                NULL,
+    created_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    UNIQUE KEY uk_nonce (nonce)
+) COMMENT 'auth_nonce';
+
+CREATE TABLE management_audit
+(
+    id            INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    management_id INT                                        NOT NULL,
+    addr          VARCHAR(64)                                NOT NULL,
+    action        ENUM ('create', 'update', 'deactivate')   NOT NULL,
+    old_level     VARCHAR(16)                                NOT NULL DEFAULT '' COMMENT 'empty on create',
+    new_level     VARCHAR(16)                                NOT NULL DEFAULT '',
+    old_weight    INT                                        NOT NULL DEFAULT 0,
+    new_weight    INT                                        NOT NULL DEFAULT 0,
+    old_status    VARCHAR(16)                                NOT NULL DEFAULT '',
+    new_status    VARCHAR(16)                                NOT NULL DEFAULT '',
+    reason        VARCHAR(512)                               NOT NULL DEFAULT '',
+    operator_addr VARCHAR(64)                                NOT NULL COMMENT 'full permission member that made the change',
+    created_time  TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    KEY idx_management_id (management_id)
+) COMMENT 'every change to the management table';
+
+CREATE TABLE approval_policy_audit
+(
+    id              INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    policy_id       INT                                        NOT NULL,
+    action          ENUM ('create')                            NOT NULL,
+    token_info_id   INT                                        NOT NULL DEFAULT 0,
+    min_amount      DECIMAL(65, 0)                             NOT NULL DEFAULT 0,
+    required_weight INT                                        NOT NULL DEFAULT 0,
+    approver_levels VARCHAR(32)                                NOT NULL DEFAULT '',
+    reject_weight   INT                                        NOT NULL DEFAULT 0,
+    veto_levels     VARCHAR(32)                                NOT NULL DEFAULT '',
+    reason          VARCHAR(512)                               NOT NULL DEFAULT '',
+    operator_addr   VARCHAR(64)                                NOT NULL COMMENT 'full permission member that made the change',
+    created_time    TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    KEY idx_policy_id (policy_id)
+) COMMENT 'every change to the approval policies';
+
+CREATE TABLE idempotency_key
+(
+    id            INT AUTO_INCREMENT PRIMARY KEY COMMENT 'id',
+    caller_addr   VARCHAR(64)                          NOT NULL COMMENT 'authenticated caller, keys are per caller',
+    idem_key      VARCHAR(128)                         NOT NULL COMMENT 'Idempotency-Key header',
+    request_hash  CHAR(64)                             NOT NULL COMMENT 'sha256 of method, path and body',
+    status        ENUM ('processing', 'completed')    NOT NULL DEFAULT 'processing',
+    response_code INT                                  NOT NULL DEFAULT 0,
+    response_body MEDIUMTEXT                           NULL COMMENT 'replayed for retries',
+    expires_time  TIMESTAMP                            NOT NULL,
+    created_time  TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT 'created_time',
+    updated_time  TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT 'updated_time',
+    UNIQUE KEY uk_caller_key (caller_addr, idem_key),
+    KEY idx_expires_time (expires_time)
+) COMMENT 'idempotency_key';
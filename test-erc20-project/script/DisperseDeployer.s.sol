@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: UNLICENSED
+pragma solidity ^0.8.13;
+
+import "@openzeppelin/contracts/token/ERC20/IERC20.sol";
+
+import {Script, console} from "forge-std/Script.sol";
+
+// Disperse pays many recipients of one token in a single transaction. The
+// sender approves this contract once; every payment is a transferFrom from
+// the sender, so each recipient gets its own Transfer event from the sender.
+contract Disperse {
+    function disperseToken(IERC20 token, address[] calldata recipients, uint256[] calldata values) external {
+        require(recipients.length == values.length, "Disperse: length mismatch");
+        for (uint256 i = 0; i < recipients.length; i++) {
+            require(token.transferFrom(msg.sender, recipients[i], values[i]), "Disperse: transfer failed");
+        }
+    }
+}
+
+contract DisperseDeployer is Script {
+
+    function run() public {
+        uint256 deployerPrivateKey = vm.envUint("PRIVATE_KEY");
+
+        vm.startBroadcast(deployerPrivateKey);
+
+        Disperse disperse = new Disperse();
+        console.log("deploy disperse:", address(disperse));
+
+        // let the payout signer use the disperse contract for the test token
+        address token = vm.envOr("TOKEN_ADDRESS", address(0));
+        if (token != address(0)) {
+            IERC20(token).approve(address(disperse), type(uint256).max);
+            console.log("approved disperse for token:", token);
+        }
+
+        vm.stopBroadcast();
+    }
+}